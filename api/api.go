@@ -4,31 +4,65 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
-	"strings"
 	"time"
 )
 
 type APIHandler struct {
 	todoService TodoService
+	// idempotency có thể nil, khi đó header Idempotency-Key bị bỏ qua.
+	idempotency    IdempotencyStore
+	idempotencyTTL time.Duration
+	// auth là nil khi xác thực bị tắt; khi đó người dùng của request lấy từ header X-User-ID.
+	auth *Authenticator
+	// apiKeys là nil khi không có endpoint /v1/api-keys.
+	apiKeys APIKeyStore
+	// events là nil khi không có endpoint /v1/todos/events; todoService phải phát sự kiện vào đó (xem EventTodoService).
+	events         *EventBroker
+	eventHeartbeat time.Duration
 }
 
-var ErrTodoNotFound = errors.New("todo not found")
-
-func NewAPIHandler(todoService TodoService) *APIHandler {
+func NewAPIHandler(todoService TodoService, idempotency IdempotencyStore) *APIHandler {
 	return &APIHandler{
-		todoService: todoService,
+		todoService:    todoService,
+		idempotency:    idempotency,
+		idempotencyTTL: defaultIdempotencyTTL,
+		eventHeartbeat: defaultEventHeartbeat,
 	}
 }
 
 // @Summary Get all Todos
-// @Description Retrieve a list of all Todos
+// @Description Retrieve a page of Todos. Next/previous pages are advertised in the Link header.
 // @Tags Todos
 // @Produce json
+// @Param limit query int false "Page size (default 50, capped at 200)"
+// @Param cursor query string false "Opaque cursor from the Link header"
+// @Param done query bool false "Filter by done state"
+// @Param status query string false "Filter by status, comma-separated (e.g. backlog,in_progress)"
+// @Param created_after query string false "Created at or after (RFC 3339)"
+// @Param created_before query string false "Created before (RFC 3339)"
+// @Param done_after query string false "Done at or after (RFC 3339)"
+// @Param done_before query string false "Done before (RFC 3339)"
+// @Param due_after query string false "Due at or after (RFC 3339)"
+// @Param due_before query string false "Due before (RFC 3339)"
+// @Param overdue query bool false "Filter by overdue state (due in the past, not done or cancelled)"
+// @Param priority query string false "Filter by priority, comma-separated (e.g. high,urgent)"
+// @Param min_priority query string false "Priority at or above" Enums(low, normal, high, urgent)
+// @Param tag query string false "Filter by tag names, comma-separated (e.g. frontend,qr)"
+// @Param tag_match query string false "Match any (default) or all of the tags" Enums(any, all)
+// @Param ready query bool false "true: open Todos without open blockers, false: open Todos waiting on a blocker"
+// @Param project_id query string false "Filter by project"
+// @Param title query string false "Case-insensitive title substring"
+// @Param sort query string false "Sort field" Enums(created_at, done_at, title, due_at, priority, urgency)
+// @Param order query string false "Sort order (default desc, asc for due_at)" Enums(asc, desc)
+// @Param If-None-Match header string false "ETag of a previously fetched page"
 // @Success 200 {array} Todo
+// @Success 304 "Page not modified"
+// @Header 200 {string} ETag "Weak ETag of the page"
+// @Failure 422 {object} Problem "Invalid query parameters"
+// @Failure 503 {object} Problem "Storage unavailable"
+// @Router /v1/todos [get]
 // @Router /todo [get]
 func (h *APIHandler) GetAllTodo(w http.ResponseWriter, r *http.Request) {
 
@@ -36,17 +70,36 @@ func (h *APIHandler) GetAllTodo(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	query, err := parseTodoQuery(r)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
-	todos, err := h.todoService.GetAllTodo(ctx)
+	page, err := h.todoService.GetAllTodo(ctx, query)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error fetching todos: %v", err), http.StatusInternalServerError)
+		writeError(w, r, err)
+		return
+	}
+	writeTodoPage(w, r, page)
+}
+
+// writeTodoPage ghi một trang todo kèm header Link và ETag, trả về 304 nếu If-None-Match khớp.
+func writeTodoPage(w http.ResponseWriter, r *http.Request, page *TodoPage) {
+	if links := pageLinks(r, page.PrevCursor, page.NextCursor); links != "" {
+		w.Header().Add("Link", links)
+	}
+	etag := listETag(page)
+	w.Header().Set("ETag", etag)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(todos); err != nil {
-		http.Error(w, fmt.Sprintf("Error encoding response: %v", err), http.StatusInternalServerError)
+	if err := json.NewEncoder(w).Encode(page.Items); err != nil {
+		log.Println("Error encoding response:", err)
 		return
 	}
 }
@@ -56,9 +109,13 @@ func (h *APIHandler) GetAllTodo(w http.ResponseWriter, r *http.Request) {
 // @Tags Todos
 // @Produce json
 // @Param id path string true "Todo ID"
+// @Param If-None-Match header string false "ETag of a previously fetched version"
 // @Success 200 {object} Todo
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Todo not found"
+// @Success 304 "Todo not modified"
+// @Header 200 {string} ETag "Current version of the Todo"
+// @Failure 400 {object} Problem "Invalid ID"
+// @Failure 404 {object} Problem "Todo not found"
+// @Router /v1/todos/{id} [get]
 // @Router /todo/getuser/{id} [get]
 func (h *APIHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 
@@ -66,31 +123,40 @@ func (h *APIHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
 
-	id := strings.TrimPrefix(r.URL.Path, "/todo/getuser/")
+	id := todoID(r)
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeMissingID, "todo ID is required")
 		return
 	}
 
+	if err := h.authorize(ctx, permReadTodo, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
 	todo, err := h.todoService.GetTodo(ctx, id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	if todo == nil {
-		http.Error(w, "Todo not found", http.StatusNotFound)
+		writeError(w, r, todoNotFound(id))
 		return
 	}
 
+	w.Header().Set("ETag", todo.ETag())
+	if etagMatches(r.Header.Get("If-None-Match"), todo.ETag()) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(todo); err != nil {
-		http.Error(w, "Failed to encode todo", http.StatusInternalServerError)
+		log.Println("Error encoding response:", err)
 	}
 }
 
@@ -99,9 +165,15 @@ func (h *APIHandler) GetTodo(w http.ResponseWriter, r *http.Request) {
 // @Tags Todos
 // @Accept json
 // @Produce json
-// @Param todo body Todo true "Todo Information"
+// @Param todo body CreateTodoRequest true "Todo Information"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of repeating the change"
 // @Success 201 {object} Todo
-// @Failure 400 {string} string "Invalid request body"
+// @Header 201 {string} Location "URL of the created Todo"
+// @Failure 400 {object} Problem "Invalid request body"
+// @Failure 403 {object} Problem "Your role on the project does not allow adding Todos"
+// @Failure 413 {object} Problem "Request body too large"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos [post]
 // @Router /todo/create [post]
 func (h *APIHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 
@@ -109,41 +181,124 @@ func (h *APIHandler) CreateTodo(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+	var input CreateTodoRequest
+	if err := decodePayload(w, r, createTodoRules, &input); err != nil {
+		writeError(w, r, err)
 		return
 	}
-	var todo Todo
-	err = json.Unmarshal(body, &todo)
-	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := h.authorize(ctx, permEditProject, input.ProjectID); err != nil {
+		writeError(w, r, err)
 		return
 	}
-	todo.CreatedAt = time.Now()
-	newTodo, err := h.todoService.CreateTodo(ctx, todo)
+	newTodo, err := h.todoService.CreateTodo(ctx, input.todo())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", todoLocation(newTodo.ID))
+	w.Header().Set("ETag", newTodo.ETag())
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(newTodo)
 }
 
-// @Summary Update a Todo
-// @Description Update details of a Todo by its ID
+// @Summary Replace a Todo
+// @Description Replace title, desc and status of a Todo by its ID. Older clients may send done instead of status;
+// @Description done=true moves the todo to done and done=false reopens a done todo.
+// @Tags Todos
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param todo body ReplaceTodoRequest true "Updated Todo Information"
+// @Param If-Match header string true "ETag from GET, or *"
+// @Success 200 {object} Todo
+// @Failure 400 {object} Problem "Invalid request body"
+// @Failure 403 {object} Problem "Your role on the Todo or the target project does not allow the change"
+// @Failure 413 {object} Problem "Request body too large"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Failure 409 {object} Problem "Status transition not allowed by the workflow"
+// @Failure 428 {object} Problem "If-Match is required"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos/{id} [put]
+func (h *APIHandler) ReplaceTodo(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method != http.MethodPut {
+		writeMethodNotAllowed(w, r, http.MethodPut)
+		return
+	}
+	id := todoID(r)
+	if id == "" {
+		writeProblem(w, r, http.StatusNotFound, CodeMissingID, "todo ID is required")
+		return
+	}
+
+	var input ReplaceTodoRequest
+	if err := decodePayload(w, r, replaceTodoRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todo := Todo{Title: input.Title, Desc: input.Desc, Status: input.Status, Priority: input.Priority, DueAt: input.DueAt, Estimate: input.Estimate, Tags: input.Tags, ProjectID: input.ProjectID,
+		Recurrence: input.Recurrence}
+	if input.Done != nil {
+		todo.Done = *input.Done
+	}
+	if input.Status != "" {
+		if err := checkDoneMatchesStatus(input.Status, input.Done); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	ctx, ok := applyIfMatch(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorizeChange(ctx, id, &todo.ProjectID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	updatedTodo, err := h.todoService.UpdateTodo(ctx, id, todo)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", updatedTodo.ETag())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(updatedTodo)
+}
+
+// @Summary Partially update a Todo
+// @Description Update only the given fields of a Todo. Accepts JSON Merge Patch (application/merge-patch+json, RFC 7396;
+// @Description plain application/json is treated the same) or JSON Patch (application/json-patch+json, RFC 6902).
+// @Description id, created_at, done_at, status_times, updated_at and version are read-only.
 // @Tags Todos
 // @Accept json
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
 // @Produce json
 // @Param id path string true "Todo ID"
-// @Param todo body Todo true "Updated Todo Information"
+// @Param patch body TodoMergePatch true "Fields to change"
+// @Param If-Match header string false "ETag from GET; required on /v1 routes"
 // @Success 200 {object} Todo
-// @Failure 400 {string} string "Invalid request body"
-// @Failure 404 {string} string "Todo not found"
+// @Failure 400 {object} Problem "Invalid request body"
+// @Failure 403 {object} Problem "Your role on the Todo or the target project does not allow the change"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 409 {object} Problem "A JSON Patch test operation failed"
+// @Failure 413 {object} Problem "Request body too large"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Failure 415 {object} Problem "Unsupported patch format"
+// @Failure 428 {object} Problem "If-Match is required"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos/{id} [patch]
 // @Router /todo/update/{id} [patch]
 func (h *APIHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 
@@ -151,93 +306,270 @@ func (h *APIHandler) UpdateTodo(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if r.Method != http.MethodPatch {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodPatch)
 		return
 	}
-	id := strings.TrimPrefix(r.URL.Path, "/todo/update/")
+	id := todoID(r)
 	if id == "" {
-		http.Error(w, "ID is required", http.StatusNotFound)
+		writeProblem(w, r, http.StatusNotFound, CodeMissingID, "todo ID is required")
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+	mediaType, ok := patchMediaType(r)
+	if !ok {
+		w.Header().Set("Accept-Patch", mergePatchContentType+", "+jsonPatchContentType)
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+			"use "+mergePatchContentType+" or "+jsonPatchContentType)
 		return
 	}
 
-	var todo Todo
-	err = json.Unmarshal(body, &todo)
+	body, err := readBody(w, r)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, r, err)
 		return
 	}
 
-	updatedTodo, err := h.todoService.UpdateTodo(ctx, id, todo)
-	if err != nil {
-		if err.Error() == "not found" {
-			http.Error(w, "Todo not found", http.StatusNotFound)
-			return
+	ctx, ok = applyIfMatch(ctx, w, r)
+	if !ok {
+		return
+	}
+
+	var updatedTodo *Todo
+	if mediaType == jsonPatchContentType {
+		updatedTodo, err = h.patchWithJSONPatch(ctx, id, body)
+	} else {
+		var patch TodoPatch
+		patch, err = decodeMergePatch(body)
+		if err == nil {
+			err = h.authorizeChange(ctx, id, patch.ProjectID)
+		}
+		if err == nil {
+			updatedTodo, err = h.todoService.PatchTodo(ctx, id, patch)
 		}
-		http.Error(w, "Failed to update todo", http.StatusInternalServerError)
+	}
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", updatedTodo.ETag())
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(updatedTodo)
 }
 
-// @Summary Update Todo Status
-// @Description Update the status of a Todo by its ID
+// patchWithJSONPatch đọc todo hiện tại, áp dụng JSON Patch rồi ghi lại có điều kiện theo version vừa đọc,
+// để thay đổi xen giữa lúc đọc và lúc ghi không bị ghi đè. Khi client không gửi If-Match thì thử lại vài lần.
+func (h *APIHandler) patchWithJSONPatch(ctx context.Context, id string, body []byte) (*Todo, error) {
+	ops, err := parseJSONPatch(body)
+	if err != nil {
+		return nil, err
+	}
+	pinned := expectedVersion(ctx) != 0
+	for attempt := 1; ; attempt++ {
+		current, err := h.todoService.GetTodo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(ctx, current.Version); err != nil {
+			return nil, err
+		}
+		patch, err := todoPatchFromJSONPatch(*current, ops)
+		if err != nil {
+			return nil, err
+		}
+		if err := h.authorizeChange(ctx, id, patch.ProjectID); err != nil {
+			return nil, err
+		}
+		updatedTodo, err := h.todoService.PatchTodo(withExpectedVersion(ctx, current.Version), id, patch)
+		if errors.Is(err, ErrPreconditionFailed) && !pinned && attempt < 3 {
+			continue
+		}
+		return updatedTodo, err
+	}
+}
+
+// @Summary Complete a Todo
+// @Description Mark a Todo as done. Completing a todo that is already done changes nothing, so retries are safe.
 // @Tags Todos
 // @Produce json
 // @Param id path string true "Todo ID"
+// @Param If-Match header string false "ETag from GET"
 // @Success 200 {object} Todo
-// @Failure 404 {string} string "Todo not found"
-// @Router /todo/update-status/{id} [patch]
-func (h *APIHandler) UpdateTodoStatus(w http.ResponseWriter, r *http.Request) {
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 409 {object} Problem "Status transition not allowed by the workflow"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Router /v1/todos/{id}/complete [post]
+func (h *APIHandler) CompleteTodo(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.todoService.CompleteTodo)
+}
+
+// @Summary Reopen a Todo
+// @Description Move a done Todo back to the reopen status of the workflow. Reopening a todo that is not done changes nothing,
+// @Description so retries are safe.
+// @Tags Todos
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param If-Match header string false "ETag from GET"
+// @Success 200 {object} Todo
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 409 {object} Problem "Status transition not allowed by the workflow"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Router /v1/todos/{id}/reopen [post]
+func (h *APIHandler) ReopenTodo(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, h.todoService.ReopenTodo)
+}
+
+// @Summary Change the status of a Todo
+// @Description Move a Todo to another status of the workflow (see GET /v1/workflow). done and done_at follow the status.
+// @Description Moving a todo to the status it already has changes nothing, so retries are safe.
+// @Tags Todos
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param transition body TransitionRequest true "Target status"
+// @Param If-Match header string false "ETag from GET"
+// @Success 200 {object} Todo
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 409 {object} Problem "Status transition not allowed by the workflow"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Failure 422 {object} Problem "Unknown status"
+// @Router /v1/todos/{id}/status [post]
+func (h *APIHandler) TransitionTodo(w http.ResponseWriter, r *http.Request) {
+	h.changeStatus(w, r, func(ctx context.Context, id string) (*Todo, error) {
+		var input TransitionRequest
+		if err := decodePayload(w, r, transitionRules, &input); err != nil {
+			return nil, err
+		}
+		return h.todoService.TransitionTodo(ctx, id, input.Status)
+	})
+}
+
+// @Summary Describe the workflow
+// @Description Statuses a Todo can have and the transitions allowed between them.
+// @Tags Todos
+// @Produce json
+// @Success 200 {object} WorkflowResponse
+// @Router /v1/workflow [get]
+func (h *APIHandler) GetWorkflow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(newWorkflowResponse(h.todoService.Workflow())); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// changeStatus là phần chung của complete, reopen và status: transition chạy sau khi đã kiểm tra id và If-Match.
+func (h *APIHandler) changeStatus(w http.ResponseWriter, r *http.Request, transition func(ctx context.Context, id string) (*Todo, error)) {
 
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 	defer cancel()
 
-	if r.Method != http.MethodPatch {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
 
-	id := r.URL.Path[len("/todo/update-status/"):]
+	id := todoID(r)
 	if id == "" {
-		http.Error(w, "Invalid Todo ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeMissingID, "todo ID is required")
 		return
 	}
 
-	err := h.todoService.UpdateTodoStatus(ctx, id)
+	ctx, ok := applyIfMatch(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorize(ctx, permEditTodo, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todo, err := transition(ctx, id)
 	if err != nil {
-		http.Error(w, "Error updating todo status: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
-	todo, err := h.todoService.GetTodo(ctx, id)
+	w.Header().Set("ETag", todo.ETag())
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Toggle Todo Status
+// @Description Flip the done state of a Todo. Prefer /complete and /reopen, which are safe to retry.
+// @Tags Todos
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param If-Match header string false "ETag from GET; required on /v1 routes"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of repeating the change"
+// @Success 200 {object} Todo
+// @Failure 400 {object} Problem "Invalid ID"
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Failure 428 {object} Problem "If-Match is required"
+// @Router /v1/todos/{id}/toggle [post]
+// @Router /todo/update-status/{id} [patch]
+func (h *APIHandler) UpdateTodoStatus(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if r.Method != http.MethodPatch && r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, "POST, PATCH")
+		return
+	}
+
+	id := todoID(r)
+	if id == "" {
+		writeProblem(w, r, http.StatusBadRequest, CodeMissingID, "todo ID is required")
+		return
+	}
+
+	ctx, ok := applyIfMatch(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorize(ctx, permEditTodo, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todo, err := h.todoService.UpdateTodoStatus(ctx, id)
 	if err != nil {
-		http.Error(w, "Error retrieving updated todo: "+err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", todo.ETag())
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(todo); err != nil {
-		http.Error(w, "Error encoding response: "+err.Error(), http.StatusInternalServerError)
+		log.Println("Error encoding response:", err)
 		return
 	}
 }
 
 // @Summary Delete a Todo
-// @Description Delete a Todo by its ID
+// @Description Move a Todo to the trash. It disappears from every other route until it is restored with
+// @Description POST /v1/trash/{id}/restore, and is purged after the retention period.
 // @Tags Todos
 // @Param id path string true "Todo ID"
+// @Param If-Match header string false "ETag from GET; required on /v1 routes"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of repeating the change"
 // @Success 204 {string} string "Todo deleted successfully"
-// @Failure 400 {string} string "Invalid ID"
-// @Failure 404 {string} string "Todo not found"
+// @Failure 400 {object} Problem "Invalid ID"
+// @Failure 403 {object} Problem "Only owners can delete the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 412 {object} Problem "Todo was modified since it was read"
+// @Failure 428 {object} Problem "If-Match is required"
+// @Router /v1/todos/{id} [delete]
 // @Router /todo/delete/{id} [delete]
 func (h *APIHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 
@@ -245,27 +577,1293 @@ func (h *APIHandler) DeleteTodo(w http.ResponseWriter, r *http.Request) {
 	defer cancel()
 
 	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeMethodNotAllowed(w, r, http.MethodDelete)
 		return
 	}
 
-	id := r.URL.Path[len("/todo/delete/"):]
+	id := todoID(r)
 	if id == "" {
-		http.Error(w, "Invalid Todo ID", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, CodeMissingID, "todo ID is required")
 		return
 	}
 
+	ctx, ok := applyIfMatch(ctx, w, r)
+	if !ok {
+		return
+	}
+	if err := h.authorize(ctx, permManageTodo, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
 	err := h.todoService.DeleteTodo(ctx, id)
 	if err != nil {
 		log.Println("Error deleting todo:", err)
+		writeError(w, r, err)
+		return
+	}
 
-		if err.Error() == "not found" {
-			http.Error(w, "not found", http.StatusNotFound)
-			return
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Bulk todo operations
+// @Description Create, complete, reopen, update or delete up to 100 todos in one request. The whole batch runs in one transaction.
+// @Description With atomic=true any failing item cancels the batch (other items report 424 rolled_back);
+// @Description otherwise successful items are kept and the response is 207 when some items failed.
+// @Description Items may carry a version that acts as If-Match for that item.
+// @Tags Todos
+// @Accept json
+// @Produce json
+// @Param batch body BulkRequest true "Action and items"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of repeating the change"
+// @Success 200 {object} BulkResponse "Every item succeeded"
+// @Success 207 {object} BulkResponse "Some items failed, the others were applied"
+// @Failure 400 {object} Problem "Invalid request body"
+// @Failure 413 {object} Problem "Request body too large"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos/bulk [post]
+func (h *APIHandler) BulkTodos(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	if r.Method != http.MethodPost {
+		writeMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+
+	batch, err := parseBulkRequest(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	results, err := h.allowedBulk(ctx, batch, func(batch *bulkBatch) ([]BulkResult, error) {
+		switch batch.action {
+		case "create":
+			return h.todoService.BulkCreateTodos(ctx, batch.todos, batch.opts)
+		case "done", "undone":
+			return h.todoService.BulkSetDone(ctx, batch.items, batch.action == "done", batch.opts)
+		case "update":
+			return h.todoService.BulkPatchTodos(ctx, batch.items, batch.opts)
 		}
-		http.Error(w, "Error deleting todo: "+err.Error(), http.StatusInternalServerError)
+		return h.todoService.BulkDeleteTodos(ctx, batch.items, batch.opts)
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	resp, status := newBulkResponse(r, batch, results)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary List tags
+// @Description Tags the caller can see in name order: their own tags, tags without an owner and tags on todos they can see. Counts only include todos the caller can see.
+// @Tags Tags
+// @Produce json
+// @Success 200 {array} Tag
+// @Router /v1/tags [get]
+func (h *APIHandler) ListTags(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	tags, err := h.todoService.ListTags(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tags); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Get a tag
+// @Tags Tags
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 200 {object} Tag
+// @Failure 404 {object} Problem "Tag not found"
+// @Router /v1/tags/{id} [get]
+func (h *APIHandler) GetTag(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadTag, tagID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	tag, err := h.todoService.GetTag(ctx, tagID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Create a tag
+// @Description Tag names are trimmed, lower-cased and unique. Tags are also created on the fly when a todo is tagged with a new name.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param tag body TagRequest true "Tag"
+// @Success 201 {object} Tag
+// @Header 201 {string} Location "URL of the created tag"
+// @Failure 403 {object} Problem "The caller is not a member of the tenant"
+// @Failure 409 {object} Problem "A tag with this name already exists"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/tags [post]
+func (h *APIHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permUseTenant, tenantFrom(ctx)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var input TagRequest
+	if err := decodePayload(w, r, tagRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	tag, err := h.todoService.CreateTag(ctx, input.tag())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", tagLocation(tag.ID))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Rename or recolor a tag
+// @Description Renaming a tag renames it on every todo that carries it and bumps their versions, so the caller needs the editor role on each of those todos.
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param tag body TagRequest true "Tag"
+// @Success 200 {object} Tag
+// @Failure 403 {object} Problem "The editor role on every todo carrying the tag is required"
+// @Failure 404 {object} Problem "Tag not found"
+// @Failure 409 {object} Problem "A tag with this name already exists"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/tags/{id} [put]
+func (h *APIHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var input TagRequest
+	if err := decodePayload(w, r, tagRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permEditTag, tagID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	tag, err := h.todoService.UpdateTag(ctx, tagID(r), input.tag())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tag); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Delete a tag
+// @Description The tag is removed from every todo that carries it; their versions are bumped. The caller needs the editor role on each of those todos.
+// @Tags Tags
+// @Param id path string true "Tag ID"
+// @Success 204 {string} string "Tag deleted"
+// @Failure 403 {object} Problem "The editor role on every todo carrying the tag is required"
+// @Failure 404 {object} Problem "Tag not found"
+// @Router /v1/tags/{id} [delete]
+func (h *APIHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permEditTag, tagID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.DeleteTag(ctx, tagID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List projects
+// @Description All projects in name order, with their open and done todo counts.
+// @Tags Projects
+// @Produce json
+// @Success 200 {array} Project
+// @Router /v1/projects [get]
+func (h *APIHandler) ListProjects(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	projects, err := h.todoService.ListProjects(ctx)
+	if err != nil {
+		writeError(w, r, err)
 		return
 	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(projects); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Get a project
+// @Tags Projects
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {object} Project
+// @Failure 404 {object} Problem "Project not found"
+// @Router /v1/projects/{id} [get]
+func (h *APIHandler) GetProject(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
+	project, err := h.todoService.GetProject(ctx, projectID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(project); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Create a project
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Param project body ProjectRequest true "Project"
+// @Success 201 {object} Project
+// @Header 201 {string} Location "URL of the created project"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/projects [post]
+func (h *APIHandler) CreateProject(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var input ProjectRequest
+	if err := decodePayload(w, r, projectRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	project, err := h.todoService.CreateProject(ctx, input.project())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", projectLocation(project.ID))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(project); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Rename a project
+// @Description Replace the name and description of a project.
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param project body ProjectRequest true "Project"
+// @Success 200 {object} Project
+// @Failure 403 {object} Problem "Only owners can change the project"
+// @Failure 404 {object} Problem "Project not found"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/projects/{id} [put]
+func (h *APIHandler) UpdateProject(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var input ProjectRequest
+	if err := decodePayload(w, r, projectRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permManageProject, projectID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	project, err := h.todoService.UpdateProject(ctx, projectID(r), input.project())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(project); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Delete a project
+// @Description Only empty projects can be deleted; move or delete their todos first. The default project cannot be deleted.
+// @Tags Projects
+// @Param id path string true "Project ID"
+// @Success 204 {string} string "Project deleted"
+// @Failure 403 {object} Problem "Only owners can delete the project"
+// @Failure 404 {object} Problem "Project not found"
+// @Failure 409 {object} Problem "Project still has todos, or is the default project"
+// @Router /v1/projects/{id} [delete]
+func (h *APIHandler) DeleteProject(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permManageProject, projectID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.DeleteProject(ctx, projectID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List the Todos of a project
+// @Description Same as GET /v1/todos restricted to one project; every filter, sort and pagination parameter of that endpoint is accepted.
+// @Tags Projects
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param limit query int false "Page size (default 50, capped at 200)"
+// @Param cursor query string false "Opaque cursor from the Link header"
+// @Param done query bool false "Filter by done state"
+// @Param sort query string false "Sort field" Enums(created_at, done_at, title, due_at, priority, urgency)
+// @Param order query string false "Sort order (default desc, asc for due_at)" Enums(asc, desc)
+// @Success 200 {array} Todo
+// @Success 304 "Page not modified"
+// @Failure 404 {object} Problem "Project not found"
+// @Failure 422 {object} Problem "Invalid query parameters"
+// @Router /v1/projects/{id}/todos [get]
+func (h *APIHandler) ListProjectTodos(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	id := projectID(r)
+	query, err := parseTodoQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if _, err := h.todoService.GetProject(ctx, id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	query.ProjectID = id
+	page, err := h.todoService.GetAllTodo(ctx, query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTodoPage(w, r, page)
+}
+
+// @Summary List the checklist of a Todo
+// @Description Items in position order. The Todo itself reports the progress as checklist.done / checklist.total.
+// @Tags Checklist
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {array} ChecklistItem
+// @Failure 404 {object} Problem "Todo not found"
+// @Router /v1/todos/{id}/checklist [get]
+func (h *APIHandler) ListChecklist(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	items, err := h.todoService.ListChecklist(ctx, todoID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Get a checklist item
+// @Tags Checklist
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param item_id path string true "Checklist item ID"
+// @Success 200 {object} ChecklistItem
+// @Failure 404 {object} Problem "Todo or checklist item not found"
+// @Router /v1/todos/{id}/checklist/{item_id} [get]
+func (h *APIHandler) GetChecklistItem(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	item, err := h.todoService.GetChecklistItem(ctx, todoID(r), checklistItemID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Add a checklist item
+// @Description Appends the item, or inserts it at position (1-based) and shifts the following items down. Bumps the version of the Todo.
+// @Tags Checklist
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param item body ChecklistItemRequest true "Checklist item"
+// @Param Idempotency-Key header string false "Retries with the same key replay the first response instead of repeating the change"
+// @Success 201 {object} ChecklistItem
+// @Header 201 {string} Location "URL of the created checklist item"
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos/{id}/checklist [post]
+func (h *APIHandler) AddChecklistItem(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var input ChecklistItemRequest
+	if err := decodePayload(w, r, addChecklistItemRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permEditTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	item, err := h.todoService.AddChecklistItem(ctx, todoID(r), input.patch())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", checklistItemLocation(item.TodoID, item.ID))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Check, rename or move a checklist item
+// @Description Only the given fields change. Moving an item to another position shifts the items in between.
+// @Description Checking the last open item completes the Todo when the workflow allows it.
+// @Tags Checklist
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param item_id path string true "Checklist item ID"
+// @Param item body ChecklistItemPatchRequest true "Fields to change"
+// @Success 200 {object} ChecklistItem
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo or checklist item not found"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos/{id}/checklist/{item_id} [patch]
+func (h *APIHandler) UpdateChecklistItem(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	var input ChecklistItemPatchRequest
+	if err := decodePayload(w, r, patchChecklistItemRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permEditTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	item, err := h.todoService.UpdateChecklistItem(ctx, todoID(r), checklistItemID(r), input.patch())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(item); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Remove a checklist item
+// @Description The following items move up one position. Bumps the version of the Todo.
+// @Tags Checklist
+// @Param id path string true "Todo ID"
+// @Param item_id path string true "Checklist item ID"
+// @Success 204 {string} string "Checklist item removed"
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo or checklist item not found"
+// @Router /v1/todos/{id}/checklist/{item_id} [delete]
+func (h *APIHandler) DeleteChecklistItem(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permEditTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.DeleteChecklistItem(ctx, todoID(r), checklistItemID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Mark a Todo as blocked by another Todo
+// @Description The Todo cannot be completed while the blocker is open. Adding an existing dependency returns the Todo unchanged.
+// @Tags Dependencies
+// @Produce json
+// @Param id path string true "ID of the blocked Todo"
+// @Param blocker_id path string true "ID of the blocking Todo"
+// @Success 200 {object} Todo
+// @Header 200 {string} ETag "Current version of the Todo"
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 409 {object} Problem "The dependency would create a cycle"
+// @Router /v1/todos/{id}/blockers/{blocker_id} [put]
+func (h *APIHandler) AddBlocker(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permEditTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permReadTodo, blockerID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todo, err := h.todoService.AddBlocker(ctx, todoID(r), blockerID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", todo.ETag())
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Remove a dependency
+// @Tags Dependencies
+// @Param id path string true "ID of the blocked Todo"
+// @Param blocker_id path string true "ID of the blocking Todo"
+// @Success 204 {string} string "Dependency removed"
+// @Failure 403 {object} Problem "Viewers cannot change the Todo"
+// @Failure 404 {object} Problem "Todo or dependency not found"
+// @Router /v1/todos/{id}/blockers/{blocker_id} [delete]
+func (h *APIHandler) RemoveBlocker(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permEditTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.RemoveBlocker(ctx, todoID(r), blockerID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List the Todos of a project in dependency order
+// @Description Every Todo of the project (not paginated), each one after all of its blockers. Todos that are ready at the same time keep their creation order; blockers in other projects are ignored.
+// @Tags Dependencies
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {array} Todo
+// @Failure 404 {object} Problem "Project not found"
+// @Router /v1/projects/{id}/order [get]
+func (h *APIHandler) OrderProjectTodos(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	todos, err := h.todoService.OrderProjectTodos(ctx, projectID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todos); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary List the occurrences of a recurring Todo
+// @Description Every occurrence of the series (not paginated) ordered by occurrence number, done ones included.
+// @Tags Recurrence
+// @Produce json
+// @Param id path string true "Series ID (series_id of any occurrence)"
+// @Success 200 {array} Todo
+// @Failure 404 {object} Problem "Series not found"
+// @Router /v1/series/{id} [get]
+func (h *APIHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadSeries, seriesID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todos, err := h.todoService.GetSeries(ctx, seriesID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todos); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Edit a whole recurring series
+// @Description Apply a JSON Merge Patch to every open occurrence of the series (not done, not cancelled) in one step;
+// @Description done and cancelled occurrences are kept as history. status, done and due_at belong to a single occurrence
+// @Description and are rejected, edit that Todo instead. recurrence null ends the series after the open occurrences.
+// @Tags Recurrence
+// @Accept json
+// @Produce json
+// @Param id path string true "Series ID"
+// @Param patch body SeriesPatchRequest true "Fields to change"
+// @Success 200 {array} Todo
+// @Failure 400 {object} Problem "Invalid request body"
+// @Failure 403 {object} Problem "Your role on an open occurrence does not allow the change"
+// @Failure 404 {object} Problem "Series not found"
+// @Failure 413 {object} Problem "Request body too large"
+// @Failure 415 {object} Problem "Unsupported patch format"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/series/{id} [patch]
+func (h *APIHandler) PatchSeries(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if mediaType, ok := patchMediaType(r); !ok || mediaType != mergePatchContentType {
+		w.Header().Set("Accept-Patch", mergePatchContentType)
+		writeProblem(w, r, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, "use "+mergePatchContentType)
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	patch, err := decodeSeriesPatch(body)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permEditSeries, seriesID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todos, err := h.todoService.PatchSeries(ctx, seriesID(r), patch)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todos); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary List the trash
+// @Description Deleted Todos, with the same filters, sorting and paging as GET /v1/todos. Sorted by deleted_at (newest first) by default.
+// @Tags Trash
+// @Produce json
+// @Param limit query int false "Page size (default 50, capped at 200)"
+// @Param cursor query string false "Opaque cursor from the Link header"
+// @Param project_id query string false "Filter by project"
+// @Param title query string false "Case-insensitive title substring"
+// @Param sort query string false "Sort field" Enums(deleted_at, created_at, done_at, title, due_at, priority, urgency)
+// @Param order query string false "Sort order (default desc, asc for due_at)" Enums(asc, desc)
+// @Param If-None-Match header string false "ETag of a previously fetched page"
+// @Success 200 {array} Todo
+// @Success 304 "Page not modified"
+// @Header 200 {string} ETag "Weak ETag of the page"
+// @Failure 422 {object} Problem "Invalid query parameters"
+// @Router /v1/trash [get]
+func (h *APIHandler) ListTrash(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	query, err := parseTodoQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	query.Trashed = true
+	page, err := h.todoService.GetAllTodo(ctx, query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeTodoPage(w, r, page)
+}
+
+// @Summary Restore a Todo from the trash
+// @Description Bring a deleted Todo back with its tags and checklist. Dependencies on it were removed when it was deleted
+// @Description and are not restored. Restoring a Todo that is not in the trash returns it unchanged.
+// @Tags Trash
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {object} Todo
+// @Header 200 {string} ETag "Current version of the Todo"
+// @Failure 403 {object} Problem "Only owners can restore the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Router /v1/trash/{id}/restore [post]
+func (h *APIHandler) RestoreTodo(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permManageTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	todo, err := h.todoService.RestoreTodo(ctx, todoID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", todo.ETag())
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(todo); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Permanently delete a Todo in the trash
+// @Description Purge a deleted Todo with its tags and checklist. This cannot be undone.
+// @Tags Trash
+// @Param id path string true "Todo ID"
+// @Success 204 {string} string "Todo purged"
+// @Failure 403 {object} Problem "Only owners can purge the Todo"
+// @Failure 404 {object} Problem "Todo not found"
+// @Failure 409 {object} Problem "Todo is not in the trash, delete it first"
+// @Router /v1/trash/{id} [delete]
+func (h *APIHandler) PurgeTodo(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permManageTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.PurgeTodo(ctx, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary Empty the trash
+// @Description Permanently delete every Todo in the trash that you are an owner of. This cannot be undone.
+// @Tags Trash
+// @Produce json
+// @Success 200 {object} TrashPurgeResult
+// @Router /v1/trash [delete]
+func (h *APIHandler) EmptyTrash(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+	defer cancel()
+
+	// Chỉ owner được xóa hẳn todo: dọn thùng rác bỏ qua các todo mà người dùng chỉ là editor hoặc viewer.
+	purged, err := h.todoService.PurgeTrash(withRequiredRole(ctx, RoleOwner), time.Now())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(TrashPurgeResult{Purged: purged}); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Get the change history of a Todo
+// @Description Append-only history of the Todo, oldest first: one entry per create, update, status change, delete, restore
+// @Description and purge, with the actor, the time and the changed fields ({"title": {"from": "a", "to": "b"}}).
+// @Description The history stays readable after the Todo is trashed or purged.
+// @Tags History
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {array} HistoryEntry
+// @Failure 404 {object} Problem "Todo not found"
+// @Router /v1/todos/{id}/history [get]
+func (h *APIHandler) GetTodoHistory(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	entries, err := h.todoService.TodoHistory(ctx, todoID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary List recent activity
+// @Description History entries of every Todo, newest first. The link to the next page is sent in the Link header (rel="next").
+// @Tags History
+// @Produce json
+// @Param limit query int false "Page size (default 50, capped at 200)"
+// @Param cursor query string false "Opaque cursor from the Link header"
+// @Param actor query string false "Only changes made by this actor"
+// @Param action query string false "Comma-separated actions" Enums(created, updated, status_changed, deleted, restored, purged)
+// @Success 200 {array} HistoryEntry
+// @Header 200 {string} Link "Link to the next page"
+// @Failure 422 {object} Problem "Invalid query parameters"
+// @Router /v1/activity [get]
+func (h *APIHandler) ListActivity(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	query, err := parseActivityQuery(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	page, err := h.todoService.ListActivity(ctx, query)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if links := pageLinks(r, "", page.NextCursor); links != "" {
+		w.Header().Add("Link", links)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(page.Items); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary List users
+// @Description All users in name order. Send a user ID in the X-User-ID header to act as that user.
+// @Tags Users
+// @Produce json
+// @Success 200 {array} User
+// @Router /v1/users [get]
+func (h *APIHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	users, err := h.todoService.ListUsers(ctx)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(users); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Get a user
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} User
+// @Failure 404 {object} Problem "User not found"
+// @Router /v1/users/{id} [get]
+func (h *APIHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	user, err := h.todoService.GetUser(ctx, userID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Create a user
+// @Description Only available to tenant administration, i.e. requests without a user when authentication is disabled. With authentication enabled users are created from their token on first sign-in.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param user body UserRequest true "User"
+// @Success 201 {object} User
+// @Header 201 {string} Location "URL of the created user"
+// @Failure 403 {object} Problem "Only tenant administration can create users"
+// @Failure 409 {object} Problem "Email already registered"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/users [post]
+func (h *APIHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permManageTenant, tenantFrom(ctx)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	var input UserRequest
+	if err := decodePayload(w, r, userRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	user, err := h.todoService.CreateUser(ctx, input.user())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", userLocation(user.ID))
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(user); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary List who a Todo is shared with
+// @Tags Users
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Success 200 {array} TodoShare
+// @Failure 404 {object} Problem "Todo not found"
+// @Router /v1/todos/{id}/shares [get]
+func (h *APIHandler) ListShares(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	shares, err := h.todoService.ListShares(ctx, todoID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(shares); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Share a Todo with a user
+// @Description The user gets the given role on the Todo: viewer reads it, editor also changes it and its status,
+// @Description owner also deletes, restores, purges and shares it. The role defaults to editor; sharing again changes the role.
+// @Description Only owners can share.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "Todo ID"
+// @Param user_id path string true "ID of the user to share with"
+// @Param share body ShareRequest false "Role of the user"
+// @Success 200 {object} TodoShare
+// @Failure 403 {object} Problem "Only owners can share the Todo"
+// @Failure 404 {object} Problem "Todo or user not found"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/todos/{id}/shares/{user_id} [put]
+func (h *APIHandler) ShareTodo(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := decodeShareRole(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permManageTodo, todoID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	share, err := h.todoService.ShareTodo(ctx, todoID(r), shareUserID(r), role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(share); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Stop sharing a Todo with a user
+// @Description Owners can remove anyone; other users can only remove themselves.
+// @Tags Users
+// @Param id path string true "Todo ID"
+// @Param user_id path string true "ID of the user"
+// @Success 204 {string} string "Share removed"
+// @Failure 403 {object} Problem "Only owners can remove other users"
+// @Failure 404 {object} Problem "Todo or share not found"
+// @Router /v1/todos/{id}/shares/{user_id} [delete]
+func (h *APIHandler) UnshareTodo(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorizeUnshare(ctx, permManageTodo, todoID(r), shareUserID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.UnshareTodo(ctx, todoID(r), shareUserID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List who a project is shared with
+// @Description A shared project gives each user their role on every Todo of the project. A project nobody shared
+// @Description has no shares and everyone is its owner.
+// @Tags Projects
+// @Produce json
+// @Param id path string true "Project ID"
+// @Success 200 {array} ProjectShare
+// @Failure 403 {object} Problem "The project is not shared with you"
+// @Failure 404 {object} Problem "Project not found"
+// @Router /v1/projects/{id}/shares [get]
+func (h *APIHandler) ListProjectShares(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorize(ctx, permReadProject, projectID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	shares, err := h.todoService.ListProjectShares(ctx, projectID(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(shares); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Share a project with a user
+// @Description The user gets the role on every Todo of the project, and editors can add Todos to it. The role defaults
+// @Description to editor; sharing again changes the role. Sharing a project nobody shared yet makes you its owner.
+// @Description The default project cannot be shared.
+// @Tags Projects
+// @Accept json
+// @Produce json
+// @Param id path string true "Project ID"
+// @Param user_id path string true "ID of the user to share with"
+// @Param share body ShareRequest false "Role of the user"
+// @Success 200 {object} ProjectShare
+// @Failure 403 {object} Problem "Only owners can share the project"
+// @Failure 404 {object} Problem "Project or user not found"
+// @Failure 409 {object} Problem "Default project, or the change would leave the project without an owner"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/projects/{id}/shares/{user_id} [put]
+func (h *APIHandler) ShareProject(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	role, err := decodeShareRole(w, r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorize(ctx, permManageProject, projectID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	share, err := h.todoService.ShareProject(ctx, projectID(r), shareUserID(r), role)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(share); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Stop sharing a project with a user
+// @Description Owners can remove anyone; other users can only remove themselves. The last owner cannot be removed
+// @Description while the project is shared with other users.
+// @Tags Projects
+// @Param id path string true "Project ID"
+// @Param user_id path string true "ID of the user"
+// @Success 204 {string} string "Share removed"
+// @Failure 403 {object} Problem "Only owners can remove other users"
+// @Failure 404 {object} Problem "Project or share not found"
+// @Failure 409 {object} Problem "The project would be left without an owner"
+// @Router /v1/projects/{id}/shares/{user_id} [delete]
+func (h *APIHandler) UnshareProject(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	if err := h.authorizeUnshare(ctx, permManageProject, projectID(r), shareUserID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.todoService.UnshareProject(ctx, projectID(r), shareUserID(r)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary List your API keys
+// @Description Lists the API keys of the authenticated user, including expired and revoked ones. Tokens are never returned again.
+// @Tags Auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} APIKey
+// @Failure 401 {object} Problem "Not authenticated"
+// @Router /v1/api-keys [get]
+func (h *APIHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	owner := userFrom(r.Context())
+	if owner == "" {
+		writeUnauthorized(w, r, CodeUnauthenticated, "API keys belong to a user, authenticate first")
+		return
+	}
+	keys, err := h.apiKeys.List(ctx, owner)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(keys); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Issue an API key
+// @Description Creates an API key for automation scripts acting as the authenticated user. The token is only included in this response; send it as "Authorization: Bearer <token>".
+// @Tags Auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key body APIKeyRequest true "API key"
+// @Success 201 {object} APIKey
+// @Failure 401 {object} Problem "Not authenticated"
+// @Failure 422 {object} Problem "Validation failed"
+// @Router /v1/api-keys [post]
+func (h *APIHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	owner := userFrom(r.Context())
+	if owner == "" {
+		writeUnauthorized(w, r, CodeUnauthenticated, "API keys belong to a user, authenticate first")
+		return
+	}
+	var input APIKeyRequest
+	if err := decodePayload(w, r, apiKeyRules, &input); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	key, err := newAPIKey(owner, input.Name, input.ExpiresAt)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	created, err := h.apiKeys.Create(ctx, key)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(created); err != nil {
+		log.Println("Error encoding response:", err)
+	}
+}
+
+// @Summary Revoke an API key
+// @Tags Auth
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 {string} string "API key revoked"
+// @Failure 401 {object} Problem "Not authenticated"
+// @Failure 404 {object} Problem "API key not found"
+// @Router /v1/api-keys/{id} [delete]
+func (h *APIHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	owner := userFrom(r.Context())
+	if owner == "" {
+		writeUnauthorized(w, r, CodeUnauthenticated, "API keys belong to a user, authenticate first")
+		return
+	}
+	if err := h.apiKeys.Revoke(ctx, owner, apiKeyID(r), time.Now()); err != nil {
+		writeError(w, r, err)
+		return
+	}
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
@@ -20,9 +22,12 @@ type MockTodoStore struct {
 	mock.Mock
 }
 
-func (m *MockTodoStore) GetAllTodo(ctx context.Context) ([]Todo, error) {
-	args := m.Called()
-	return args.Get(0).([]Todo), args.Error(1)
+func (m *MockTodoStore) GetAllTodo(ctx context.Context, query TodoQuery) (*TodoPage, error) {
+	args := m.Called(query)
+	if page := args.Get(0); page != nil {
+		return page.(*TodoPage), args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
 func (m *MockTodoStore) GetTodo(ctx context.Context, id string) (*Todo, error) {
@@ -45,15 +50,355 @@ func (m *MockTodoStore) UpdateTodo(ctx context.Context, id string, todo Todo) (*
 	return args.Get(0).(*Todo), args.Error(1)
 }
 
+func (m *MockTodoStore) PatchTodo(ctx context.Context, id string, patch TodoPatch) (*Todo, error) {
+	args := m.Called(id, patch)
+	return args.Get(0).(*Todo), args.Error(1)
+}
+
 func (m *MockTodoStore) DeleteTodo(ctx context.Context, id string) error {
 	args := m.Called(id)
 	return args.Error(0)
 }
-func (m *MockTodoStore) UpdateTodoStatus(ctx context.Context, id string) error {
+func (m *MockTodoStore) UpdateTodoStatus(ctx context.Context, id string) (*Todo, error) {
+	args := m.Called(id)
+	if todo := args.Get(0); todo != nil {
+		return todo.(*Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) CompleteTodo(ctx context.Context, id string) (*Todo, error) {
+	args := m.Called(id)
+	if todo := args.Get(0); todo != nil {
+		return todo.(*Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) ReopenTodo(ctx context.Context, id string) (*Todo, error) {
+	args := m.Called(id)
+	if todo := args.Get(0); todo != nil {
+		return todo.(*Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) TransitionTodo(ctx context.Context, id string, status Status) (*Todo, error) {
+	args := m.Called(id, status)
+	if todo := args.Get(0); todo != nil {
+		return todo.(*Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) Workflow() *Workflow {
+	return DefaultWorkflow()
+}
+
+func (m *MockTodoStore) ListTags(ctx context.Context) ([]Tag, error) {
+	args := m.Called()
+	if tags := args.Get(0); tags != nil {
+		return tags.([]Tag), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) GetTag(ctx context.Context, id string) (*Tag, error) {
+	args := m.Called(id)
+	if tag := args.Get(0); tag != nil {
+		return tag.(*Tag), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) CreateTag(ctx context.Context, tag Tag) (*Tag, error) {
+	args := m.Called(tag)
+	if created := args.Get(0); created != nil {
+		return created.(*Tag), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) UpdateTag(ctx context.Context, id string, tag Tag) (*Tag, error) {
+	args := m.Called(id, tag)
+	if updated := args.Get(0); updated != nil {
+		return updated.(*Tag), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) DeleteTag(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTodoStore) TagRole(ctx context.Context, id string) (Role, error) {
+	args := m.Called(id)
+	return args.Get(0).(Role), args.Error(1)
+}
+
+func (m *MockTodoStore) ListProjects(ctx context.Context) ([]Project, error) {
+	args := m.Called()
+	if projects := args.Get(0); projects != nil {
+		return projects.([]Project), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) GetProject(ctx context.Context, id string) (*Project, error) {
+	args := m.Called(id)
+	if project := args.Get(0); project != nil {
+		return project.(*Project), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) CreateProject(ctx context.Context, project Project) (*Project, error) {
+	args := m.Called(project)
+	if created := args.Get(0); created != nil {
+		return created.(*Project), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) UpdateProject(ctx context.Context, id string, project Project) (*Project, error) {
+	args := m.Called(id, project)
+	if updated := args.Get(0); updated != nil {
+		return updated.(*Project), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) DeleteProject(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTodoStore) ListChecklist(ctx context.Context, todoID string) ([]ChecklistItem, error) {
+	args := m.Called(todoID)
+	if items := args.Get(0); items != nil {
+		return items.([]ChecklistItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) GetChecklistItem(ctx context.Context, todoID, itemID string) (*ChecklistItem, error) {
+	args := m.Called(todoID, itemID)
+	if item := args.Get(0); item != nil {
+		return item.(*ChecklistItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) AddChecklistItem(ctx context.Context, todoID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	args := m.Called(todoID, patch)
+	if item := args.Get(0); item != nil {
+		return item.(*ChecklistItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) UpdateChecklistItem(ctx context.Context, todoID, itemID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	args := m.Called(todoID, itemID, patch)
+	if item := args.Get(0); item != nil {
+		return item.(*ChecklistItem), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) DeleteChecklistItem(ctx context.Context, todoID, itemID string) error {
+	args := m.Called(todoID, itemID)
+	return args.Error(0)
+}
+
+func (m *MockTodoStore) AddBlocker(ctx context.Context, todoID, blockerID string) (*Todo, error) {
+	args := m.Called(todoID, blockerID)
+	if todo := args.Get(0); todo != nil {
+		return todo.(*Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) RemoveBlocker(ctx context.Context, todoID, blockerID string) error {
+	args := m.Called(todoID, blockerID)
+	return args.Error(0)
+}
+
+func (m *MockTodoStore) OrderProjectTodos(ctx context.Context, projectID string) ([]Todo, error) {
+	args := m.Called(projectID)
+	if todos := args.Get(0); todos != nil {
+		return todos.([]Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) RestoreTodo(ctx context.Context, id string) (*Todo, error) {
+	args := m.Called(id)
+	if todo := args.Get(0); todo != nil {
+		return todo.(*Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) PurgeTodo(ctx context.Context, id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockTodoStore) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTodoStore) TodoHistory(ctx context.Context, todoID string) ([]HistoryEntry, error) {
+	args := m.Called(todoID)
+	if entries := args.Get(0); entries != nil {
+		return entries.([]HistoryEntry), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) ListActivity(ctx context.Context, query ActivityQuery) (*ActivityPage, error) {
+	args := m.Called(query)
+	if page := args.Get(0); page != nil {
+		return page.(*ActivityPage), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) ListUsers(ctx context.Context) ([]User, error) {
+	args := m.Called()
+	if users := args.Get(0); users != nil {
+		return users.([]User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) GetUser(ctx context.Context, id string) (*User, error) {
+	args := m.Called(id)
+	if user := args.Get(0); user != nil {
+		return user.(*User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) CreateUser(ctx context.Context, user User) (*User, error) {
+	args := m.Called(user)
+	if created := args.Get(0); created != nil {
+		return created.(*User), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) ListShares(ctx context.Context, todoID string) ([]TodoShare, error) {
+	args := m.Called(todoID)
+	if shares := args.Get(0); shares != nil {
+		return shares.([]TodoShare), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) ShareTodo(ctx context.Context, todoID, userID string, role Role) (*TodoShare, error) {
+	args := m.Called(todoID, userID, role)
+	if share := args.Get(0); share != nil {
+		return share.(*TodoShare), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) UnshareTodo(ctx context.Context, todoID, userID string) error {
+	args := m.Called(todoID, userID)
+	return args.Error(0)
+}
+
+func (m *MockTodoStore) TodoRole(ctx context.Context, id string) (Role, error) {
+	args := m.Called(id)
+	return args.Get(0).(Role), args.Error(1)
+}
+
+func (m *MockTodoStore) ProjectRole(ctx context.Context, id string) (Role, error) {
 	args := m.Called(id)
+	return args.Get(0).(Role), args.Error(1)
+}
+
+func (m *MockTodoStore) ListProjectShares(ctx context.Context, projectID string) ([]ProjectShare, error) {
+	args := m.Called(projectID)
+	if shares := args.Get(0); shares != nil {
+		return shares.([]ProjectShare), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) ShareProject(ctx context.Context, projectID, userID string, role Role) (*ProjectShare, error) {
+	args := m.Called(projectID, userID, role)
+	if share := args.Get(0); share != nil {
+		return share.(*ProjectShare), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) UnshareProject(ctx context.Context, projectID, userID string) error {
+	args := m.Called(projectID, userID)
 	return args.Error(0)
 }
 
+func (m *MockTodoStore) GetSeries(ctx context.Context, seriesID string) ([]Todo, error) {
+	args := m.Called(seriesID)
+	if todos := args.Get(0); todos != nil {
+		return todos.([]Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) PatchSeries(ctx context.Context, seriesID string, patch TodoPatch) ([]Todo, error) {
+	args := m.Called(seriesID, patch)
+	if todos := args.Get(0); todos != nil {
+		return todos.([]Todo), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTodoStore) BulkCreateTodos(ctx context.Context, todos []Todo, opts BulkOptions) ([]BulkResult, error) {
+	args := m.Called(todos, opts)
+	return args.Get(0).([]BulkResult), args.Error(1)
+}
+
+func (m *MockTodoStore) BulkSetDone(ctx context.Context, items []BulkItem, done bool, opts BulkOptions) ([]BulkResult, error) {
+	args := m.Called(items, done, opts)
+	return args.Get(0).([]BulkResult), args.Error(1)
+}
+
+func (m *MockTodoStore) BulkPatchTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	args := m.Called(items, opts)
+	return args.Get(0).([]BulkResult), args.Error(1)
+}
+
+func (m *MockTodoStore) BulkDeleteTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	args := m.Called(items, opts)
+	return args.Get(0).([]BulkResult), args.Error(1)
+}
+
+func newMemoryHandler(t *testing.T) (*APIHandler, *MemoryTodoService) {
+	t.Helper()
+	store := NewMemoryTodoService()
+	return NewAPIHandler(store, NewMemoryIdempotencyStore()), store
+}
+
+func withID(req *http.Request, id string) *http.Request {
+	return mux.SetURLVars(req, map[string]string{"id": id})
+}
+
+func decodeProblem(t *testing.T, rr *httptest.ResponseRecorder) Problem {
+	t.Helper()
+	assert.Equal(t, problemContentType, rr.Header().Get("Content-Type"))
+	var p Problem
+	if err := json.Unmarshal(rr.Body.Bytes(), &p); err != nil {
+		t.Fatalf("response is not a problem document: %v (%s)", err, rr.Body.String())
+	}
+	assert.Equal(t, rr.Code, p.Status)
+	return p
+}
+
 func TestGetAllTodo(t *testing.T) {
 	mockStore := new(MockTodoStore)
 	handler := &APIHandler{todoService: mockStore}
@@ -68,7 +413,7 @@ func TestGetAllTodo(t *testing.T) {
 		},
 	}
 
-	mockStore.On("GetAllTodo").Return(mockData, nil)
+	mockStore.On("GetAllTodo", TodoQuery{}).Return(&TodoPage{Items: mockData}, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/todo", nil)
 	rr := httptest.NewRecorder()
@@ -129,20 +474,20 @@ func TestGetTodo(t *testing.T) {
 	})
 
 	t.Run("Test Todo Not Found", func(t *testing.T) {
-		mockStore.On("GetTodo", "not_found").Return(nil, fmt.Errorf("Todo not found"))
+		mockStore.On("GetTodo", "not_found").Return(nil, fmt.Errorf("lookup: %w", todoNotFound("not_found")))
 		req, err := http.NewRequest("GET", "/todo/getuser/not_found", nil)
 		if err != nil {
 			t.Fatalf("Could not create request: %v", err)
 		}
 
 		rr := httptest.NewRecorder()
-		handler.GetTodo(rr, req)
+		handler.GetTodo(rr, withID(req, "not_found"))
 
 		assert.Equal(t, http.StatusNotFound, rr.Code)
 
-		expected := "Todo not found"
-		actual := strings.TrimSpace(rr.Body.String()) // Loại bỏ newline hoặc khoảng trắng thừa
-		assert.Equal(t, expected, actual)
+		problem := decodeProblem(t, rr)
+		assert.Equal(t, CodeTodoNotFound, problem.Code)
+		assert.Equal(t, "/todo/getuser/not_found", problem.Instance)
 
 		mockStore.AssertExpectations(t)
 	})
@@ -158,14 +503,14 @@ func TestGetTodo(t *testing.T) {
 			Title:     "Test Todo",
 			Desc:      "This is a test todo",
 			Done:      false,
-			CreatedAt: time.Date(2024, time.November, 8, 15, 45, 50, 681403600, time.Local),
+			CreatedAt: time.Date(2024, time.November, 8, 15, 45, 50, 681403600, time.UTC),
 			DoneAt:    nil,
 		}
 
 		mockStore.On("GetTodo", "1").Return(mockData, nil) // Trả về con trỏ mockData
 
 		rr := httptest.NewRecorder()
-		handler.GetTodo(rr, req)
+		handler.GetTodo(rr, withID(req, "1"))
 
 		assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -186,10 +531,9 @@ func TestCreateTodo_Success(t *testing.T) {
 	mockStore := new(MockTodoStore)
 	handler := &APIHandler{todoService: mockStore}
 
-	todoRequest := Todo{
+	todoRequest := CreateTodoRequest{
 		Title: "Test Todo",
 		Desc:  "This is a test todo",
-		Done:  false,
 	}
 
 	expectedTodo := &Todo{
@@ -241,8 +585,8 @@ func TestUpdateTodo(t *testing.T) {
 		handler.UpdateTodo(rr, req)
 
 		assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
-		expected := "Method not allowed\n" // Kiểm tra cả ký tự xuống dòng
-		assert.Equal(t, expected, rr.Body.String())
+		assert.Equal(t, http.MethodPatch, rr.Header().Get("Allow"))
+		assert.Equal(t, CodeMethodNotAllowed, decodeProblem(t, rr).Code)
 	})
 
 	t.Run("Test Missing ID", func(t *testing.T) {
@@ -261,41 +605,43 @@ func TestUpdateTodo(t *testing.T) {
 			t.Fatal(err)
 		}
 		rr := httptest.NewRecorder()
-		handler.UpdateTodo(rr, req)
+		handler.UpdateTodo(rr, withID(req, "1"))
 
 		assert.Equal(t, http.StatusBadRequest, rr.Code)
-		assert.Equal(t, "Invalid request body\n", rr.Body.String())
+		assert.Equal(t, CodeInvalidBody, decodeProblem(t, rr).Code)
 	})
 
 	t.Run("Test Todo Not Found", func(t *testing.T) {
 		todoID := "not_found"
-		mockStore.On("UpdateTodo", todoID, Todo{}).Return(&Todo{}, errors.New("not found"))
+		mockStore.On("PatchTodo", todoID, TodoPatch{}).Return(&Todo{}, todoNotFound(todoID))
 
 		req, err := http.NewRequest("PATCH", "/todo/update/"+todoID, bytes.NewBufferString("{}"))
 		if err != nil {
 			t.Fatal(err)
 		}
 		rr := httptest.NewRecorder()
-		handler.UpdateTodo(rr, req)
+		handler.UpdateTodo(rr, withID(req, todoID))
 
 		assert.Equal(t, http.StatusNotFound, rr.Code)
-		assert.Equal(t, "Todo not found\n", rr.Body.String())
+		assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
 		mockStore.AssertExpectations(t)
 	})
 
 	t.Run("Test Failed to Update Todo", func(t *testing.T) {
 		todoID := "1"
-		mockStore.On("UpdateTodo", todoID, Todo{}).Return(&Todo{}, errors.New("todo not found"))
+		mockStore.On("PatchTodo", todoID, TodoPatch{}).Return(&Todo{}, errors.New("todo not found"))
 
 		req, err := http.NewRequest("PATCH", "/todo/update/"+todoID, bytes.NewBufferString("{}"))
 		if err != nil {
 			t.Fatal(err)
 		}
 		rr := httptest.NewRecorder()
-		handler.UpdateTodo(rr, req)
+		handler.UpdateTodo(rr, withID(req, todoID))
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
-		assert.Equal(t, "Failed to update todo\n", rr.Body.String())
+		problem := decodeProblem(t, rr)
+		assert.Equal(t, CodeInternal, problem.Code)
+		assert.NotContains(t, problem.Detail, "todo not found", "internal errors are not leaked")
 		mockStore.AssertExpectations(t)
 	})
 
@@ -312,16 +658,17 @@ func TestUpdateTodo(t *testing.T) {
 			DoneAt:    nil,
 		}
 
-		mockStore.On("UpdateTodo", todoID, Todo{
-			Title: "Updated Todo",
-			Desc:  "This is an updated todo",
-			Done:  true,
+		title, desc, done := "Updated Todo", "This is an updated todo", true
+		mockStore.On("PatchTodo", todoID, TodoPatch{
+			Title: &title,
+			Desc:  &desc,
+			Done:  &done,
 		}).Return(updatedTodo, nil)
 
-		reqBody, err := json.Marshal(Todo{
-			Title: "Updated Todo",
-			Desc:  "This is an updated todo",
-			Done:  true,
+		reqBody, err := json.Marshal(map[string]interface{}{
+			"title": title,
+			"desc":  desc,
+			"done":  done,
 		})
 		if err != nil {
 			t.Fatal(err)
@@ -333,7 +680,7 @@ func TestUpdateTodo(t *testing.T) {
 		}
 
 		rr := httptest.NewRecorder()
-		handler.UpdateTodo(rr, req)
+		handler.UpdateTodo(rr, withID(req, todoID))
 
 		assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -374,8 +721,7 @@ func TestUpdateTodoStatus(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		mockStore.ExpectedCalls = nil
 
-		mockStore.On("UpdateTodoStatus", todoID).Return(nil)
-		mockStore.On("GetTodo", todoID).Return(updatedTodo, nil)
+		mockStore.On("UpdateTodoStatus", todoID).Return(updatedTodo, nil)
 
 		reqBody, err := json.Marshal(map[string]bool{"done": true})
 		assert.NoError(t, err)
@@ -384,7 +730,7 @@ func TestUpdateTodoStatus(t *testing.T) {
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
-		handler.UpdateTodoStatus(rr, req)
+		handler.UpdateTodoStatus(rr, withID(req, todoID))
 
 		assert.Equal(t, http.StatusOK, rr.Code)
 
@@ -403,7 +749,7 @@ func TestUpdateTodoStatus(t *testing.T) {
 	t.Run("Error_UpdateTodoStatus", func(t *testing.T) {
 		mockStore.ExpectedCalls = nil
 
-		mockStore.On("UpdateTodoStatus", todoID).Return(assert.AnError)
+		mockStore.On("UpdateTodoStatus", todoID).Return(nil, assert.AnError)
 
 		reqBody, err := json.Marshal(map[string]bool{"done": true})
 		assert.NoError(t, err)
@@ -412,19 +758,18 @@ func TestUpdateTodoStatus(t *testing.T) {
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
-		handler.UpdateTodoStatus(rr, req)
+		handler.UpdateTodoStatus(rr, withID(req, todoID))
 
 		assert.Equal(t, http.StatusInternalServerError, rr.Code)
-		assert.Contains(t, rr.Body.String(), "Error updating todo status:")
+		assert.Equal(t, CodeInternal, decodeProblem(t, rr).Code)
 
 		mockStore.AssertExpectations(t)
 	})
 
-	t.Run("Error_GetTodo", func(t *testing.T) {
+	t.Run("Error_TodoNotFound", func(t *testing.T) {
 		mockStore.ExpectedCalls = nil
 
-		mockStore.On("UpdateTodoStatus", todoID).Return(nil)
-		mockStore.On("GetTodo", todoID).Return(nil, assert.AnError)
+		mockStore.On("UpdateTodoStatus", todoID).Return(nil, todoNotFound(todoID))
 
 		reqBody, err := json.Marshal(map[string]bool{"done": true})
 		assert.NoError(t, err)
@@ -433,10 +778,10 @@ func TestUpdateTodoStatus(t *testing.T) {
 		assert.NoError(t, err)
 
 		rr := httptest.NewRecorder()
-		handler.UpdateTodoStatus(rr, req)
+		handler.UpdateTodoStatus(rr, withID(req, todoID))
 
-		assert.Equal(t, http.StatusInternalServerError, rr.Code)
-		assert.Contains(t, rr.Body.String(), "Error retrieving updated todo")
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
 
 		mockStore.AssertExpectations(t)
 	})
@@ -462,13 +807,13 @@ func TestDeleteTodo(t *testing.T) {
 		req := httptest.NewRequest(http.MethodDelete, "/todo/delete/1", nil)
 		w := httptest.NewRecorder()
 
-		mockStore.On("DeleteTodo", "1").Return(fmt.Errorf("not found"))
+		mockStore.On("DeleteTodo", "1").Return(todoNotFound("1"))
 
-		handler.DeleteTodo(w, req)
+		handler.DeleteTodo(w, withID(req, "1"))
 
 		res := w.Result()
 		assert.Equal(t, http.StatusNotFound, res.StatusCode)
-		assert.Equal(t, "not found\n", w.Body.String())
+		assert.Equal(t, CodeTodoNotFound, decodeProblem(t, w).Code)
 
 		mockStore.AssertExpectations(t)
 	})
@@ -482,7 +827,7 @@ func TestDeleteTodo(t *testing.T) {
 		}
 
 		rr := httptest.NewRecorder()
-		handler.DeleteTodo(rr, req)
+		handler.DeleteTodo(rr, withID(req, todoID))
 
 		assert.Equal(t, http.StatusNoContent, rr.Code)
 		assert.Empty(t, rr.Body.String())
@@ -490,3 +835,1558 @@ func TestDeleteTodo(t *testing.T) {
 		mockStore.AssertExpectations(t)
 	})
 }
+
+func TestMemoryStore_CRUDFlow(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+
+	reqBody, err := json.Marshal(CreateTodoRequest{Title: "Write migration", Desc: "todo table"})
+	assert.NoError(t, err)
+	rr := httptest.NewRecorder()
+	handler.CreateTodo(rr, httptest.NewRequest(http.MethodPost, "/todo/create", bytes.NewReader(reqBody)))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var created Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+	assert.NotEmpty(t, created.ID)
+	assert.False(t, created.Done)
+
+	rr = httptest.NewRecorder()
+	handler.GetTodo(rr, withID(httptest.NewRequest(http.MethodGet, "/todo/getuser/"+created.ID, nil), created.ID))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.UpdateTodoStatus(rr, withID(httptest.NewRequest(http.MethodPatch, "/todo/update-status/"+created.ID, nil), created.ID))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var toggled Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&toggled))
+	assert.True(t, toggled.Done)
+	assert.NotNil(t, toggled.DoneAt)
+
+	reqBody, err = json.Marshal(map[string]interface{}{"title": "Write migrations", "desc": "todo table", "done": false})
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	handler.UpdateTodo(rr, withID(httptest.NewRequest(http.MethodPatch, "/todo/update/"+created.ID, bytes.NewReader(reqBody)), created.ID))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var updated Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&updated))
+	assert.Equal(t, "Write migrations", updated.Title)
+	assert.False(t, updated.Done)
+	assert.Nil(t, updated.DoneAt)
+
+	rr = httptest.NewRecorder()
+	handler.DeleteTodo(rr, withID(httptest.NewRequest(http.MethodDelete, "/todo/delete/"+created.ID, nil), created.ID))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	page, err := store.GetAllTodo(context.Background(), TodoQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+}
+
+func TestMemoryStore_NotFound(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.GetTodo(rr, withID(httptest.NewRequest(http.MethodGet, "/todo/getuser/missing", nil), "missing"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.UpdateTodo(rr, withID(httptest.NewRequest(http.MethodPatch, "/todo/update/missing", bytes.NewBufferString(`{"title":"x"}`)), "missing"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.DeleteTodo(rr, withID(httptest.NewRequest(http.MethodDelete, "/todo/delete/missing", nil), "missing"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestMemoryStore_GetAllTodoOrder(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+
+	first, err := store.CreateTodo(context.Background(), Todo{Title: "first"})
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	second, err := store.CreateTodo(context.Background(), Todo{Title: "second"})
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.GetAllTodo(rr, httptest.NewRequest(http.MethodGet, "/todo", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	if assert.Len(t, todos, 2) {
+		assert.Equal(t, second.ID, todos[0].ID)
+		assert.Equal(t, first.ID, todos[1].ID)
+	}
+}
+
+func TestErrorMapping(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"not found", todoNotFound("1"), http.StatusNotFound, CodeTodoNotFound},
+		{"wrapped not found", fmt.Errorf("query: %w", todoNotFound("1")), http.StatusNotFound, CodeTodoNotFound},
+		{"validation", &ValidationError{Fields: []FieldError{{Field: "title", Code: "required", Message: "title is required"}}}, http.StatusUnprocessableEntity, CodeValidationFailed},
+		{"conflict", fmt.Errorf("insert: %w", ErrConflict), http.StatusConflict, CodeConflict},
+		{"unavailable", fmt.Errorf("query: %w", ErrUnavailable), http.StatusServiceUnavailable, CodeServiceUnavailable},
+		{"unknown", errors.New("boom"), http.StatusInternalServerError, CodeInternal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			writeError(rr, httptest.NewRequest(http.MethodGet, "/todo", nil), tc.err)
+
+			assert.Equal(t, tc.status, rr.Code)
+			problem := decodeProblem(t, rr)
+			assert.Equal(t, tc.code, problem.Code)
+			assert.Equal(t, "/problems/"+tc.code, problem.Type)
+		})
+	}
+}
+
+func TestMemoryStore_ValidationProblem(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.CreateTodo(rr, httptest.NewRequest(http.MethodPost, "/todo/create", bytes.NewBufferString(`{"title":"   "}`)))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, CodeValidationFailed, problem.Code)
+	if assert.Len(t, problem.Errors, 1) {
+		assert.Equal(t, "title", problem.Errors[0].Field)
+		assert.Equal(t, "required", problem.Errors[0].Code)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", "*")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	fieldCodes := func(p Problem) map[string]string {
+		codes := make(map[string]string)
+		for _, fe := range p.Errors {
+			codes[fe.Field] = fe.Code
+		}
+		return codes
+	}
+
+	t.Run("All violations are reported at once", func(t *testing.T) {
+		body := fmt.Sprintf(`{"id":"x","done":true,"created_at":"2024-01-01T00:00:00Z","title":%q,"desc":7,"colour":"red"}`,
+			strings.Repeat("a", maxTitleLength+1))
+		rr := send(http.MethodPost, "/v1/todos", body)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, map[string]string{
+			"id":         "read_only",
+			"created_at": "read_only",
+			"done":       "not_allowed",
+			"title":      "too_long",
+			"desc":       "invalid_type",
+			"colour":     "unknown_field",
+		}, fieldCodes(decodeProblem(t, rr)))
+	})
+
+	t.Run("Missing title", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", `{"desc":"no title"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, map[string]string{"title": "required"}, fieldCodes(decodeProblem(t, rr)))
+	})
+
+	t.Run("Title is trimmed", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", `{"title":"  Padded  "}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		var created Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+		assert.Equal(t, "Padded", created.Title)
+
+		rr = send(http.MethodPatch, "/v1/todos/"+created.ID, `{"title":"  Renamed "}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		got, err := store.GetTodo(context.Background(), created.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Renamed", got.Title)
+
+		rr = send(http.MethodPut, "/v1/todos/"+created.ID, `{"title":"Replaced","done":"yes"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, map[string]string{"done": "invalid_type"}, fieldCodes(decodeProblem(t, rr)))
+	})
+
+	t.Run("Body size is capped", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", `{"title":"big","desc":"`+strings.Repeat("x", maxBodyBytes)+`"}`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+		assert.Equal(t, CodePayloadTooLarge, decodeProblem(t, rr).Code)
+	})
+
+	t.Run("Body must be a JSON object", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", `[{"title":"x"}]`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, CodeInvalidBody, decodeProblem(t, rr).Code)
+	})
+}
+
+func TestGetAllTodo_PaginationLinks(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	for i := 0; i < 3; i++ {
+		_, err := store.CreateTodo(context.Background(), Todo{Title: fmt.Sprintf("item %d", i)})
+		assert.NoError(t, err)
+	}
+
+	rr := httptest.NewRecorder()
+	handler.GetAllTodo(rr, httptest.NewRequest(http.MethodGet, "/todo?limit=2&done=false", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Len(t, todos, 2)
+
+	link := rr.Header().Get("Link")
+	assert.Contains(t, link, `rel="next"`)
+	assert.NotContains(t, link, `rel="prev"`)
+	assert.Contains(t, link, "done=false")
+
+	next := link[strings.Index(link, "<")+1 : strings.Index(link, ">")]
+	rr = httptest.NewRecorder()
+	handler.GetAllTodo(rr, httptest.NewRequest(http.MethodGet, next, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Len(t, todos, 1)
+	assert.Contains(t, rr.Header().Get("Link"), `rel="prev"`)
+}
+
+func TestGetAllTodo_InvalidQuery(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+
+	rr := httptest.NewRecorder()
+	handler.GetAllTodo(rr, httptest.NewRequest(http.MethodGet, "/todo?limit=abc&done=maybe&created_after=yesterday", nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	problem := decodeProblem(t, rr)
+	var fields []string
+	for _, fe := range problem.Errors {
+		fields = append(fields, fe.Field)
+	}
+	assert.ElementsMatch(t, []string{"limit", "done", "created_after"}, fields)
+}
+
+func TestRouter_V1Resources(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/todos", bytes.NewBufferString(`{"title":"Route by resource"}`)))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var created Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+	assert.Equal(t, "/v1/todos/"+created.ID, rr.Header().Get("Location"))
+	assert.Empty(t, rr.Header().Get("Deprecation"))
+
+	location := rr.Header().Get("Location")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, location, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/todos/"+created.ID+"/toggle", nil)
+	req.Header.Set("If-Match", created.ETag())
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var toggled Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&toggled))
+	assert.True(t, toggled.Done)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/todos", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Len(t, todos, 1)
+
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodDelete, "/v1/todos/"+created.ID, nil)
+	req.Header.Set("If-Match", toggled.ETag())
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/todos/"+created.ID, nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/v1/todos", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, CodeMethodNotAllowed, decodeProblem(t, rr).Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v2/todos", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeNotFound, decodeProblem(t, rr).Code)
+}
+
+func TestRouter_CompleteReopen(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	created, err := store.CreateTodo(context.Background(), Todo{Title: "Ship it"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	post := func(path, ifMatch string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Gửi lại /complete (ví dụ client retry) không đảo trạng thái và không tăng version.
+	for i := 0; i < 2; i++ {
+		rr := post("/v1/todos/"+created.ID+"/complete", "")
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var todo Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+		assert.True(t, todo.Done)
+		assert.Equal(t, 2, todo.Version)
+		assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+	}
+
+	rr := post("/v1/todos/"+created.ID+"/reopen", created.ETag())
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Equal(t, CodePreconditionFailed, decodeProblem(t, rr).Code)
+
+	rr = post("/v1/todos/"+created.ID+"/reopen", `"2"`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var reopened Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&reopened))
+	assert.False(t, reopened.Done)
+	assert.Nil(t, reopened.DoneAt)
+
+	rr = post("/v1/todos/"+generateNewID()+"/complete", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/v1/todos/"+created.ID+"/complete", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestRouter_Workflow(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodGet, "/v1/workflow", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var wf WorkflowResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&wf))
+	assert.Equal(t, StatusBacklog, wf.Initial)
+	assert.Contains(t, wf.Statuses, StatusBlocked)
+	assert.Equal(t, []Status{StatusBacklog}, wf.Transitions[StatusCancelled])
+
+	rr = send(http.MethodPost, "/v1/todos", "", `{"title":"Plan sprint","status":"in_progress"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "not_allowed", decodeProblem(t, rr).Errors[0].Code)
+
+	created, err := store.CreateTodo(context.Background(), Todo{Title: "Plan sprint"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	path := "/v1/todos/" + created.ID
+
+	rr = send(http.MethodPost, path+"/status", "", `{"status":"blocked"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var blocked Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&blocked))
+	assert.Equal(t, StatusBlocked, blocked.Status)
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+	rr = send(http.MethodPost, path+"/status", "", `{"status":"done"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	problem := decodeProblem(t, rr)
+	assert.Equal(t, CodeInvalidTransition, problem.Code)
+	assert.Contains(t, problem.Detail, "allowed: backlog, in_progress, cancelled")
+
+	rr = send(http.MethodPost, path+"/toggle", blocked.ETag(), "")
+	assert.Equal(t, http.StatusConflict, rr.Code, "older clients cannot complete a blocked todo either")
+
+	rr = send(http.MethodPost, path+"/status", "", `{"status":"someday"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "status", decodeProblem(t, rr).Errors[0].Field)
+
+	rr = send(http.MethodPost, path+"/status", "", `{}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = send(http.MethodPatch, path, blocked.ETag(), `{"status":"in_progress"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = send(http.MethodPut, path, "*", `{"title":"Plan sprint","status":"cancelled","done":true}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "conflicts_with_status", decodeProblem(t, rr).Errors[0].Code)
+
+	rr = send(http.MethodPut, path, "*", `{"title":"Plan sprint","done":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var done Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&done))
+	assert.Equal(t, StatusDone, done.Status)
+	assert.NotNil(t, done.DoneAt)
+
+	rr = send(http.MethodPatch, path, "*", `{"status_times":{}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "read_only", decodeProblem(t, rr).Errors[0].Code)
+
+	rr = send(http.MethodGet, "/v1/todos?status=done,blocked", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Len(t, todos, 1)
+
+	rr = send(http.MethodGet, "/v1/todos?status=backlog", "", "")
+	todos = nil
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Empty(t, todos)
+}
+
+func TestRouter_Planning(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	list := func(query string) []string {
+		rr := send(http.MethodGet, "/v1/todos?"+query, "")
+		assert.Equal(t, http.StatusOK, rr.Code, query)
+		var todos []Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+
+	rr := send(http.MethodPost, "/v1/todos", `{"title":"Ship release","priority":"urgent","due_at":"2020-01-31T17:00:00Z","estimate":120}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var release Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&release))
+	assert.Equal(t, PriorityUrgent, release.Priority)
+	assert.Equal(t, 120, *release.Estimate)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Water plants","priority":"low","due_at":"2999-01-01T00:00:00Z"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Read book"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"priority":"normal"`)
+
+	assert.Equal(t, []string{"Ship release"}, list("overdue=true"))
+	assert.Equal(t, []string{"Ship release", "Read book"}, list("min_priority=normal&sort=urgency"))
+	assert.Equal(t, []string{"Ship release", "Water plants", "Read book"}, list("sort=due_at"))
+	assert.Equal(t, []string{"Water plants"}, list("priority=low,high"))
+	assert.Equal(t, []string{"Water plants"}, list("due_after=2100-01-01T00:00:00Z"))
+
+	rr = send(http.MethodGet, "/v1/todos?priority=someday&overdue=maybe&due_before=soon", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	var fields []string
+	for _, fe := range decodeProblem(t, rr).Errors {
+		fields = append(fields, fe.Field)
+	}
+	assert.ElementsMatch(t, []string{"priority", "overdue", "due_before"}, fields)
+
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Bad","priority":"asap","due_at":"tomorrow","estimate":0}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	codes := map[string]string{}
+	for _, fe := range decodeProblem(t, rr).Errors {
+		codes[fe.Field] = fe.Code
+	}
+	assert.Equal(t, map[string]string{"priority": "invalid_value", "due_at": "invalid_format", "estimate": "invalid_type"}, codes)
+
+	rr = send(http.MethodPost, "/v1/todos", fmt.Sprintf(`{"title":"Forever","estimate":%d}`, maxEstimateMinutes+1))
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "out_of_range", decodeProblem(t, rr).Errors[0].Code)
+
+	path := "/v1/todos/" + release.ID
+	rr = send(http.MethodPatch, path, `{"due_at":null,"estimate":null,"priority":"high"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var patched Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&patched))
+	assert.Nil(t, patched.DueAt)
+	assert.Nil(t, patched.Estimate)
+	assert.Equal(t, PriorityHigh, patched.Priority)
+
+	rr = send(http.MethodPatch, path, `{"priority":null}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func TestRouter_Tags(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodPost, "/v1/tags", `{"name":" Frontend ","color":"#1E90FF"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var frontend Tag
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&frontend))
+	assert.Equal(t, "frontend", frontend.Name)
+	assert.Equal(t, "/v1/tags/"+frontend.ID, rr.Header().Get("Location"))
+
+	rr = send(http.MethodPost, "/v1/tags", `{"name":"frontend"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = send(http.MethodPost, "/v1/tags", `{"name":"","todo_count":3}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Len(t, decodeProblem(t, rr).Errors, 2)
+
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Scan codes","tags":["qr","frontend"]}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var scan Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&scan))
+	assert.Equal(t, []string{"frontend", "qr"}, scan.Tags)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Print codes","tags":["qr","print"]}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Bad","tags":["ok",7]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "tags[1]", decodeProblem(t, rr).Errors[0].Field)
+
+	list := func(query string) []string {
+		rr := send(http.MethodGet, "/v1/todos?"+query, "")
+		assert.Equal(t, http.StatusOK, rr.Code, query)
+		var todos []Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+		var titles []string
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		return titles
+	}
+	assert.Equal(t, []string{"Print codes", "Scan codes"}, list("tag=frontend,print&sort=title&order=asc"))
+	assert.Equal(t, []string{"Scan codes"}, list("tag=qr&tag=frontend&tag_match=all"))
+	rr = send(http.MethodGet, "/v1/todos?tag=qr&tag_match=most", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = send(http.MethodPatch, "/v1/todos/"+scan.ID, `{"tags":null}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"tags":[]`)
+
+	rr = send(http.MethodGet, "/v1/tags", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var tags []Tag
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&tags))
+	if assert.Len(t, tags, 3) {
+		assert.Equal(t, "frontend", tags[0].Name)
+		assert.Equal(t, 0, tags[0].TodoCount)
+	}
+
+	rr = send(http.MethodPut, "/v1/tags/"+frontend.ID, `{"name":"web"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodGet, "/v1/tags/"+frontend.ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"name":"web"`)
+
+	rr = send(http.MethodDelete, "/v1/tags/"+frontend.ID, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodGet, "/v1/tags/"+frontend.ID, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "tag_not_found", decodeProblem(t, rr).Code)
+
+	remaining, err := store.ListTags(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, remaining, 2)
+}
+
+func TestRouter_Projects(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodPost, "/v1/projects", `{"name":"qr","description":"QR scanning"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var qr Project
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&qr))
+	assert.Equal(t, "/v1/projects/"+qr.ID, rr.Header().Get("Location"))
+
+	rr = send(http.MethodPost, "/v1/projects", `{"name":"","open_count":1}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Scan","project_id":"`+qr.ID+`"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Inbox item"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"project_id":"default"`)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Lost","project_id":"nope"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "unknown_project", decodeProblem(t, rr).Errors[0].Code)
+
+	rr = send(http.MethodGet, "/v1/projects/"+qr.ID+"/todos?limit=10", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	if assert.Len(t, todos, 1) {
+		assert.Equal(t, "Scan", todos[0].Title)
+	}
+	rr = send(http.MethodGet, "/v1/todos?project_id=default", "")
+	todos = nil
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Len(t, todos, 1)
+	rr = send(http.MethodGet, "/v1/projects/nope/todos", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "project_not_found", decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodGet, "/v1/projects", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var projects []Project
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&projects))
+	assert.Len(t, projects, 2)
+
+	rr = send(http.MethodPut, "/v1/projects/"+qr.ID, `{"name":"qr codes"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodGet, "/v1/projects/"+qr.ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"open_count":1`)
+	assert.Contains(t, rr.Body.String(), `"name":"qr codes"`)
+
+	rr = send(http.MethodDelete, "/v1/projects/"+qr.ID, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeProjectNotEmpty, decodeProblem(t, rr).Code)
+	rr = send(http.MethodDelete, "/v1/projects/default", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeDefaultProject, decodeProblem(t, rr).Code)
+}
+
+func TestRouter_Checklist(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+	todo, err := store.CreateTodo(context.Background(), Todo{Title: "Release 1.2"})
+	assert.NoError(t, err)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	base := "/v1/todos/" + todo.ID + "/checklist"
+
+	rr := send(http.MethodPost, base, `{"title":"tag release"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var first ChecklistItem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&first))
+	assert.Equal(t, base+"/"+first.ID, rr.Header().Get("Location"))
+	rr = send(http.MethodPost, base, `{"title":"run tests","position":1}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var second ChecklistItem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&second))
+	assert.Equal(t, 1, second.Position)
+
+	rr = send(http.MethodPost, base, `{"title":"","done":true,"position":0}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Len(t, decodeProblem(t, rr).Errors, 3)
+
+	rr = send(http.MethodGet, base, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var items []ChecklistItem
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&items))
+	if assert.Len(t, items, 2) {
+		assert.Equal(t, "run tests", items[0].Title)
+		assert.Equal(t, "tag release", items[1].Title)
+	}
+
+	rr = send(http.MethodPatch, base+"/"+second.ID, `{"done":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID, "")
+	assert.Contains(t, rr.Body.String(), `"checklist":{"done":1,"total":2}`)
+	assert.Contains(t, rr.Body.String(), `"done":false`)
+
+	rr = send(http.MethodPatch, base+"/"+first.ID, `{"done":true,"position":1}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"position":1`)
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID, "")
+	assert.Contains(t, rr.Body.String(), `"checklist":{"done":2,"total":2}`)
+	assert.Contains(t, rr.Body.String(), `"status":"done"`)
+
+	rr = send(http.MethodPatch, "/v1/todos/"+todo.ID, `{"checklist":{"done":0,"total":0}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = send(http.MethodDelete, base+"/"+second.ID, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodGet, base+"/"+second.ID, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "checklist_item_not_found", decodeProblem(t, rr).Code)
+	rr = send(http.MethodGet, base+"/"+first.ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"position":1`)
+	rr = send(http.MethodGet, "/v1/todos/missing/checklist", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
+}
+
+func TestRouter_Dependencies(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+	ctx := context.Background()
+	first, err := store.CreateTodo(ctx, Todo{Title: "Flash firmware"})
+	assert.NoError(t, err)
+	second, err := store.CreateTodo(ctx, Todo{Title: "Print test label"})
+	assert.NoError(t, err)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	rr := send(http.MethodPut, "/v1/todos/"+second.ID+"/blockers/"+first.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+	assert.Contains(t, rr.Body.String(), `"blocked_by":["`+first.ID+`"]`)
+
+	rr = send(http.MethodPut, "/v1/todos/"+first.ID+"/blockers/"+second.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeDependencyCycle, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodPost, "/v1/todos/"+second.ID+"/complete")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	p := decodeProblem(t, rr)
+	assert.Equal(t, CodeBlocked, p.Code)
+	assert.Contains(t, p.Detail, first.ID)
+
+	rr = send(http.MethodGet, "/v1/todos?ready=true")
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	if assert.Len(t, todos, 1) {
+		assert.Equal(t, first.ID, todos[0].ID)
+	}
+	rr = send(http.MethodGet, "/v1/todos?ready=maybe")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = send(http.MethodGet, "/v1/projects/default/order")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	todos = nil
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	if assert.Len(t, todos, 2) {
+		assert.Equal(t, first.ID, todos[0].ID)
+		assert.Equal(t, second.ID, todos[1].ID)
+	}
+
+	rr = send(http.MethodDelete, "/v1/todos/"+second.ID+"/blockers/"+first.ID)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodDelete, "/v1/todos/"+second.ID+"/blockers/"+first.ID)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "dependency_not_found", decodeProblem(t, rr).Code)
+	rr = send(http.MethodPost, "/v1/todos/"+second.ID+"/complete")
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestRouter_Recurrence(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rr
+	}
+
+	rr := send(http.MethodPost, "/v1/todos", `{"title":"Water plants","recurrence":"FREQ=WEEKLY;BYDAY=MO"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "due_at", decodeProblem(t, rr).Errors[0].Field)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Water plants","due_at":"2026-10-19T08:00:00Z","recurrence":"FREQ=HOURLY"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "invalid_format", decodeProblem(t, rr).Errors[0].Code)
+
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Water plants","due_at":"2026-10-19T08:00:00Z","recurrence":"rrule:freq=weekly;byday=mo;interval=1"}`)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var first Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&first))
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO", first.Recurrence.String())
+	assert.NotEmpty(t, first.SeriesID)
+	assert.Equal(t, 1, first.Occurrence)
+
+	rr = send(http.MethodPost, "/v1/todos/"+first.ID+"/complete", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = send(http.MethodGet, "/v1/series/"+first.SeriesID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var series []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&series))
+	if assert.Len(t, series, 2) {
+		assert.Equal(t, 2, series[1].Occurrence)
+		assert.Equal(t, time.Date(2026, time.October, 26, 8, 0, 0, 0, time.UTC), series[1].DueAt.UTC())
+	}
+
+	rr = send(http.MethodPatch, "/v1/series/"+first.SeriesID, `{"title":"Water all plants","due_at":null}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "not_allowed", decodeProblem(t, rr).Errors[0].Code)
+	rr = send(http.MethodPatch, "/v1/series/"+first.SeriesID, `{"title":"Water all plants"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	series = nil
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&series))
+	if assert.Len(t, series, 2) {
+		assert.Equal(t, "Water plants", series[0].Title)
+		assert.Equal(t, "Water all plants", series[1].Title)
+	}
+
+	rr = send(http.MethodGet, "/v1/series/missing", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "series_not_found", decodeProblem(t, rr).Code)
+}
+
+func TestRouter_Trash(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", "*")
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var todos [2]Todo
+	for i, title := range []string{"Renew domain", "Archive logs"} {
+		rr := send(http.MethodPost, "/v1/todos", `{"title":"`+title+`"}`)
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos[i]))
+	}
+
+	rr := send(http.MethodDelete, "/v1/trash/"+todos[0].ID, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeNotTrashed, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodDelete, "/v1/todos/"+todos[0].ID, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodGet, "/v1/todos/"+todos[0].ID, "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = send(http.MethodGet, "/v1/trash", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var trashed []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&trashed))
+	if assert.Len(t, trashed, 1) {
+		assert.Equal(t, todos[0].ID, trashed[0].ID)
+		assert.NotNil(t, trashed[0].DeletedAt)
+	}
+
+	rr = send(http.MethodPost, "/v1/trash/"+todos[0].ID+"/restore", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var restored Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&restored))
+	assert.Nil(t, restored.DeletedAt)
+	assert.Equal(t, restored.ETag(), rr.Header().Get("ETag"))
+	rr = send(http.MethodGet, "/v1/todos/"+todos[0].ID, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	for _, todo := range todos {
+		rr = send(http.MethodDelete, "/v1/todos/"+todo.ID, "")
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+	}
+	rr = send(http.MethodDelete, "/v1/trash/"+todos[0].ID, "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodPost, "/v1/trash/"+todos[0].ID+"/restore", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodDelete, "/v1/trash", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var result TrashPurgeResult
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&result))
+	assert.Equal(t, 1, result.Purged)
+}
+
+func TestRouter_History(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body, actor string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", "*")
+		if actor != "" {
+			req.Header.Set(actorHeader, actor)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodPost, "/v1/todos", `{"title":"Renew certificate"}`, "alice")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var todo Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+	rr = send(http.MethodPatch, "/v1/todos/"+todo.ID, `{"priority":"urgent"}`, "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodPost, "/v1/todos/"+todo.ID+"/complete", "", "bob")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Too long"}`, strings.Repeat("a", maxActorLength+1))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, CodeInvalidActor, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID+"/history", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var history []HistoryEntry
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&history))
+	if assert.Len(t, history, 3) {
+		assert.Equal(t, "alice", history[0].Actor)
+		assert.Equal(t, anonymousActor, history[1].Actor)
+		assert.JSONEq(t, `"normal"`, string(history[1].Changes["priority"].From))
+		assert.JSONEq(t, `"urgent"`, string(history[1].Changes["priority"].To))
+		assert.Equal(t, HistoryStatusChanged, history[2].Action)
+		assert.Equal(t, "bob", history[2].Actor)
+	}
+	rr = send(http.MethodGet, "/v1/todos/missing/history", "", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodGet, "/v1/activity?limit=2", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var activity []HistoryEntry
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&activity))
+	if assert.Len(t, activity, 2) {
+		assert.Equal(t, history[2].ID, activity[0].ID)
+	}
+	link := rr.Header().Get("Link")
+	assert.Contains(t, link, `rel="next"`)
+	next := link[strings.Index(link, "<")+1 : strings.Index(link, ">")]
+	rr = send(http.MethodGet, next, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	activity = nil
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&activity))
+	if assert.Len(t, activity, 1) {
+		assert.Equal(t, HistoryCreated, activity[0].Action)
+	}
+	assert.Empty(t, rr.Header().Get("Link"))
+
+	rr = send(http.MethodGet, "/v1/activity?action=status_changed&actor=bob", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	activity = nil
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&activity))
+	assert.Len(t, activity, 1)
+	rr = send(http.MethodGet, "/v1/activity?action=archived", "", "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "action", decodeProblem(t, rr).Errors[0].Field)
+}
+
+func TestRouter_LegacyAliases(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	created, err := store.CreateTodo(context.Background(), Todo{Title: "Legacy client"})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		method, path, body, successor string
+		status                        int
+	}{
+		{http.MethodGet, "/todo", "", "/v1/todos", http.StatusOK},
+		{http.MethodGet, "/todo/getuser/" + created.ID, "", "/v1/todos/" + created.ID, http.StatusOK},
+		{http.MethodPatch, "/todo/update/" + created.ID, `{"title":"Legacy client","desc":"still works"}`, "/v1/todos/" + created.ID, http.StatusOK},
+		{http.MethodPatch, "/todo/update-status/" + created.ID, "", "/v1/todos/" + created.ID + "/toggle", http.StatusOK},
+		{http.MethodPost, "/todo/create", `{"title":"Another"}`, "/v1/todos", http.StatusCreated},
+		{http.MethodDelete, "/todo/delete/" + created.ID, "", "/v1/todos/" + created.ID, http.StatusNoContent},
+	}
+	for _, tc := range cases {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body)))
+
+			assert.Equal(t, tc.status, rr.Code)
+			assert.Equal(t, "@"+fmt.Sprint(legacyDeprecatedAt.Unix()), rr.Header().Get("Deprecation"))
+			assert.Equal(t, legacySunset.Format(http.TimeFormat), rr.Header().Get("Sunset"))
+			assert.Contains(t, rr.Header().Values("Link"), "<"+tc.successor+`>; rel="successor-version"`)
+		})
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	created, err := store.CreateTodo(context.Background(), Todo{Title: "Shared todo"})
+	assert.NoError(t, err)
+	path := "/v1/todos/" + created.ID
+
+	send := func(method, ifMatch, ifNoneMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodGet, "", "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	rr = send(http.MethodGet, "", etag, "")
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.String())
+
+	rr = send(http.MethodPatch, "", "", `{"title":"No precondition"}`)
+	assert.Equal(t, http.StatusPreconditionRequired, rr.Code)
+	assert.Equal(t, CodePreconditionRequired, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodPatch, etag, "", `{"title":"First writer"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `"2"`, rr.Header().Get("ETag"))
+
+	rr = send(http.MethodPatch, etag, "", `{"title":"Second writer"}`)
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+	assert.Equal(t, CodePreconditionFailed, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodDelete, `"not-a-version"`, "", "")
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code)
+
+	rr = send(http.MethodGet, "", etag, "")
+	assert.Equal(t, http.StatusOK, rr.Code, "stale If-None-Match gets the new representation")
+
+	got, err := store.GetTodo(context.Background(), created.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "First writer", got.Title)
+
+	rr = send(http.MethodDelete, "*", "", "")
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+}
+
+func TestGetAllTodo_NotModified(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	created, err := store.CreateTodo(context.Background(), Todo{Title: "Cached list"})
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	handler.GetAllTodo(rr, httptest.NewRequest(http.MethodGet, "/v1/todos", nil))
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/todos", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	handler.GetAllTodo(rr, req)
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+
+	_, err = store.UpdateTodoStatus(context.Background(), created.ID)
+	assert.NoError(t, err)
+
+	rr = httptest.NewRecorder()
+	handler.GetAllTodo(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotEqual(t, etag, rr.Header().Get("ETag"))
+}
+
+func TestPatchFormats(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	created, err := store.CreateTodo(context.Background(), Todo{Title: "Patch me", Desc: "original"})
+	assert.NoError(t, err)
+	path := "/v1/todos/" + created.ID
+
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("If-Match", "*")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	t.Run("Merge patch changes only the given fields", func(t *testing.T) {
+		rr := patch(mergePatchContentType, `{"title":"Merged"}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var todo Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+		assert.Equal(t, "Merged", todo.Title)
+		assert.Equal(t, "original", todo.Desc)
+	})
+
+	t.Run("Merge patch null clears desc", func(t *testing.T) {
+		rr := patch("application/json", `{"desc":null}`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var todo Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+		assert.Equal(t, "", todo.Desc)
+	})
+
+	t.Run("JSON Patch", func(t *testing.T) {
+		rr := patch(jsonPatchContentType, `[
+			{"op":"test","path":"/title","value":"Merged"},
+			{"op":"replace","path":"/done","value":true},
+			{"op":"add","path":"/desc","value":"patched"}
+		]`)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var todo Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+		assert.Equal(t, "Merged", todo.Title)
+		assert.Equal(t, "patched", todo.Desc)
+		assert.True(t, todo.Done)
+		assert.NotNil(t, todo.DoneAt)
+	})
+
+	t.Run("JSON Patch failed test is a conflict", func(t *testing.T) {
+		rr := patch(jsonPatchContentType, `[{"op":"test","path":"/title","value":"Other"},{"op":"replace","path":"/title","value":"x"}]`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, CodeConflict, decodeProblem(t, rr).Code)
+	})
+
+	t.Run("Read-only fields are rejected", func(t *testing.T) {
+		rr := patch(mergePatchContentType, `{"id":"other","created_at":"2024-01-01T00:00:00Z","title":"ok"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		problem := decodeProblem(t, rr)
+		assert.Len(t, problem.Errors, 2)
+		for _, fieldErr := range problem.Errors {
+			assert.Equal(t, "read_only", fieldErr.Code)
+		}
+
+		rr = patch(jsonPatchContentType, `[{"op":"replace","path":"/version","value":99}]`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, "version", decodeProblem(t, rr).Errors[0].Field)
+	})
+
+	t.Run("Removing the title is a validation error", func(t *testing.T) {
+		rr := patch(jsonPatchContentType, `[{"op":"remove","path":"/title"}]`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, "required", decodeProblem(t, rr).Errors[0].Code)
+	})
+
+	t.Run("Malformed documents", func(t *testing.T) {
+		rr := patch(jsonPatchContentType, `{"op":"replace"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = patch(jsonPatchContentType, `[{"op":"frobnicate","path":"/title"}]`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = patch(mergePatchContentType, `["title"]`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("Unsupported media type", func(t *testing.T) {
+		rr := patch("text/plain", `title=x`)
+		assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+		assert.Contains(t, rr.Header().Get("Accept-Patch"), jsonPatchContentType)
+	})
+
+	t.Run("PUT replaces the whole todo", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPut, path, strings.NewReader(`{"title":"Replaced"}`))
+		req.Header.Set("If-Match", "*")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var todo Todo
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+		assert.Equal(t, "Replaced", todo.Title)
+		assert.Equal(t, "", todo.Desc)
+		assert.False(t, todo.Done)
+	})
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := map[string]interface{}{"a": []interface{}{"x", "y"}, "b": map[string]interface{}{"c/d": 1.0}}
+	ops := []jsonPatchOp{
+		{Op: "add", Path: "/a/1", Value: json.RawMessage(`"inserted"`)},
+		{Op: "add", Path: "/a/-", Value: json.RawMessage(`"last"`)},
+		{Op: "remove", Path: "/a/0"},
+		{Op: "copy", From: "/b/c~1d", Path: "/e"},
+		{Op: "move", From: "/b", Path: "/f"},
+		{Op: "test", Path: "/f/c~1d", Value: json.RawMessage(`1`)},
+	}
+	patched, err := applyJSONPatch(doc, ops)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a": []interface{}{"inserted", "y", "last"},
+		"e": 1.0,
+		"f": map[string]interface{}{"c/d": 1.0},
+	}, patched)
+
+	_, err = applyJSONPatch(patched, []jsonPatchOp{{Op: "replace", Path: "/missing", Value: json.RawMessage(`1`)}})
+	assert.ErrorIs(t, err, ErrConflict)
+}
+
+func TestBulkTodos(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(body string) (*httptest.ResponseRecorder, BulkResponse) {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/todos/bulk", strings.NewReader(body)))
+		var resp BulkResponse
+		if rr.Header().Get("Content-Type") == "application/json" {
+			assert.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		}
+		return rr, resp
+	}
+
+	rr, resp := send(`{"action":"create","atomic":true,"items":[{"title":"a"},{"title":"b"}]}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.True(t, resp.Committed)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, http.StatusCreated, resp.Results[0].Status)
+	first, second := resp.Results[0].ID, resp.Results[1].ID
+
+	rr, resp = send(fmt.Sprintf(`{"action":"done","items":[{"id":%q},{"id":"missing"},{"id":%q,"version":1}]}`, first, second))
+	assert.Equal(t, http.StatusMultiStatus, rr.Code)
+	assert.True(t, resp.Committed)
+	assert.Equal(t, 2, resp.Succeeded)
+	assert.Equal(t, 1, resp.Failed)
+	assert.Equal(t, http.StatusNotFound, resp.Results[1].Status)
+	assert.Equal(t, CodeTodoNotFound, resp.Results[1].Error.Code)
+
+	rr, resp = send(fmt.Sprintf(`{"action":"update","atomic":true,"items":[{"id":%q,"patch":{"title":"changed"}},{"id":%q,"version":1,"patch":{"done":false}}]}`, first, second))
+	assert.Equal(t, http.StatusPreconditionFailed, rr.Code, "an atomic batch reports the failing item's status")
+	assert.False(t, resp.Committed)
+	assert.Equal(t, http.StatusFailedDependency, resp.Results[0].Status)
+	assert.Equal(t, CodeRolledBack, resp.Results[0].Error.Code)
+	got, err := store.GetTodo(context.Background(), first)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", got.Title)
+
+	rr, _ = send(`{"action":"create","items":[{"title":" "},{"title":"ok","done":true}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	problem := decodeProblem(t, rr)
+	assert.ElementsMatch(t, []FieldError{
+		{Field: "items[0].title", Code: "required", Message: "title is required"},
+		{Field: "items[1].done", Code: "not_allowed", Message: createTodoRules.rejected["done"].Message},
+	}, problem.Errors)
+
+	rr, _ = send(`{"action":"archive","items":[{"id":"x"}]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "action", decodeProblem(t, rr).Errors[0].Field)
+
+	rr, _ = send(`{"action":"delete","items":[]}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr, resp = send(fmt.Sprintf(`{"action":"delete","items":[{"id":%q},{"id":%q}]}`, first, second))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, http.StatusNoContent, resp.Results[0].Status)
+	page, err := store.GetAllTodo(context.Background(), TodoQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+}
+
+func TestRouter_Users(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body, user string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", "*")
+		if user != "" {
+			req.Header.Set(userHeader, user)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	createUser := func(body string) User {
+		rr := send(http.MethodPost, "/v1/users", body, "")
+		assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		var user User
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+		assert.Equal(t, "/v1/users/"+user.ID, rr.Header().Get("Location"))
+		return user
+	}
+
+	alice := createUser(`{"name":"Alice","email":"alice@example.com"}`)
+	bob := createUser(`{"name":"Bob","email":"bob@example.com"}`)
+	rr := send(http.MethodPost, "/v1/users", `{"name":"Alice","email":"ALICE@example.com"}`, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeEmailTaken, decodeProblem(t, rr).Code)
+	rr = send(http.MethodPost, "/v1/users", `{"name":"Carol","email":"carol@example.com","id":"carol"}`, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = send(http.MethodGet, "/v1/users/"+bob.ID, "", "")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodGet, "/v1/users/missing", "", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "user_not_found", decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodGet, "/v1/todos", "", "missing")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, CodeUnknownUser, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Rotate keys"}`, alice.ID)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var todo Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+	assert.Equal(t, alice.ID, todo.OwnerID)
+	rr = send(http.MethodPatch, "/v1/todos/"+todo.ID, `{"owner_id":"`+bob.ID+`"}`, alice.ID)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID, "", bob.ID)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, CodeTodoNotFound, decodeProblem(t, rr).Code)
+	rr = send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, "", alice.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID+"/shares", "", bob.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var shares []TodoShare
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&shares))
+	if assert.Len(t, shares, 1) {
+		assert.Equal(t, bob.ID, shares[0].UserID)
+	}
+	rr = send(http.MethodPost, "/v1/todos/"+todo.ID+"/complete", "", bob.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID+"/history", "", alice.ID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var history []HistoryEntry
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&history))
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, alice.ID, history[0].Actor, "the user is the actor of the history")
+		assert.Equal(t, bob.ID, history[1].Actor)
+	}
+
+	rr = send(http.MethodDelete, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, "", alice.ID)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodDelete, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, "", alice.ID)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "share_not_found", decodeProblem(t, rr).Code)
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID, "", bob.ID)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestRouter_Roles(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body, user string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", "*")
+		if user != "" {
+			req.Header.Set(userHeader, user)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(v))
+	}
+	forbidden := func(rr *httptest.ResponseRecorder) {
+		t.Helper()
+		assert.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+		assert.Equal(t, CodeForbidden, decodeProblem(t, rr).Code)
+	}
+	var alice, bob User
+	decode(send(http.MethodPost, "/v1/users", `{"name":"Alice","email":"alice@example.com"}`, ""), &alice)
+	decode(send(http.MethodPost, "/v1/users", `{"name":"Bob","email":"bob@example.com"}`, ""), &bob)
+
+	var todo Todo
+	decode(send(http.MethodPost, "/v1/todos", `{"title":"Review budget"}`, alice.ID), &todo)
+	rr := send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, `{"role":"admin"}`, alice.ID)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, `{"role":"viewer"}`, alice.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var share TodoShare
+	decode(rr, &share)
+	assert.Equal(t, RoleViewer, share.Role)
+
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/v1/todos/"+todo.ID, "", bob.ID).Code, "viewers read the todo")
+	forbidden(send(http.MethodPatch, "/v1/todos/"+todo.ID, `{"title":"changed"}`, bob.ID))
+	forbidden(send(http.MethodPost, "/v1/todos/"+todo.ID+"/complete", "", bob.ID))
+	forbidden(send(http.MethodPost, "/v1/todos/"+todo.ID+"/checklist", `{"title":"step"}`, bob.ID))
+	forbidden(send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, `{"role":"owner"}`, bob.ID))
+
+	rr = send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, "", alice.ID)
+	decode(rr, &share)
+	assert.Equal(t, RoleEditor, share.Role, "the role defaults to editor")
+	assert.Equal(t, http.StatusOK, send(http.MethodPost, "/v1/todos/"+todo.ID+"/complete", "", bob.ID).Code)
+	forbidden(send(http.MethodDelete, "/v1/todos/"+todo.ID, "", bob.ID))
+	rr = send(http.MethodPost, "/v1/todos/bulk", fmt.Sprintf(`{"action":"delete","items":[{"id":%q}]}`, todo.ID), bob.ID)
+	var bulk BulkResponse
+	decode(rr, &bulk)
+	if assert.Len(t, bulk.Results, 1) {
+		assert.Equal(t, http.StatusForbidden, bulk.Results[0].Status)
+	}
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, "", bob.ID).Code,
+		"users can leave a share")
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v1/todos/"+todo.ID, "", bob.ID).Code)
+
+	var project Project
+	decode(send(http.MethodPost, "/v1/projects", `{"name":"Launch"}`, alice.ID), &project)
+	forbidden(send(http.MethodPost, "/v1/todos", `{"title":"Book venue","project_id":"`+project.ID+`"}`, bob.ID))
+	forbidden(send(http.MethodGet, "/v1/projects/"+project.ID+"/shares", "", bob.ID))
+	rr = send(http.MethodPut, "/v1/projects/"+project.ID+"/shares/"+bob.ID, `{"role":"editor"}`, alice.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusCreated, send(http.MethodPost, "/v1/todos", `{"title":"Book venue","project_id":"`+project.ID+`"}`, bob.ID).Code)
+	var projectShares []ProjectShare
+	decode(send(http.MethodGet, "/v1/projects/"+project.ID+"/shares", "", bob.ID), &projectShares)
+	assert.Len(t, projectShares, 2)
+	forbidden(send(http.MethodDelete, "/v1/projects/"+project.ID, "", bob.ID))
+	forbidden(send(http.MethodDelete, "/v1/projects/"+project.ID+"/shares/"+alice.ID, "", bob.ID))
+	rr = send(http.MethodDelete, "/v1/projects/"+project.ID+"/shares/"+alice.ID, "", alice.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeLastOwner, decodeProblem(t, rr).Code)
+	rr = send(http.MethodPut, "/v1/projects/"+DefaultProjectID+"/shares/"+bob.ID, "", alice.ID)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, CodeDefaultProject, decodeProblem(t, rr).Code)
+}
+
+func TestRouter_TagRoles(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, body, user string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("If-Match", "*")
+		if user != "" {
+			req.Header.Set(userHeader, user)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	decode := func(rr *httptest.ResponseRecorder, v interface{}) {
+		t.Helper()
+		assert.NoError(t, json.NewDecoder(rr.Body).Decode(v))
+	}
+	forbidden := func(rr *httptest.ResponseRecorder) {
+		t.Helper()
+		assert.Equal(t, http.StatusForbidden, rr.Code, rr.Body.String())
+		assert.Equal(t, CodeForbidden, decodeProblem(t, rr).Code)
+	}
+	var alice, bob User
+	decode(send(http.MethodPost, "/v1/users", `{"name":"Alice","email":"alice@example.com"}`, ""), &alice)
+	decode(send(http.MethodPost, "/v1/users", `{"name":"Bob","email":"bob@example.com"}`, ""), &bob)
+	forbidden(send(http.MethodPost, "/v1/users", `{"name":"Eve","email":"eve@example.com"}`, bob.ID))
+
+	var todo Todo
+	decode(send(http.MethodPost, "/v1/todos", `{"title":"Review budget","tags":["finance"]}`, alice.ID), &todo)
+	var tags []Tag
+	decode(send(http.MethodGet, "/v1/tags", "", alice.ID), &tags)
+	require.Len(t, tags, 1)
+	tag := tags[0]
+	assert.Equal(t, alice.ID, tag.OwnerID)
+
+	// Bob không thấy tag chỉ gắn cho todo riêng của Alice.
+	decode(send(http.MethodGet, "/v1/tags", "", bob.ID), &tags)
+	assert.Empty(t, tags)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v1/tags/"+tag.ID, "", bob.ID).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodPut, "/v1/tags/"+tag.ID, `{"name":"money"}`, bob.ID).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/v1/tags/"+tag.ID, "", bob.ID).Code)
+
+	rr := send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, `{"role":"viewer"}`, alice.ID)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/v1/tags/"+tag.ID, "", bob.ID).Code, "viewers see the tags of the todo")
+	forbidden(send(http.MethodPut, "/v1/tags/"+tag.ID, `{"name":"money"}`, bob.ID))
+	forbidden(send(http.MethodDelete, "/v1/tags/"+tag.ID, "", bob.ID))
+
+	// Tag còn gắn cho một todo Bob không thấy thì Bob vẫn không được sửa, dù là editor của todo kia.
+	var private Todo
+	decode(send(http.MethodPost, "/v1/todos", `{"title":"Payroll","tags":["finance"]}`, alice.ID), &private)
+	send(http.MethodPut, "/v1/todos/"+todo.ID+"/shares/"+bob.ID, `{"role":"editor"}`, alice.ID)
+	forbidden(send(http.MethodPut, "/v1/tags/"+tag.ID, `{"name":"money"}`, bob.ID))
+
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/v1/todos/"+private.ID, "", alice.ID).Code)
+	forbidden(send(http.MethodPut, "/v1/tags/"+tag.ID, `{"name":"money"}`, bob.ID))
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/v1/trash/"+private.ID, "", alice.ID).Code)
+	rr = send(http.MethodPut, "/v1/tags/"+tag.ID, `{"name":"money"}`, bob.ID)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	var own Tag
+	rr = send(http.MethodPost, "/v1/tags", `{"name":"errands"}`, bob.ID)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	decode(rr, &own)
+	assert.Equal(t, bob.ID, own.OwnerID)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/v1/tags/"+own.ID, "", alice.ID).Code)
+	assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, "/v1/tags/"+own.ID, "", bob.ID).Code)
+}
+
+func TestRouter_Tenants(t *testing.T) {
+	store := NewTenantTodoService(func() TodoService { return NewMemoryTodoService() })
+	router := newRouter(NewAPIHandler(store, NewMemoryIdempotencyStore()))
+	send := func(method, path, body, tenant string, header ...string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if tenant != "" {
+			req.Header.Set(tenantHeader, tenant)
+		}
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	listed := func(tenant string) []Todo {
+		rr := send(http.MethodGet, "/v1/todos", "", tenant)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var todos []Todo
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+		return todos
+	}
+
+	for _, tenant := range []string{"Acme", "-acme", "acme corp", strings.Repeat("a", maxTenantIDLength+1)} {
+		rr := send(http.MethodGet, "/v1/todos", "", tenant)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, tenant)
+		assert.Equal(t, CodeInvalidTenant, decodeProblem(t, rr).Code)
+	}
+
+	rr := send(http.MethodPost, "/v1/todos", `{"title":"rotate keys"}`, "acme", "Idempotency-Key", "create-1")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var todo Todo
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+
+	assert.Len(t, listed("acme"), 1)
+	assert.Empty(t, listed("globex"))
+	assert.Empty(t, listed(""), "requests without a tenant use the default tenant")
+	rr = send(http.MethodGet, "/v1/todos/"+todo.ID, "", "globex")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Idempotency-Key chỉ có nghĩa trong tenant của nó.
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"rotate keys"}`, "globex", "Idempotency-Key", "create-1")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var replayed Todo
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&replayed))
+	assert.NotEqual(t, todo.ID, replayed.ID)
+	assert.Len(t, listed("globex"), 1)
+
+	// Mỗi tenant có project mặc định riêng.
+	rr = send(http.MethodPut, "/v1/projects/"+DefaultProjectID, `{"name":"Inbox"}`, "acme")
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = send(http.MethodGet, "/v1/projects/"+DefaultProjectID, "", "globex")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var project Project
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&project))
+	assert.NotEqual(t, "Inbox", project.Name)
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// apiKeyPrefix đứng đầu mọi API key, để middleware phân biệt API key với JWT và để key bị lộ dễ được nhận ra.
+	apiKeyPrefix        = "tdk_"
+	apiKeyDisplayLength = 12
+	maxAPIKeyNameLength = 100
+	// apiKeyTouchInterval là khoảng thời gian tối thiểu giữa hai lần ghi last_used_at, để mỗi request không phải ghi database.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKey là một khóa dài hạn cho script tự động, thay mặt UserID. Server chỉ lưu sha256 của token;
+// Token chỉ có trong response tạo key và không thể đọc lại.
+type APIKey struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	TenantID string `json:"tenant_id"`
+	Name     string `json:"name" example:"nightly import"`
+	// Prefix là vài ký tự đầu của token, giúp nhận ra key trong danh sách.
+	Prefix     string     `json:"prefix" example:"tdk_3q2-7wEj"`
+	Token      string     `json:"token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	hash       string
+}
+
+// APIKeyStore lưu API key; key được tra theo hash của token. Key thuộc tenant của ctx khi tạo; List và Revoke chỉ
+// thấy key của tenant trong ctx, còn Authenticate tra trên mọi tenant và trả về tenant của key.
+type APIKeyStore interface {
+	Create(ctx context.Context, key APIKey) (*APIKey, error)
+	// List trả về các key của người dùng, kể cả key đã hết hạn hoặc bị thu hồi, mới nhất trước.
+	List(ctx context.Context, userID string) ([]APIKey, error)
+	// Revoke thu hồi key của người dùng; key của người khác được coi như không tồn tại. Thu hồi lại một key
+	// đã thu hồi không lỗi.
+	Revoke(ctx context.Context, userID, id string, now time.Time) error
+	// Authenticate trả về key có hash cho trước và ghi nhận lần dùng, hoặc ErrInvalidToken nếu key không tồn tại,
+	// đã hết hạn hoặc đã bị thu hồi.
+	Authenticate(ctx context.Context, hash string, now time.Time) (*APIKey, error)
+}
+
+func apiKeyNotFound(id string) error {
+	return &NotFoundError{Resource: "api_key", ID: id}
+}
+
+// hashAPIKey trả về sha256 dạng hex của token, giá trị được lưu thay cho token.
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newAPIKey sinh token ngẫu nhiên 256 bit cho key mới.
+func newAPIKey(userID, name string, expiresAt *time.Time) (APIKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, fmt.Errorf("sinh API key thất bại: %w", err)
+	}
+	token := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	return APIKey{
+		ID:        generateNewID(),
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    token[:apiKeyDisplayLength],
+		Token:     token,
+		ExpiresAt: expiresAt,
+		hash:      hashAPIKey(token),
+	}, nil
+}
+
+func validateAPIKey(key APIKey, now time.Time) error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(key.Name) == "" {
+		verr.Add("name", "required", "name is required")
+	} else if utf8.RuneCountInString(key.Name) > maxAPIKeyNameLength {
+		verr.Add("name", "too_long", fmt.Sprintf("name must be at most %d characters", maxAPIKeyNameLength))
+	}
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		verr.Add("expires_at", "in_past", "expires_at must be in the future")
+	}
+	return verr.Err()
+}
+
+// usable cho biết key còn dùng được để xác thực tại thời điểm now.
+func (k APIKey) usable(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+// APIKeyRequest là payload của POST /v1/api-keys. Bỏ trống expires_at để key không hết hạn.
+type APIKeyRequest struct {
+	Name      string     `json:"name" example:"nightly import"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+var apiKeyRules = payloadRules{
+	fields: map[string]fieldRule{
+		"name":       {kind: "string", required: true, trim: true, maxLength: maxAPIKeyNameLength},
+		"expires_at": {kind: "datetime", nullable: true},
+	},
+	rejected: map[string]FieldError{
+		"id":           {Code: "read_only", Message: "id is assigned by the server"},
+		"user_id":      {Code: "read_only", Message: "keys always belong to the authenticated user"},
+		"tenant_id":    {Code: "read_only", Message: "keys always belong to the tenant of the request"},
+		"token":        {Code: "read_only", Message: "token is generated by the server"},
+		"prefix":       {Code: "read_only", Message: "prefix is derived from the token"},
+		"created_at":   {Code: "read_only", Message: "created_at is set by the server"},
+		"last_used_at": {Code: "read_only", Message: "last_used_at is set by the server"},
+		"revoked_at":   {Code: "read_only", Message: "revoke a key with DELETE /v1/api-keys/{id}"},
+	},
+}
+
+type MemoryAPIKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]APIKey
+}
+
+// NewMemoryAPIKeyStore tạo APIKeyStore trong bộ nhớ, đi cùng MemoryTodoService.
+func NewMemoryAPIKeyStore() *MemoryAPIKeyStore {
+	return &MemoryAPIKeyStore{
+		keys: make(map[string]APIKey),
+	}
+}
+
+func (s *MemoryAPIKeyStore) Create(ctx context.Context, key APIKey) (*APIKey, error) {
+	now := time.Now()
+	if err := validateAPIKey(key, now); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key.CreatedAt = now
+	key.TenantID = tenantFrom(ctx)
+	stored := key
+	stored.Token = ""
+	s.keys[key.ID] = stored
+	return &key, nil
+}
+
+func (s *MemoryAPIKeyStore) List(ctx context.Context, userID string) ([]APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID := tenantFrom(ctx)
+	keys := []APIKey{}
+	for _, key := range s.keys {
+		if key.UserID == userID && key.TenantID == tenantID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.After(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+func (s *MemoryAPIKeyStore) Revoke(ctx context.Context, userID, id string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || key.UserID != userID || key.TenantID != tenantFrom(ctx) {
+		return apiKeyNotFound(id)
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &now
+		s.keys[id] = key
+	}
+	return nil
+}
+
+func (s *MemoryAPIKeyStore) Authenticate(ctx context.Context, hash string, now time.Time) (*APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, key := range s.keys {
+		if key.hash != hash {
+			continue
+		}
+		if !key.usable(now) {
+			return nil, invalidToken("API key has expired or was revoked")
+		}
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+			key.LastUsedAt = &now
+			s.keys[id] = key
+		}
+		return &key, nil
+	}
+	return nil, invalidToken("unknown API key")
+}
+
+type DbAPIKeyStore struct {
+	db *Db
+}
+
+func NewDbAPIKeyStore(db *Db) *DbAPIKeyStore {
+	return &DbAPIKeyStore{
+		db: db,
+	}
+}
+
+const apiKeyColumns = "id, user_id, tenant_id, name, prefix, hash, created_at, expires_at, last_used_at, revoked_at"
+
+func scanAPIKey(row pgx.Row) (APIKey, error) {
+	var key APIKey
+	err := row.Scan(&key.ID, &key.UserID, &key.TenantID, &key.Name, &key.Prefix, &key.hash, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt)
+	return key, err
+}
+
+func (s *DbAPIKeyStore) Create(ctx context.Context, key APIKey) (*APIKey, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	if err := validateAPIKey(key, now); err != nil {
+		return nil, err
+	}
+	key.CreatedAt = now
+	key.TenantID = tenantFrom(ctx)
+	var expiresAt *time.Time
+	if key.ExpiresAt != nil {
+		utc := key.ExpiresAt.UTC()
+		expiresAt = &utc
+	}
+	_, err := s.db.conn.Exec(ctx,
+		"INSERT INTO api_key (id, user_id, tenant_id, name, prefix, hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		key.ID, key.UserID, key.TenantID, key.Name, key.Prefix, key.hash, key.CreatedAt, expiresAt)
+	if isForeignKeyViolation(err) {
+		return nil, userNotFound(key.UserID)
+	}
+	if err != nil {
+		return nil, dbError("thêm API key thất bại", err)
+	}
+	return &key, nil
+}
+
+func (s *DbAPIKeyStore) List(ctx context.Context, userID string) ([]APIKey, error) {
+	rows, err := s.db.conn.Query(ctx, "SELECT "+apiKeyColumns+" FROM api_key WHERE user_id = $1 AND tenant_id = $2 ORDER BY created_at DESC, id",
+		userID, tenantFrom(ctx))
+	if err != nil {
+		return nil, dbError("truy vấn API key thất bại", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, dbError("đọc API key thất bại", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("đọc API key thất bại", err)
+	}
+	return keys, nil
+}
+
+func (s *DbAPIKeyStore) Revoke(ctx context.Context, userID, id string, now time.Time) error {
+	tag, err := s.db.conn.Exec(ctx,
+		"UPDATE api_key SET revoked_at = COALESCE(revoked_at, $1) WHERE id = $2 AND user_id = $3 AND tenant_id = $4",
+		now.UTC(), id, userID, tenantFrom(ctx))
+	if err != nil {
+		return dbError("thu hồi API key thất bại", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apiKeyNotFound(id)
+	}
+	return nil
+}
+
+func (s *DbAPIKeyStore) Authenticate(ctx context.Context, hash string, now time.Time) (*APIKey, error) {
+	key, err := scanAPIKey(s.db.conn.QueryRow(ctx, "SELECT "+apiKeyColumns+" FROM api_key WHERE hash = $1", hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, invalidToken("unknown API key")
+	}
+	if err != nil {
+		return nil, dbError("tra cứu API key thất bại", err)
+	}
+	if !key.usable(now) {
+		return nil, invalidToken("API key has expired or was revoked")
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		used := now.UTC().Truncate(time.Microsecond)
+		// Ghi last_used_at thất bại không làm request thất bại, key vẫn hợp lệ.
+		if _, err := s.db.conn.Exec(ctx, "UPDATE api_key SET last_used_at = $1 WHERE id = $2", used, key.ID); err == nil {
+			key.LastUsedAt = &used
+		}
+	}
+	return &key, nil
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HistoryAction là loại thay đổi được ghi trong lịch sử của todo.
+type HistoryAction string
+
+const (
+	HistoryCreated HistoryAction = "created"
+	HistoryUpdated HistoryAction = "updated"
+	// HistoryStatusChanged là một cập nhật có đổi status (complete, reopen, toggle, chuyển status, PATCH).
+	HistoryStatusChanged HistoryAction = "status_changed"
+	HistoryDeleted       HistoryAction = "deleted"
+	HistoryRestored      HistoryAction = "restored"
+	HistoryPurged        HistoryAction = "purged"
+)
+
+var historyActions = []HistoryAction{HistoryCreated, HistoryUpdated, HistoryStatusChanged, HistoryDeleted, HistoryRestored, HistoryPurged}
+
+// Actor của thay đổi không đến từ một request có danh tính.
+const (
+	anonymousActor = "anonymous"
+	// systemActor là actor của các thay đổi do server tự chạy, như janitor dọn thùng rác.
+	systemActor    = "system"
+	actorHeader    = "X-Actor"
+	maxActorLength = 255
+)
+
+// auditedFields là các trường của todo (theo tên JSON) được so sánh khi ghi lịch sử.
+// Checklist, blocker và các trường server tự tính (version, updated_at, done_at, status_times) không được ghi.
+var auditedFields = []string{"title", "desc", "project_id", "status", "done", "priority", "due_at", "estimate", "tags", "recurrence", "deleted_at"}
+
+// FieldChange là giá trị của một trường trước và sau thay đổi, ở dạng JSON như trong response của todo.
+type FieldChange struct {
+	From json.RawMessage `json:"from" swaggertype:"object"`
+	To   json.RawMessage `json:"to" swaggertype:"object"`
+}
+
+// HistoryEntry là một bản ghi trong lịch sử chỉ-ghi-thêm của todo. Version là version của todo sau thay đổi.
+type HistoryEntry struct {
+	ID        string                 `json:"id"`
+	TodoID    string                 `json:"todo_id"`
+	Action    HistoryAction          `json:"action" swaggertype:"string" enums:"created,updated,status_changed,deleted,restored,purged" example:"status_changed"`
+	Actor     string                 `json:"actor" example:"alice"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Version   int                    `json:"version" example:"3"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// ActivityQuery là tham số của activity feed: mọi bản ghi lịch sử, mới nhất trước.
+type ActivityQuery struct {
+	Limit   int
+	Cursor  string
+	Actor   string
+	Actions []HistoryAction
+}
+
+// ActivityPage là một trang của activity feed; NextCursor rỗng ở trang cuối.
+type ActivityPage struct {
+	Items      []HistoryEntry
+	NextCursor string
+}
+
+// activityCursor là vị trí của bản ghi cuối trang trước trong thứ tự (created_at, id) giảm dần.
+type activityCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        string    `json:"i"`
+}
+
+type actorKey struct{}
+
+// withActor gắn người thực hiện thay đổi vào context để TodoService ghi vào lịch sử.
+func withActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// actorFrom trả về actor trong context, anonymousActor nếu không có.
+func actorFrom(ctx context.Context) string {
+	if actor, _ := ctx.Value(actorKey{}).(string); actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// requestActor đọc actor của request từ header X-Actor.
+func requestActor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor := strings.TrimSpace(r.Header.Get(actorHeader))
+		if len(actor) > maxActorLength {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidActor,
+				fmt.Sprintf("%s must be at most %d characters", actorHeader, maxActorLength))
+			return
+		}
+		if actor != "" {
+			r = r.WithContext(withActor(r.Context(), actor))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// auditValues trả về giá trị JSON của các trường trong auditedFields; nil với todo nil.
+func auditValues(todo *Todo) map[string]json.RawMessage {
+	if todo == nil {
+		return nil
+	}
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return nil
+	}
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil
+	}
+	return values
+}
+
+// emptyJSON cho biết giá trị là rỗng: null, "", [], false hoặc 0. Danh sách tag nil và rỗng được coi là như nhau.
+func emptyJSON(v json.RawMessage) bool {
+	switch string(v) {
+	case "", "null", `""`, "[]", "false", "0":
+		return true
+	}
+	return false
+}
+
+// sameJSON so sánh hai giá trị của một trường, coi null và [] là như nhau.
+func sameJSON(a, b json.RawMessage) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	return (len(a) == 0 || string(a) == "null" || string(a) == "[]") && (len(b) == 0 || string(b) == "null" || string(b) == "[]")
+}
+
+// historyEntry dựng bản ghi lịch sử cho thay đổi từ before sang after: before nil là todo vừa tạo (các trường rỗng
+// được bỏ qua), after nil là todo vừa bị xóa hẳn. ok là false khi một cập nhật không đổi trường nào được theo dõi.
+func historyEntry(ctx context.Context, before, after *Todo, now time.Time) (entry HistoryEntry, ok bool) {
+	entry = HistoryEntry{
+		ID:        generateNewID(),
+		Actor:     actorFrom(ctx),
+		Changes:   map[string]FieldChange{},
+		CreatedAt: now,
+	}
+	switch {
+	case after == nil:
+		entry.TodoID, entry.Action, entry.Version = before.ID, HistoryPurged, before.Version
+		return entry, true
+	case before == nil:
+		entry.Action = HistoryCreated
+	case before.DeletedAt == nil && after.DeletedAt != nil:
+		entry.Action = HistoryDeleted
+	case before.DeletedAt != nil && after.DeletedAt == nil:
+		entry.Action = HistoryRestored
+	case before.Status != after.Status:
+		entry.Action = HistoryStatusChanged
+	default:
+		entry.Action = HistoryUpdated
+	}
+	entry.TodoID, entry.Version = after.ID, after.Version
+
+	from, to := auditValues(before), auditValues(after)
+	for _, field := range auditedFields {
+		if before == nil && emptyJSON(to[field]) || sameJSON(from[field], to[field]) {
+			continue
+		}
+		change := FieldChange{From: from[field], To: to[field]}
+		if change.From == nil {
+			change.From = json.RawMessage("null")
+		}
+		entry.Changes[field] = change
+	}
+	return entry, entry.Action != HistoryUpdated || len(entry.Changes) > 0
+}
+
+func parseHistoryAction(s string) (HistoryAction, error) {
+	for _, action := range historyActions {
+		if string(action) == s {
+			return action, nil
+		}
+	}
+	names := make([]string, 0, len(historyActions))
+	for _, action := range historyActions {
+		names = append(names, string(action))
+	}
+	return "", fmt.Errorf("action must be one of %s", strings.Join(names, ", "))
+}
+
+// normalize điền giá trị mặc định, kiểm tra tham số và giải mã cursor (nếu có).
+func (q ActivityQuery) normalize() (ActivityQuery, *activityCursor, error) {
+	verr := &ValidationError{}
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultPageLimit
+	case q.Limit < 0:
+		verr.Add("limit", "invalid", "limit must be positive")
+	case q.Limit > maxPageLimit:
+		q.Limit = maxPageLimit
+	}
+	for _, action := range q.Actions {
+		if _, err := parseHistoryAction(string(action)); err != nil {
+			verr.Add("action", "invalid", err.Error())
+		}
+	}
+	var cur *activityCursor
+	if q.Cursor != "" {
+		var err error
+		if cur, err = decodeActivityCursor(q.Cursor); err != nil {
+			verr.Add("cursor", "invalid", "cursor is malformed")
+		}
+	}
+	return q, cur, verr.Err()
+}
+
+// matches áp dụng bộ lọc của query cho MemoryTodoService.
+func (q ActivityQuery) matches(entry HistoryEntry) bool {
+	if q.Actor != "" && entry.Actor != q.Actor {
+		return false
+	}
+	if len(q.Actions) == 0 {
+		return true
+	}
+	for _, action := range q.Actions {
+		if entry.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+// after cho biết entry nằm sau cursor trong thứ tự (created_at, id) giảm dần.
+func (c *activityCursor) after(entry HistoryEntry) bool {
+	if c == nil {
+		return true
+	}
+	if !entry.CreatedAt.Equal(c.CreatedAt) {
+		return entry.CreatedAt.Before(c.CreatedAt)
+	}
+	return entry.ID < c.ID
+}
+
+func encodeActivityCursor(entry HistoryEntry) string {
+	data, _ := json.Marshal(activityCursor{CreatedAt: entry.CreatedAt, ID: entry.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeActivityCursor(s string) (*activityCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur activityCursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, err
+	}
+	if cur.ID == "" {
+		return nil, errors.New("cursor has no id")
+	}
+	return &cur, nil
+}
+
+// newActivityPage cắt entries (đã sắp xếp, lấy dư một phần tử) thành một trang.
+func newActivityPage(entries []HistoryEntry, limit int) *ActivityPage {
+	page := &ActivityPage{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		page.NextCursor = encodeActivityCursor(page.Items[limit-1])
+	}
+	return page
+}
+
+// parseActivityQuery đọc tham số của GET /v1/activity.
+func parseActivityQuery(r *http.Request) (ActivityQuery, error) {
+	values := r.URL.Query()
+	verr := &ValidationError{}
+	q := ActivityQuery{
+		Cursor: values.Get("cursor"),
+		Actor:  values.Get("actor"),
+	}
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			verr.Add("limit", "invalid", "limit must be a positive integer")
+		}
+		q.Limit = n
+	}
+	// action giống status của /v1/todos: ?action=deleted,purged
+	for _, v := range values["action"] {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				q.Actions = append(q.Actions, HistoryAction(name))
+			}
+		}
+	}
+	return q, verr.Err()
+}
+
+const historyColumns = "id, todo_id, action, actor, changes, version, created_at"
+
+func scanHistoryEntry(row pgx.Row, entry *HistoryEntry) error {
+	var action string
+	var changes []byte
+	if err := row.Scan(&entry.ID, &entry.TodoID, &action, &entry.Actor, &changes, &entry.Version, &entry.CreatedAt); err != nil {
+		return err
+	}
+	entry.Action = HistoryAction(action)
+	entry.Changes = map[string]FieldChange{}
+	if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+		return fmt.Errorf("đọc changes của bản ghi lịch sử %s thất bại: %w", entry.ID, err)
+	}
+	return nil
+}
+
+// record ghi lịch sử cho thay đổi từ before sang after (xem historyEntry) và sự kiện tương ứng vào eventBatch của ctx;
+// caller chạy nó trong transaction của thay đổi.
+func (s *DbTodoService) record(ctx context.Context, before, after *Todo) error {
+	entry, ok := historyEntry(ctx, before, after, time.Now().UTC().Truncate(time.Microsecond))
+	if !ok {
+		return nil
+	}
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("mã hóa changes thất bại: %w", err)
+	}
+	_, err = s.conn().Exec(ctx,
+		"INSERT INTO todo_history ("+historyColumns+", tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		entry.ID, entry.TodoID, string(entry.Action), entry.Actor, changes, entry.Version, entry.CreatedAt, tenantFrom(ctx))
+	if err != nil {
+		return dbError("ghi lịch sử todo thất bại", err)
+	}
+	eventsFrom(ctx).add(newTodoEvent(ctx, entry, before, after))
+	return nil
+}
+
+func (s *DbTodoService) queryHistory(ctx context.Context, sql string, args ...interface{}) ([]HistoryEntry, error) {
+	rows, err := s.conn().Query(ctx, sql, args...)
+	if err != nil {
+		return nil, dbError("truy vấn lịch sử thất bại", err)
+	}
+	defer rows.Close()
+
+	entries := []HistoryEntry{}
+	for rows.Next() {
+		var entry HistoryEntry
+		if err := scanHistoryEntry(rows, &entry); err != nil {
+			return nil, dbError("scan thất bại", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc rows", err)
+	}
+	return entries, nil
+}
+
+// TodoHistory trả về lịch sử của todo theo thứ tự thời gian, kể cả khi todo nằm trong thùng rác hoặc đã bị xóa hẳn.
+// Todo đã bị xóa hẳn không còn owner để kiểm tra, nên lịch sử của nó chỉ hiện khi ctx không có người dùng.
+func (s *DbTodoService) TodoHistory(ctx context.Context, todoID string) ([]HistoryEntry, error) {
+	if userFrom(ctx) != "" {
+		if err := s.checkHistoryTodo(ctx, todoID); err != nil {
+			return nil, err
+		}
+	}
+	entries, err := s.queryHistory(ctx, "SELECT "+historyColumns+" FROM todo_history WHERE todo_id = $1 AND tenant_id = $2 ORDER BY created_at, id",
+		todoID, tenantFrom(ctx))
+	if err != nil || len(entries) > 0 {
+		return entries, err
+	}
+	// Todo tạo trước khi có bảng todo_history chưa có bản ghi nào.
+	if err := s.checkHistoryTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// checkHistoryTodo kiểm tra todo tồn tại (kể cả trong thùng rác) và người dùng trong ctx được thấy nó.
+func (s *DbTodoService) checkHistoryTodo(ctx context.Context, todoID string) error {
+	w := &sqlWhere{}
+	w.add("id = ?", todoID)
+	visibleTodos(ctx, w, "")
+	var exists bool
+	if err := s.conn().QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM todo"+w.String()+")", w.args...).Scan(&exists); err != nil {
+		return dbError("kiểm tra sự tồn tại của todo thất bại", err)
+	}
+	if !exists {
+		return todoNotFound(todoID)
+	}
+	return nil
+}
+
+func (s *DbTodoService) ListActivity(ctx context.Context, query ActivityQuery) (*ActivityPage, error) {
+	query, cur, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+	w := &sqlWhere{}
+	if query.Actor != "" {
+		w.add("actor = ?", query.Actor)
+	}
+	if len(query.Actions) > 0 {
+		actions := make([]string, 0, len(query.Actions))
+		for _, action := range query.Actions {
+			actions = append(actions, string(action))
+		}
+		w.add("action = ANY(?)", actions)
+	}
+	if cur != nil {
+		w.add("(created_at, id) < (?, ?)", cur.CreatedAt.UTC(), cur.ID)
+	}
+	forTenant(ctx, w, "")
+	if userFrom(ctx) != "" {
+		w.add("todo_id IN (SELECT id FROM todo WHERE " + visibleTo(ctx, w, "") + ")")
+	}
+	entries, err := s.queryHistory(ctx,
+		"SELECT "+historyColumns+" FROM todo_history"+w.String()+" ORDER BY created_at DESC, id DESC LIMIT "+strconv.Itoa(query.Limit+1),
+		w.args...)
+	if err != nil {
+		return nil, err
+	}
+	return newActivityPage(entries, query.Limit), nil
+}
+
+// recordLocked giống DbTodoService.record; caller phải giữ s.mu.
+func (s *MemoryTodoService) recordLocked(ctx context.Context, before, after *Todo) {
+	if entry, ok := historyEntry(ctx, before, after, time.Now()); ok {
+		s.history = append(s.history, entry)
+		eventsFrom(ctx).add(newTodoEvent(ctx, entry, before, after))
+	}
+}
+
+// historyVisibleLocked cho biết người dùng trong ctx được xem lịch sử của todo, như DbTodoService.checkHistoryTodo;
+// caller phải giữ s.mu.
+func (s *MemoryTodoService) historyVisibleLocked(ctx context.Context, todoID string) bool {
+	if userFrom(ctx) == "" {
+		return true
+	}
+	if _, ok := s.liveLocked(ctx, todoID); ok {
+		return true
+	}
+	_, ok := s.trashedLocked(ctx, todoID)
+	return ok
+}
+
+func (s *MemoryTodoService) TodoHistory(ctx context.Context, todoID string) ([]HistoryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.historyVisibleLocked(ctx, todoID) {
+		return nil, todoNotFound(todoID)
+	}
+	entries := []HistoryEntry{}
+	for _, entry := range s.history {
+		if entry.TodoID == todoID {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, todoNotFound(todoID)
+	}
+	return entries, nil
+}
+
+func (s *MemoryTodoService) ListActivity(ctx context.Context, query ActivityQuery) (*ActivityPage, error) {
+	query, cur, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []HistoryEntry{}
+	for _, entry := range s.history {
+		if query.matches(entry) && cur.after(entry) && s.historyVisibleLocked(ctx, entry.TodoID) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].CreatedAt.Equal(entries[j].CreatedAt) {
+			return entries[i].CreatedAt.After(entries[j].CreatedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	if len(entries) > query.Limit+1 {
+		entries = entries[:query.Limit+1]
+	}
+	return newActivityPage(entries, query.Limit), nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	authMethodJWT    = "jwt"
+	authMethodAPIKey = "api_key"
+	// minHS256SecretLength là độ dài tối thiểu của JWT_HS256_SECRET (RFC 7518 mục 3.2).
+	minHS256SecretLength = 32
+	maxSubjectLength     = 255
+)
+
+// Principal là danh tính đã được xác thực của request: người dùng cùng cách họ xác thực.
+type Principal struct {
+	UserID string
+	Method string // authMethodJWT hoặc authMethodAPIKey
+	// APIKeyID là key đã dùng, rỗng khi xác thực bằng JWT.
+	APIKeyID string
+	// TenantID là tenant của người dùng: claim tenant_id của JWT hoặc tenant đã tạo API key.
+	TenantID string
+}
+
+type principalKey struct{}
+
+func withPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// principalFrom trả về danh tính đã xác thực của request, false khi xác thực bị tắt.
+func principalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticator xác minh bearer token của request: JWT ký bằng HS256 hoặc RS256, hoặc API key (tiền tố apiKeyPrefix)
+// tra trong APIKeyStore.
+type Authenticator struct {
+	// jwt là nil khi không cấu hình khóa JWT nào, khi đó chỉ API key được chấp nhận.
+	jwt *jwtVerifier
+	now func() time.Time
+}
+
+// NewAuthenticator tạo Authenticator với các khóa JWT cho trước; keys có thể rỗng.
+func NewAuthenticator(keys *jwtKeySet, issuer, audience string) *Authenticator {
+	a := &Authenticator{now: time.Now}
+	if keys != nil && !keys.empty() {
+		a.jwt = &jwtVerifier{keys: keys, issuer: issuer, audience: audience}
+	}
+	return a
+}
+
+// loadAuthenticator đọc cấu hình xác thực từ biến môi trường: JWT_HS256_SECRET (khóa tĩnh), JWT_RS256_PUBLIC_KEY
+// (đường dẫn file PEM), JWT_JWKS_FILE (đường dẫn file JWKS), JWT_ISSUER và JWT_AUDIENCE (claim iss và aud bắt buộc).
+func loadAuthenticator(getenv func(string) string) (*Authenticator, error) {
+	keys := &jwtKeySet{}
+	if secret := getenv("JWT_HS256_SECRET"); secret != "" {
+		if len(secret) < minHS256SecretLength {
+			return nil, fmt.Errorf("JWT_HS256_SECRET phải dài ít nhất %d byte", minHS256SecretLength)
+		}
+		keys.addSecret([]byte(secret))
+	}
+	if path := getenv("JWT_RS256_PUBLIC_KEY"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("đọc JWT_RS256_PUBLIC_KEY thất bại: %w", err)
+		}
+		if err := keys.addPublicKeyPEM(data); err != nil {
+			return nil, fmt.Errorf("JWT_RS256_PUBLIC_KEY không hợp lệ: %w", err)
+		}
+	}
+	if path := getenv("JWT_JWKS_FILE"); path != "" {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("đọc JWT_JWKS_FILE thất bại: %w", err)
+		}
+		if err := keys.addJWKS(data); err != nil {
+			return nil, fmt.Errorf("JWT_JWKS_FILE không hợp lệ: %w", err)
+		}
+	}
+	return NewAuthenticator(keys, getenv("JWT_ISSUER"), getenv("JWT_AUDIENCE")), nil
+}
+
+// acceptsJWT cho biết có khóa JWT nào được cấu hình.
+func (a *Authenticator) acceptsJWT() bool {
+	return a.jwt != nil
+}
+
+// principal xác minh token và trả về danh tính tương ứng. Subject của JWT là ID người dùng và claim tenant_id là tenant
+// của họ (DefaultTenantID khi vắng); người dùng chưa tồn tại được tạo trong tenant đó từ claim name và email, để
+// identity provider không phải đăng ký người dùng trước.
+func (a *Authenticator) principal(ctx context.Context, token string, users TodoService, apiKeys APIKeyStore) (Principal, error) {
+	now := a.now()
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		if apiKeys == nil {
+			return Principal{}, invalidToken("API keys are not enabled")
+		}
+		key, err := apiKeys.Authenticate(ctx, hashAPIKey(token), now)
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{UserID: key.UserID, Method: authMethodAPIKey, APIKeyID: key.ID, TenantID: key.TenantID}, nil
+	}
+
+	if a.jwt == nil {
+		return Principal{}, invalidToken("JWT authentication is not configured, use an API key")
+	}
+	claims, err := a.jwt.verify(token, now)
+	if err != nil {
+		return Principal{}, err
+	}
+	if len(claims.Subject) > maxSubjectLength {
+		return Principal{}, invalidToken(fmt.Sprintf("token subject must be at most %d characters", maxSubjectLength))
+	}
+	tenantID := DefaultTenantID
+	if claims.Tenant != "" {
+		if err := validateTenantID(claims.Tenant); err != nil {
+			return Principal{}, invalidToken("token tenant_id claim is invalid: " + err.Error())
+		}
+		tenantID = claims.Tenant
+	}
+	if err := provisionUser(withTenant(ctx, tenantID), users, claims); err != nil {
+		return Principal{}, err
+	}
+	return Principal{UserID: claims.Subject, Method: authMethodJWT, TenantID: tenantID}, nil
+}
+
+// provisionUser bảo đảm subject của token là một người dùng, tạo mới nếu cần.
+func provisionUser(ctx context.Context, users TodoService, claims *jwtClaims) error {
+	_, err := users.GetUser(ctx, claims.Subject)
+	var notFound *NotFoundError
+	if !errors.As(err, &notFound) {
+		return err
+	}
+	if claims.Email == "" {
+		return invalidToken("token subject is not a known user and the token has no email claim")
+	}
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = claims.Email
+	}
+	_, err = users.CreateUser(ctx, User{ID: claims.Subject, Name: name, Email: claims.Email})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrConflict):
+		// Request khác cùng subject vừa tạo người dùng; nếu không thì email đã thuộc về người dùng khác.
+		if _, err := users.GetUser(ctx, claims.Subject); err == nil {
+			return nil
+		}
+		return invalidToken("the email of the token belongs to another user")
+	case errors.Is(err, ErrValidation):
+		return invalidToken("token claims cannot create a user: " + err.Error())
+	}
+	return err
+}
+
+// writeUnauthorized trả về 401 kèm header WWW-Authenticate (RFC 6750).
+func writeUnauthorized(w http.ResponseWriter, r *http.Request, code, detail string) {
+	challenge := `Bearer realm="todo"`
+	if code == CodeInvalidToken {
+		challenge += `, error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeProblem(w, r, http.StatusUnauthorized, code, detail)
+}
+
+// authenticate yêu cầu header Authorization: Bearer với JWT hoặc API key và gắn người dùng đã xác thực vào context,
+// làm người dùng, actor và tenant của request. Tài liệu /swagger/ không cần xác thực. Bỏ qua khi h.auth là nil.
+func (h *APIHandler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.auth == nil || strings.HasPrefix(r.URL.Path, "/swagger/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		header := strings.TrimSpace(r.Header.Get("Authorization"))
+		if header == "" {
+			writeUnauthorized(w, r, CodeUnauthenticated, "an Authorization: Bearer token or API key is required")
+			return
+		}
+		scheme, token, ok := strings.Cut(header, " ")
+		token = strings.TrimSpace(token)
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			writeUnauthorized(w, r, CodeInvalidToken, "the Authorization header must use the Bearer scheme")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		p, err := h.auth.principal(ctx, token, h.todoService, h.apiKeys)
+		cancel()
+		if errors.Is(err, ErrInvalidToken) {
+			writeUnauthorized(w, r, CodeInvalidToken, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		ctx = withTenant(withActor(withUser(withPrincipal(r.Context(), p), p.UserID), p.UserID), p.TenantID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
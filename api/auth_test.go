@@ -0,0 +1,443 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHS256Secret = "0123456789abcdef0123456789abcdef"
+
+// signJWT ký token compact với header và claims cho trước; key là []byte cho HS256 hoặc *rsa.PrivateKey cho RS256.
+func signJWT(t *testing.T, header, claims map[string]interface{}, key interface{}) string {
+	t.Helper()
+	segment := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		require.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := segment(header) + "." + segment(claims)
+	var signature []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(sub string) map[string]interface{} {
+	return map[string]interface{}{"sub": sub, "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"rsa-1","use":"sig","n":%q,"e":%q},{"kty":"oct","kid":"hmac-1","k":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		base64.RawURLEncoding.EncodeToString([]byte("jwks-secret")))
+	keys := &jwtKeySet{}
+	require.NoError(t, keys.addJWKS([]byte(jwks)))
+	keys.addSecret([]byte(testHS256Secret))
+	der, err := x509.MarshalPKIXPublicKey(&otherKey.PublicKey)
+	require.NoError(t, err)
+	require.NoError(t, keys.addPublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})))
+	assert.Error(t, keys.addPublicKeyPEM([]byte("not a key")))
+
+	v := &jwtVerifier{keys: keys, issuer: "https://id.example.com", audience: "todo"}
+	now := time.Now()
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		c := validClaims("alice")
+		c["iss"] = "https://id.example.com"
+		c["aud"] = []string{"other", "todo"}
+		for k, val := range extra {
+			c[k] = val
+		}
+		return c
+	}
+	hs256 := map[string]interface{}{"alg": "HS256", "typ": "JWT"}
+
+	for name, token := range map[string]string{
+		"static HS256":       signJWT(t, hs256, claims(nil), []byte(testHS256Secret)),
+		"JWKS HS256":         signJWT(t, map[string]interface{}{"alg": "HS256", "kid": "hmac-1"}, claims(nil), []byte("jwks-secret")),
+		"JWKS RS256":         signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "rsa-1"}, claims(nil), rsaKey),
+		"static RS256":       signJWT(t, map[string]interface{}{"alg": "RS256"}, claims(nil), otherKey),
+		"audience string":    signJWT(t, hs256, claims(map[string]interface{}{"aud": "todo"}), []byte(testHS256Secret)),
+		"expiry within skew": signJWT(t, hs256, claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()}), []byte(testHS256Secret)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := v.verify(token, now)
+			require.NoError(t, err)
+			assert.Equal(t, "alice", got.Subject)
+		})
+	}
+
+	unsigned := signJWT(t, map[string]interface{}{"alg": "none"}, claims(nil), nil)
+	for name, token := range map[string]string{
+		"not a JWT":       "abc.def",
+		"alg none":        unsigned,
+		"wrong secret":    signJWT(t, hs256, claims(nil), []byte("another secret of thirty-two bytes")),
+		"unknown RSA key": signJWT(t, map[string]interface{}{"alg": "RS256"}, claims(nil), rsaKey),
+		// Khóa trong JWKS chỉ dùng cho thuật toán của nó, kể cả khi bí mật HS256 trùng.
+		"alg mismatch":   signJWT(t, map[string]interface{}{"alg": "RS256", "kid": "hmac-1"}, claims(nil), otherKey),
+		"tampered":       signJWT(t, hs256, claims(nil), []byte(testHS256Secret))[:20] + "x" + signJWT(t, hs256, claims(nil), []byte(testHS256Secret))[21:],
+		"expired":        signJWT(t, hs256, claims(map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}), []byte(testHS256Secret)),
+		"no expiry":      signJWT(t, hs256, claims(map[string]interface{}{"exp": nil}), []byte(testHS256Secret)),
+		"not yet valid":  signJWT(t, hs256, claims(map[string]interface{}{"nbf": now.Add(5 * time.Minute).Unix()}), []byte(testHS256Secret)),
+		"wrong issuer":   signJWT(t, hs256, claims(map[string]interface{}{"iss": "https://evil.example.com"}), []byte(testHS256Secret)),
+		"wrong audience": signJWT(t, hs256, claims(map[string]interface{}{"aud": "billing"}), []byte(testHS256Secret)),
+		"no subject":     signJWT(t, hs256, claims(map[string]interface{}{"sub": ""}), []byte(testHS256Secret)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := v.verify(token, now)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+// runAPIKeyStoreConformance kiểm tra các hành vi mà mọi APIKeyStore phải có; alice và bob phải là người dùng có sẵn.
+func runAPIKeyStoreConformance(t *testing.T, newStore func(t *testing.T) APIKeyStore) {
+	ctx := context.Background()
+	issue := func(t *testing.T, s APIKeyStore, userID, name string, expiresAt *time.Time) *APIKey {
+		key, err := newAPIKey(userID, name, expiresAt)
+		require.NoError(t, err)
+		created, err := s.Create(ctx, key)
+		require.NoError(t, err)
+		return created
+	}
+
+	t.Run("CreateAndAuthenticate", func(t *testing.T) {
+		s := newStore(t)
+		key := issue(t, s, "alice", " nightly import ", nil)
+		assert.True(t, strings.HasPrefix(key.Token, apiKeyPrefix))
+		assert.Equal(t, key.Token[:apiKeyDisplayLength], key.Prefix)
+		assert.Equal(t, "nightly import", key.Name)
+
+		now := time.Now()
+		got, err := s.Authenticate(ctx, hashAPIKey(key.Token), now)
+		require.NoError(t, err)
+		assert.Equal(t, key.ID, got.ID)
+		assert.Equal(t, "alice", got.UserID)
+		assert.Empty(t, got.Token)
+		require.NotNil(t, got.LastUsedAt)
+
+		keys, err := s.List(ctx, "alice")
+		require.NoError(t, err)
+		require.Len(t, keys, 1)
+		assert.Empty(t, keys[0].Token, "token must never be returned again")
+		assert.NotNil(t, keys[0].LastUsedAt)
+
+		_, err = s.Authenticate(ctx, hashAPIKey(apiKeyPrefix+"unknown"), now)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		s := newStore(t)
+		past := time.Now().Add(-time.Hour)
+		for _, name := range []string{"", strings.Repeat("x", maxAPIKeyNameLength+1)} {
+			key, err := newAPIKey("alice", name, nil)
+			require.NoError(t, err)
+			_, err = s.Create(ctx, key)
+			assert.ErrorIs(t, err, ErrValidation)
+		}
+		key, err := newAPIKey("alice", "old", &past)
+		require.NoError(t, err)
+		_, err = s.Create(ctx, key)
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("ExpiredAndRevoked", func(t *testing.T) {
+		s := newStore(t)
+		soon := time.Now().Add(time.Hour)
+		expiring := issue(t, s, "alice", "expiring", &soon)
+		_, err := s.Authenticate(ctx, hashAPIKey(expiring.Token), soon.Add(time.Second))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+
+		key := issue(t, s, "alice", "revoked", nil)
+		var notFound *NotFoundError
+		assert.ErrorAs(t, s.Revoke(ctx, "bob", key.ID, time.Now()), &notFound, "other users cannot revoke the key")
+		assert.ErrorAs(t, s.Revoke(ctx, "alice", "missing", time.Now()), &notFound)
+		require.NoError(t, s.Revoke(ctx, "alice", key.ID, time.Now()))
+		require.NoError(t, s.Revoke(ctx, "alice", key.ID, time.Now()), "revoking twice is not an error")
+		_, err = s.Authenticate(ctx, hashAPIKey(key.Token), time.Now())
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("ListPerUser", func(t *testing.T) {
+		s := newStore(t)
+		first := issue(t, s, "alice", "first", nil)
+		time.Sleep(time.Millisecond)
+		second := issue(t, s, "alice", "second", nil)
+		issue(t, s, "bob", "bob's", nil)
+
+		keys, err := s.List(ctx, "alice")
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		assert.Equal(t, second.ID, keys[0].ID)
+		assert.Equal(t, first.ID, keys[1].ID)
+
+		keys, err = s.List(ctx, "carol")
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+	})
+
+	t.Run("PerTenant", func(t *testing.T) {
+		s := newStore(t)
+		acme := withTenant(ctx, "acme")
+		key, err := newAPIKey("alice", "acme import", nil)
+		require.NoError(t, err)
+		created, err := s.Create(acme, key)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", created.TenantID)
+
+		got, err := s.Authenticate(ctx, hashAPIKey(created.Token), time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, "acme", got.TenantID, "authentication finds the key in any tenant")
+		keys, err := s.List(ctx, "alice")
+		require.NoError(t, err)
+		assert.Empty(t, keys)
+		var notFound *NotFoundError
+		assert.ErrorAs(t, s.Revoke(ctx, "alice", created.ID, time.Now()), &notFound)
+		keys, err = s.List(acme, "alice")
+		require.NoError(t, err)
+		assert.Len(t, keys, 1)
+	})
+}
+
+func TestMemoryAPIKeyStore(t *testing.T) {
+	runAPIKeyStoreConformance(t, func(t *testing.T) APIKeyStore {
+		return NewMemoryAPIKeyStore()
+	})
+}
+
+func TestDbAPIKeyStore(t *testing.T) {
+	pool := testPool(t)
+
+	runAPIKeyStoreConformance(t, func(t *testing.T) APIKeyStore {
+		ctx := context.Background()
+		_, err := pool.Exec(ctx, "TRUNCATE users CASCADE")
+		require.NoError(t, err)
+		_, err = pool.Exec(ctx, "INSERT INTO users (id, name, email) VALUES ('alice', 'Alice', 'alice@example.com'), ('bob', 'Bob', 'bob@example.com')")
+		require.NoError(t, err)
+		return NewDbAPIKeyStore(&Db{conn: pool})
+	})
+}
+
+func TestRouter_Authentication(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	handler.auth = NewAuthenticator(func() *jwtKeySet {
+		keys := &jwtKeySet{}
+		keys.addSecret([]byte(testHS256Secret))
+		return keys
+	}(), "", "")
+	handler.apiKeys = NewMemoryAPIKeyStore()
+	router := newRouter(handler)
+
+	send := func(method, path, body, authorization string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	hs256 := map[string]interface{}{"alg": "HS256"}
+	aliceClaims := validClaims("alice")
+	aliceClaims["email"] = "Alice@Example.com"
+	aliceClaims["name"] = "Alice"
+	alice := "Bearer " + signJWT(t, hs256, aliceClaims, []byte(testHS256Secret))
+
+	rr := send(http.MethodGet, "/v1/todos", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, CodeUnauthenticated, decodeProblem(t, rr).Code)
+	assert.Equal(t, `Bearer realm="todo"`, rr.Header().Get("WWW-Authenticate"))
+
+	for _, authorization := range []string{"Basic YWxpY2U6c2VjcmV0", "Bearer not.a.jwt", "Bearer " + apiKeyPrefix + "unknown",
+		"Bearer " + signJWT(t, hs256, validClaims("bob"), []byte(testHS256Secret))} {
+		rr = send(http.MethodGet, "/v1/todos", "", authorization)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code, authorization)
+		assert.Equal(t, CodeInvalidToken, decodeProblem(t, rr).Code)
+		assert.Contains(t, rr.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	}
+
+	// Subject mới được tạo thành người dùng từ claim name và email, và là owner của todo họ tạo.
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"Alice's todo"}`, alice)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var todo Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+	assert.Equal(t, "alice", todo.OwnerID)
+	user, err := store.GetUser(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", user.Email)
+
+	// X-User-ID không đổi được người dùng đã xác thực.
+	_, err = store.CreateUser(context.Background(), User{ID: "mallory", Name: "Mallory", Email: "mallory@example.com"})
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/todos", strings.NewReader(`{"title":"Mallory's todo"}`))
+	req.Header.Set("Authorization", alice)
+	req.Header.Set(userHeader, "mallory")
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+	assert.Equal(t, "alice", todo.OwnerID)
+
+	// Email của token đã thuộc về người dùng khác.
+	mallory := validClaims("mallory-2")
+	mallory["email"] = "alice@example.com"
+	rr = send(http.MethodGet, "/v1/todos", "", "Bearer "+signJWT(t, hs256, mallory, []byte(testHS256Secret)))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = send(http.MethodPost, "/v1/api-keys", `{"name":"nightly import","token":"mine"}`, alice)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	rr = send(http.MethodPost, "/v1/api-keys", `{"name":"nightly import"}`, alice)
+	assert.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	var key APIKey
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&key))
+	assert.True(t, strings.HasPrefix(key.Token, apiKeyPrefix))
+	assert.Equal(t, "alice", key.UserID)
+
+	// Script dùng API key thao tác như Alice.
+	automation := "Bearer " + key.Token
+	rr = send(http.MethodGet, "/v1/todos", "", automation)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var todos []Todo
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+	assert.Len(t, todos, 2)
+
+	rr = send(http.MethodGet, "/v1/api-keys", "", automation)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var keys []APIKey
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&keys))
+	if assert.Len(t, keys, 1) {
+		assert.Empty(t, keys[0].Token)
+		assert.Equal(t, key.Prefix, keys[0].Prefix)
+		assert.NotNil(t, keys[0].LastUsedAt)
+	}
+
+	// Người dùng khác không thấy và không thu hồi được key của Alice.
+	bob := validClaims("bob")
+	bob["email"] = "bob@example.com"
+	bobAuth := "Bearer " + signJWT(t, hs256, bob, []byte(testHS256Secret))
+	rr = send(http.MethodGet, "/v1/api-keys", "", bobAuth)
+	assert.Equal(t, "[]\n", rr.Body.String())
+	rr = send(http.MethodDelete, "/v1/api-keys/"+key.ID, "", bobAuth)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "api_key_not_found", decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodDelete, "/v1/api-keys/"+key.ID, "", alice)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	rr = send(http.MethodGet, "/v1/todos", "", automation)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, CodeInvalidToken, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodGet, "/swagger/index.html", "", "")
+	assert.NotEqual(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestRouter_APIKeysWithoutAuthentication(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	handler.apiKeys = NewMemoryAPIKeyStore()
+	router := newRouter(handler)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/v1/api-keys", strings.NewReader(`{"name":"script"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, CodeUnauthenticated, decodeProblem(t, rr).Code)
+}
+
+func TestRouter_TenantAuthentication(t *testing.T) {
+	store := NewTenantTodoService(func() TodoService { return NewMemoryTodoService() })
+	handler := NewAPIHandler(store, NewMemoryIdempotencyStore())
+	keys := &jwtKeySet{}
+	keys.addSecret([]byte(testHS256Secret))
+	handler.auth = NewAuthenticator(keys, "", "")
+	handler.apiKeys = NewMemoryAPIKeyStore()
+	router := newRouter(handler)
+
+	send := func(method, path, body, authorization, tenant string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", authorization)
+		if tenant != "" {
+			req.Header.Set(tenantHeader, tenant)
+		}
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	token := func(sub, tenant string) string {
+		claims := validClaims(sub)
+		claims["email"] = sub + "@example.com"
+		if tenant != "" {
+			claims["tenant_id"] = tenant
+		}
+		return "Bearer " + signJWT(t, map[string]interface{}{"alg": "HS256"}, claims, []byte(testHS256Secret))
+	}
+	count := func(authorization string) int {
+		rr := send(http.MethodGet, "/v1/todos", "", authorization, "")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var todos []Todo
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&todos))
+		return len(todos)
+	}
+	alice, bob := token("alice", "acme"), token("bob", "")
+
+	rr := send(http.MethodPost, "/v1/todos", `{"title":"rotate keys"}`, alice, "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	_, err := store.GetUser(withTenant(context.Background(), "acme"), "alice")
+	assert.NoError(t, err, "the user is provisioned in the tenant of the token")
+	assert.Equal(t, 1, count(alice))
+	assert.Equal(t, 0, count(bob), "tokens without tenant_id use the default tenant")
+
+	rr = send(http.MethodGet, "/v1/todos", "", alice, "acme")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = send(http.MethodGet, "/v1/todos", "", alice, "globex")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, CodeTenantMismatch, decodeProblem(t, rr).Code)
+
+	rr = send(http.MethodPost, "/v1/todos", `{"title":"plan offsite"}`, token("alice", "globex"), "")
+	require.Equal(t, http.StatusCreated, rr.Code, "the same subject can join another tenant: %s", rr.Body.String())
+	assert.Equal(t, 1, count(alice))
+
+	rr = send(http.MethodGet, "/v1/todos", "", token("carol", "Acme Corp"), "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, CodeInvalidToken, decodeProblem(t, rr).Code)
+
+	// API key dùng tenant của người tạo ra nó.
+	rr = send(http.MethodPost, "/v1/api-keys", `{"name":"acme import"}`, alice, "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var key APIKey
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&key))
+	assert.Equal(t, "acme", key.TenantID)
+	assert.Equal(t, 1, count("Bearer "+key.Token))
+	rr = send(http.MethodGet, "/v1/api-keys", "", bob, "")
+	assert.Equal(t, "[]\n", rr.Body.String())
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	maxBulkItems     = 100
+	maxBulkBodyBytes = 1 << 20
+)
+
+// BulkOptions điều khiển cách một batch được áp dụng.
+type BulkOptions struct {
+	// Atomic=true: nếu một phần tử lỗi thì toàn bộ batch bị hủy (all-or-nothing).
+	// Atomic=false: các phần tử thành công vẫn được ghi, phần tử lỗi được báo riêng.
+	Atomic bool
+}
+
+// BulkItem chọn một todo trong batch. Version khác 0 có tác dụng như If-Match cho riêng phần tử đó.
+type BulkItem struct {
+	ID      string
+	Version int
+	Patch   TodoPatch // chỉ dùng cho BulkPatchTodos
+}
+
+// BulkResult là kết quả của một phần tử, theo đúng thứ tự đầu vào.
+// Todo là nil với delete và với phần tử lỗi; Err là ErrRolledBack nếu phần tử bị hủy theo batch atomic.
+type BulkResult struct {
+	ID   string
+	Todo *Todo
+	Err  error
+}
+
+// rollbackResults đánh dấu các phần tử đã thành công là bị hủy khi batch atomic thất bại.
+func rollbackResults(results []BulkResult) {
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Todo = nil
+			results[i].Err = ErrRolledBack
+		}
+	}
+}
+
+func itemIDs(results []BulkResult, items []BulkItem) []BulkResult {
+	for i := range results {
+		if results[i].ID == "" {
+			results[i].ID = items[i].ID
+		}
+	}
+	return results
+}
+
+// runBulk chạy n phần tử trong một transaction. Mỗi phần tử dùng một savepoint riêng,
+// nên ở chế độ không atomic lỗi của một phần tử (kể cả lỗi SQL) không làm hỏng các phần tử còn lại.
+func (s *DbTodoService) runBulk(ctx context.Context, n int, opts BulkOptions, apply func(svc *DbTodoService, i int) (*Todo, error)) ([]BulkResult, error) {
+	tx, err := s.db.conn.Begin(ctx)
+	if err != nil {
+		return nil, dbError("bắt đầu transaction thất bại", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Sự kiện của phần tử bị rollback về savepoint, hoặc của cả batch không được commit, bị bỏ khỏi eventBatch.
+	events := eventsFrom(ctx)
+	start := events.mark()
+	results := make([]BulkResult, n)
+	failed := false
+	for i := range results {
+		if failed && opts.Atomic {
+			results[i].Err = ErrRolledBack
+			continue
+		}
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			events.truncate(start)
+			return nil, dbError("tạo savepoint thất bại", err)
+		}
+		mark := events.mark()
+		todo, err := apply(&DbTodoService{db: s.db, tx: sp, workflow: s.workflow}, i)
+		if err != nil {
+			sp.Rollback(ctx)
+			events.truncate(mark)
+		} else if err = sp.Commit(ctx); err != nil {
+			events.truncate(mark)
+			todo, err = nil, dbError("giải phóng savepoint thất bại", err)
+		}
+		if todo != nil {
+			results[i].ID = todo.ID
+		}
+		results[i].Todo, results[i].Err = todo, err
+		failed = failed || err != nil
+	}
+
+	if opts.Atomic && failed {
+		events.truncate(start)
+		rollbackResults(results)
+		return results, nil
+	}
+	if err := tx.Commit(ctx); err != nil {
+		events.truncate(start)
+		return nil, dbError("commit batch thất bại", err)
+	}
+	return results, nil
+}
+
+func (s *DbTodoService) BulkCreateTodos(ctx context.Context, todos []Todo, opts BulkOptions) ([]BulkResult, error) {
+	return s.runBulk(ctx, len(todos), opts, func(svc *DbTodoService, i int) (*Todo, error) {
+		return svc.CreateTodo(ctx, todos[i])
+	})
+}
+
+func (s *DbTodoService) BulkSetDone(ctx context.Context, items []BulkItem, done bool, opts BulkOptions) ([]BulkResult, error) {
+	results, err := s.runBulk(ctx, len(items), opts, func(svc *DbTodoService, i int) (*Todo, error) {
+		return svc.setDone(withExpectedVersion(ctx, items[i].Version), items[i].ID, done)
+	})
+	return itemIDs(results, items), err
+}
+
+func (s *DbTodoService) BulkPatchTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	results, err := s.runBulk(ctx, len(items), opts, func(svc *DbTodoService, i int) (*Todo, error) {
+		return svc.PatchTodo(withExpectedVersion(ctx, items[i].Version), items[i].ID, items[i].Patch)
+	})
+	return itemIDs(results, items), err
+}
+
+func (s *DbTodoService) BulkDeleteTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	results, err := s.runBulk(ctx, len(items), opts, func(svc *DbTodoService, i int) (*Todo, error) {
+		return nil, svc.DeleteTodo(withExpectedVersion(ctx, items[i].Version), items[i].ID)
+	})
+	return itemIDs(results, items), err
+}
+
+// runBulk giữ khóa trong suốt batch để các request khác không thấy trạng thái dở dang.
+// Batch atomic thất bại được khôi phục từ bản sao chụp trước khi chạy.
+func (s *MemoryTodoService) runBulk(ctx context.Context, n int, opts BulkOptions, apply func(i int) (*Todo, error)) []BulkResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var snapshot map[string]Todo
+	var tags map[string]Tag
+	var trash map[string]Todo
+	var shares map[string][]TodoShare
+	// history chỉ được ghi thêm, nên cắt về độ dài cũ là đủ để hủy các bản ghi của batch.
+	history := len(s.history)
+	events := eventsFrom(ctx)
+	start := events.mark()
+	if opts.Atomic {
+		snapshot = make(map[string]Todo, len(s.todos))
+		for id, todo := range s.todos {
+			snapshot[id] = todo
+		}
+		// Tag được tạo ngầm khi gắn cho todo cũng phải được hủy cùng batch.
+		tags = make(map[string]Tag, len(s.tags))
+		for id, tag := range s.tags {
+			tags[id] = tag
+		}
+		// Todo bị xóa trong batch được chuyển vào thùng rác, nên thùng rác cũng phải được khôi phục.
+		trash = make(map[string]Todo, len(s.trash))
+		for id, todo := range s.trash {
+			trash[id] = todo
+		}
+		// Done một occurrence chép các lần chia sẻ sang occurrence tiếp theo (xem spawnNextLocked).
+		shares = make(map[string][]TodoShare, len(s.shares))
+		for id, todoShares := range s.shares {
+			shares[id] = todoShares
+		}
+	}
+
+	results := make([]BulkResult, n)
+	failed := false
+	for i := range results {
+		if failed && opts.Atomic {
+			results[i].Err = ErrRolledBack
+			continue
+		}
+		todo, err := apply(i)
+		if todo != nil {
+			results[i].ID = todo.ID
+		}
+		results[i].Todo, results[i].Err = todo, err
+		failed = failed || err != nil
+	}
+
+	if opts.Atomic && failed {
+		s.todos = snapshot
+		s.tags = tags
+		s.trash = trash
+		s.shares = shares
+		s.history = s.history[:history]
+		events.truncate(start)
+		rollbackResults(results)
+	}
+	return results
+}
+
+func (s *MemoryTodoService) BulkCreateTodos(ctx context.Context, todos []Todo, opts BulkOptions) ([]BulkResult, error) {
+	return s.runBulk(ctx, len(todos), opts, func(i int) (*Todo, error) {
+		todo := todos[i]
+		if err := validateTodo(todo); err != nil {
+			return nil, err
+		}
+		startSeries(&todo)
+		todo.OwnerID = userFrom(ctx)
+		return s.createLocked(ctx, todo)
+	}), nil
+}
+
+func (s *MemoryTodoService) BulkSetDone(ctx context.Context, items []BulkItem, done bool, opts BulkOptions) ([]BulkResult, error) {
+	return itemIDs(s.runBulk(ctx, len(items), opts, func(i int) (*Todo, error) {
+		return s.setDoneLocked(withExpectedVersion(ctx, items[i].Version), items[i].ID, done)
+	}), items), nil
+}
+
+func (s *MemoryTodoService) BulkPatchTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	return itemIDs(s.runBulk(ctx, len(items), opts, func(i int) (*Todo, error) {
+		if err := validatePatch(items[i].Patch); err != nil {
+			return nil, err
+		}
+		return s.patchLocked(withExpectedVersion(ctx, items[i].Version), items[i].ID, items[i].Patch)
+	}), items), nil
+}
+
+func (s *MemoryTodoService) BulkDeleteTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	return itemIDs(s.runBulk(ctx, len(items), opts, func(i int) (*Todo, error) {
+		return nil, s.deleteLocked(withExpectedVersion(ctx, items[i].Version), items[i].ID)
+	}), items), nil
+}
+
+// BulkRequest là payload của POST /v1/todos/bulk.
+// Phần tử của items tùy theo action: create nhận các trường của POST /v1/todos; done, undone và delete nhận {id, version};
+// update nhận {id, version, patch} với patch là một merge patch như PATCH /v1/todos/{id}.
+type BulkRequest struct {
+	Action string            `json:"action" enums:"create,done,undone,update,delete"`
+	Atomic bool              `json:"atomic"`
+	Items  []json.RawMessage `json:"items" swaggertype:"array,object"`
+}
+
+// BulkItemResponse là kết quả của một phần tử trong response bulk.
+type BulkItemResponse struct {
+	Index  int      `json:"index"`
+	ID     string   `json:"id,omitempty"`
+	Status int      `json:"status"`
+	Todo   *Todo    `json:"todo,omitempty"`
+	Error  *Problem `json:"error,omitempty"`
+}
+
+// BulkResponse báo cáo từng phần tử của batch; committed=false nghĩa là không có thay đổi nào được ghi.
+type BulkResponse struct {
+	Action    string             `json:"action"`
+	Atomic    bool               `json:"atomic"`
+	Committed bool               `json:"committed"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Results   []BulkItemResponse `json:"results"`
+}
+
+var bulkActions = []string{"create", "done", "undone", "update", "delete"}
+
+var (
+	bulkRequestRules = payloadRules{
+		fields: map[string]fieldRule{
+			"action": {kind: "string", required: true, trim: true},
+			"atomic": {kind: "boolean"},
+			"items":  {kind: "array", required: true, maxLength: maxBulkItems},
+		},
+	}
+	bulkTargetRules = payloadRules{
+		fields: map[string]fieldRule{
+			"id":      {kind: "string", required: true, trim: true},
+			"version": {kind: "integer"},
+		},
+	}
+	bulkUpdateRules = payloadRules{
+		fields: map[string]fieldRule{
+			"id":      {kind: "string", required: true, trim: true},
+			"version": {kind: "integer"},
+			"patch":   {kind: "object", required: true},
+		},
+	}
+)
+
+// bulkBatch là BulkRequest đã được kiểm tra và chuyển sang kiểu của TodoService.
+type bulkBatch struct {
+	action string
+	opts   BulkOptions
+	todos  []Todo     // action create
+	items  []BulkItem // các action còn lại
+}
+
+// parseBulkRequest kiểm tra toàn bộ batch trước khi chạy: mọi vi phạm của mọi phần tử
+// được trả về cùng lúc với tên trường dạng items[i].title.
+func parseBulkRequest(w http.ResponseWriter, r *http.Request) (*bulkBatch, error) {
+	body, err := readBodyLimit(w, r, maxBulkBodyBytes)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+	var req BulkRequest
+	if err := bulkRequestRules.decode(fields, &req); err != nil {
+		return nil, err
+	}
+
+	verr := &ValidationError{}
+	batch := &bulkBatch{action: req.Action, opts: BulkOptions{Atomic: req.Atomic}}
+	if !containsString(bulkActions, req.Action) {
+		verr.Add("action", "invalid_value", "action must be one of "+strings.Join(bulkActions, ", "))
+		return nil, verr
+	}
+
+	for i, raw := range req.Items {
+		prefix := fmt.Sprintf("items[%d].", i)
+		item, err := decodeObject(raw)
+		if err != nil {
+			verr.Add(fmt.Sprintf("items[%d]", i), "invalid_type", "item must be an object")
+			continue
+		}
+		switch req.Action {
+		case "create":
+			var input CreateTodoRequest
+			err = addPrefixed(verr, prefix, createTodoRules.decode(item, &input))
+			batch.todos = append(batch.todos, input.todo())
+		case "update":
+			var input struct {
+				ID      string          `json:"id"`
+				Version int             `json:"version"`
+				Patch   json.RawMessage `json:"patch"`
+			}
+			err = addPrefixed(verr, prefix, bulkUpdateRules.decode(item, &input))
+			if err == nil && input.Patch != nil {
+				var patch TodoPatch
+				patch, err = decodeMergePatch(input.Patch)
+				err = addPrefixed(verr, prefix+"patch.", err)
+				batch.items = append(batch.items, BulkItem{ID: input.ID, Version: input.Version, Patch: patch})
+			}
+		default:
+			var input struct {
+				ID      string `json:"id"`
+				Version int    `json:"version"`
+			}
+			err = addPrefixed(verr, prefix, bulkTargetRules.decode(item, &input))
+			batch.items = append(batch.items, BulkItem{ID: input.ID, Version: input.Version})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newBulkResponse dựng response và chọn status tổng: 200 khi mọi phần tử thành công, 207 khi batch không atomic
+// có phần tử lỗi, và status của phần tử lỗi đầu tiên khi batch atomic bị hủy.
+func newBulkResponse(r *http.Request, batch *bulkBatch, results []BulkResult) (BulkResponse, int) {
+	resp := BulkResponse{
+		Action:    batch.action,
+		Atomic:    batch.opts.Atomic,
+		Committed: true,
+		Results:   make([]BulkItemResponse, len(results)),
+	}
+	for i, result := range results {
+		item := BulkItemResponse{Index: i, ID: result.ID, Todo: result.Todo}
+		switch {
+		case result.Err != nil:
+			p := problemFor(r, result.Err)
+			item.Status, item.Error = p.Status, &p
+			resp.Failed++
+		case batch.action == "create":
+			item.Status = http.StatusCreated
+			resp.Succeeded++
+		case batch.action == "delete":
+			item.Status = http.StatusNoContent
+			resp.Succeeded++
+		default:
+			item.Status = http.StatusOK
+			resp.Succeeded++
+		}
+		resp.Results[i] = item
+	}
+
+	if resp.Failed == 0 {
+		return resp, http.StatusOK
+	}
+	if !batch.opts.Atomic {
+		return resp, http.StatusMultiStatus
+	}
+	resp.Committed = false
+	for _, item := range resp.Results {
+		if item.Error != nil && item.Error.Code != CodeRolledBack {
+			return resp, item.Status
+		}
+	}
+	return resp, http.StatusConflict
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"strings"
+	"time"
+)
+
+// ChecklistItem là một bước trong checklist của todo ("write migration", "write handler"...).
+// Position bắt đầu từ 1 và liên tục trong một todo.
+type ChecklistItem struct {
+	ID        string    `json:"id"`
+	TodoID    string    `json:"todo_id"`
+	Title     string    `json:"title" example:"Write migration"`
+	Done      bool      `json:"done"`
+	Position  int       `json:"position" example:"1"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ChecklistProgress là tiến độ checklist của todo, ví dụ 3/5 là {"done": 3, "total": 5}.
+type ChecklistProgress struct {
+	Done  int `json:"done" example:"3"`
+	Total int `json:"total" example:"5"`
+}
+
+// ChecklistItemPatch là thay đổi của một item: trường nil được giữ nguyên.
+// Khi thêm item, Title là bắt buộc và Position nil nghĩa là thêm vào cuối.
+type ChecklistItemPatch struct {
+	Title    *string
+	Done     *bool
+	Position *int
+}
+
+const maxChecklistItems = 100
+
+func checklistItemNotFound(id string) error {
+	return &NotFoundError{Resource: "checklist_item", ID: id}
+}
+
+func validateChecklistItem(patch ChecklistItemPatch, adding bool) error {
+	verr := &ValidationError{}
+	if patch.Title != nil {
+		validateTitle(verr, *patch.Title)
+	} else if adding {
+		verr.Add("title", "required", "title is required")
+	}
+	if patch.Position != nil && *patch.Position < 1 {
+		verr.Add("position", "invalid_value", "position must be at least 1")
+	}
+	return verr.Err()
+}
+
+func tooManyChecklistItems() error {
+	verr := &ValidationError{}
+	verr.Add("checklist", "too_many", fmt.Sprintf("a todo can have at most %d checklist items", maxChecklistItems))
+	return verr
+}
+
+// clampPosition đưa vị trí client gửi về khoảng [1, n]; vị trí lớn hơn n nghĩa là cuối danh sách.
+func clampPosition(position, n int) int {
+	if position > n {
+		return n
+	}
+	if position < 1 {
+		return 1
+	}
+	return position
+}
+
+// checklistCompletes cho biết todo có nên tự chuyển sang done hay không: mọi item đã được check
+// và workflow cho phép đi từ trạng thái hiện tại tới done (ví dụ todo đang blocked thì giữ nguyên).
+func checklistCompletes(wf *Workflow, todo *Todo) bool {
+	return todo.Checklist.Total > 0 && todo.Checklist.Done == todo.Checklist.Total &&
+		!todo.Done && wf.Allowed(todo.Status, StatusDone)
+}
+
+// ChecklistItemRequest là payload của POST /v1/todos/{id}/checklist. Không có position thì item được thêm vào cuối.
+type ChecklistItemRequest struct {
+	Title    string `json:"title" example:"Write migration"`
+	Position *int   `json:"position,omitempty" example:"1"`
+}
+
+// ChecklistItemPatchRequest là payload của PATCH /v1/todos/{id}/checklist/{item_id}: check/bỏ check,
+// đổi tên hoặc chuyển item tới vị trí khác; các item còn lại được dồn lại cho liên tục.
+type ChecklistItemPatchRequest struct {
+	Title    *string `json:"title,omitempty" example:"Write migration"`
+	Done     *bool   `json:"done,omitempty"`
+	Position *int    `json:"position,omitempty" example:"2"`
+}
+
+var checklistItemReadOnly = map[string]FieldError{
+	"id":         {Code: "read_only", Message: "id is assigned by the server"},
+	"todo_id":    {Code: "read_only", Message: "todo_id is taken from the URL"},
+	"created_at": {Code: "read_only", Message: "created_at is set by the server"},
+	"updated_at": {Code: "read_only", Message: "updated_at is set by the server"},
+}
+
+var (
+	addChecklistItemRules = payloadRules{
+		fields: map[string]fieldRule{
+			"title":    titleRule,
+			"position": {kind: "integer"},
+		},
+		rejected: withRejected(checklistItemReadOnly, map[string]FieldError{
+			"done": {Code: "not_allowed", Message: "new checklist items always start unchecked"},
+		}),
+	}
+	patchChecklistItemRules = payloadRules{
+		fields: map[string]fieldRule{
+			"title":    {kind: "string", trim: true, maxLength: maxTitleLength},
+			"done":     {kind: "boolean"},
+			"position": {kind: "integer"},
+		},
+		rejected: checklistItemReadOnly,
+	}
+)
+
+func (r ChecklistItemRequest) patch() ChecklistItemPatch {
+	return ChecklistItemPatch{Title: &r.Title, Position: r.Position}
+}
+
+func (r ChecklistItemPatchRequest) patch() ChecklistItemPatch {
+	return ChecklistItemPatch{Title: r.Title, Done: r.Done, Position: r.Position}
+}
+
+const checklistColumns = "id, todo_id, title, done, position, created_at, updated_at"
+
+func scanChecklistItem(row pgx.Row, item *ChecklistItem) error {
+	return row.Scan(&item.ID, &item.TodoID, &item.Title, &item.Done, &item.Position, &item.CreatedAt, &item.UpdatedAt)
+}
+
+// loadChecklistProgress đếm item của nhiều todo bằng một câu truy vấn duy nhất.
+func (s *DbTodoService) loadChecklistProgress(ctx context.Context, todos ...*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(todos))
+	byID := make(map[string]*Todo, len(todos))
+	for _, todo := range todos {
+		todo.Checklist = ChecklistProgress{}
+		ids = append(ids, todo.ID)
+		byID[todo.ID] = todo
+	}
+
+	rows, err := s.conn().Query(ctx,
+		"SELECT todo_id, COUNT(CASE WHEN done THEN 1 END), COUNT(*) FROM checklist_item WHERE todo_id = ANY($1) AND tenant_id = $2 GROUP BY todo_id",
+		ids, tenantFrom(ctx))
+	if err != nil {
+		return dbError("đọc checklist của todo thất bại", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoID string
+		var progress ChecklistProgress
+		if err := rows.Scan(&todoID, &progress.Done, &progress.Total); err != nil {
+			return dbError("scan checklist thất bại", err)
+		}
+		if todo, ok := byID[todoID]; ok {
+			todo.Checklist = progress
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbError("lỗi sau khi đọc checklist", err)
+	}
+	return nil
+}
+
+// touchTodo tăng version của todo khi checklist của nó thay đổi (tiến độ là một phần của todo và ETag).
+// Câu UPDATE cũng khóa dòng todo nên các thay đổi vị trí trên cùng checklist được thực hiện lần lượt.
+func (s *DbTodoService) touchTodo(ctx context.Context, todoID string) error {
+	w := &sqlWhere{}
+	now := w.arg(time.Now().UTC().Truncate(time.Microsecond))
+	liveTodo(ctx, w, todoID)
+	tag, err := s.conn().Exec(ctx, "UPDATE todo SET version = version + 1, updated_at = "+now+w.String(), w.args...)
+	if err != nil {
+		return dbError("cập nhật todo thất bại", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return todoNotFound(todoID)
+	}
+	return nil
+}
+
+func (s *DbTodoService) countChecklist(ctx context.Context, todoID string) (int, error) {
+	var n int
+	if err := s.conn().QueryRow(ctx, "SELECT COUNT(*) FROM checklist_item WHERE todo_id = $1 AND tenant_id = $2", todoID, tenantFrom(ctx)).Scan(&n); err != nil {
+		return 0, dbError("đếm checklist thất bại", err)
+	}
+	return n, nil
+}
+
+// ListChecklist và GetChecklistItem chỉ đọc item của todo còn sống mà người dùng trong ctx được thấy (xem liveTodo).
+func (s *DbTodoService) ListChecklist(ctx context.Context, todoID string) ([]ChecklistItem, error) {
+	w := &sqlWhere{}
+	liveTodo(ctx, w, todoID)
+	rows, err := s.conn().Query(ctx,
+		"SELECT "+checklistColumns+" FROM checklist_item WHERE todo_id IN (SELECT id FROM todo"+w.String()+") AND tenant_id = "+
+			w.arg(tenantFrom(ctx))+" ORDER BY position", w.args...)
+	if err != nil {
+		return nil, dbError("truy vấn checklist thất bại", err)
+	}
+	defer rows.Close()
+
+	items := []ChecklistItem{}
+	for rows.Next() {
+		var item ChecklistItem
+		if err := scanChecklistItem(rows, &item); err != nil {
+			return nil, dbError("scan checklist thất bại", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc checklist", err)
+	}
+	if len(items) == 0 {
+		// Checklist rỗng: phân biệt todo không có item với todo không tồn tại.
+		if _, err := s.GetTodo(ctx, todoID); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+func (s *DbTodoService) GetChecklistItem(ctx context.Context, todoID, itemID string) (*ChecklistItem, error) {
+	var item ChecklistItem
+	w := &sqlWhere{}
+	liveTodo(ctx, w, todoID)
+	err := scanChecklistItem(s.conn().QueryRow(ctx,
+		"SELECT "+checklistColumns+" FROM checklist_item WHERE todo_id IN (SELECT id FROM todo"+w.String()+") AND id = "+w.arg(itemID)+
+			" AND tenant_id = "+w.arg(tenantFrom(ctx)), w.args...), &item)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if _, err := s.GetTodo(ctx, todoID); err != nil {
+				return nil, err
+			}
+			return nil, checklistItemNotFound(itemID)
+		}
+		return nil, dbError("truy vấn checklist thất bại", err)
+	}
+	return &item, nil
+}
+
+func (s *DbTodoService) AddChecklistItem(ctx context.Context, todoID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	if err := validateChecklistItem(patch, true); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	item := ChecklistItem{ID: generateNewID(), TodoID: todoID, Title: *patch.Title, CreatedAt: now, UpdatedAt: now}
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		if err := svc.touchTodo(ctx, todoID); err != nil {
+			return err
+		}
+		n, err := svc.countChecklist(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		if n >= maxChecklistItems {
+			return tooManyChecklistItems()
+		}
+		item.Position = n + 1
+		if patch.Position != nil {
+			item.Position = clampPosition(*patch.Position, n+1)
+		}
+		if item.Position <= n {
+			if _, err := svc.conn().Exec(ctx,
+				"UPDATE checklist_item SET position = position + 1 WHERE todo_id = $1 AND tenant_id = $2 AND position >= $3",
+				todoID, tenantFrom(ctx), item.Position); err != nil {
+				return dbError("dồn vị trí checklist thất bại", err)
+			}
+		}
+		_, err = svc.conn().Exec(ctx,
+			"INSERT INTO checklist_item ("+checklistColumns+", tenant_id) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			item.ID, item.TodoID, item.Title, item.Done, item.Position, item.CreatedAt, item.UpdatedAt, tenantFrom(ctx))
+		if err != nil {
+			return dbError("thêm checklist item thất bại", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// UpdateChecklistItem đổi tên, check/bỏ check hoặc chuyển vị trí của item. Check item cuối cùng
+// của checklist thì todo được chuyển sang done trong cùng transaction (xem checklistCompletes).
+func (s *DbTodoService) UpdateChecklistItem(ctx context.Context, todoID, itemID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	if err := validateChecklistItem(patch, false); err != nil {
+		return nil, err
+	}
+	if patch.Title == nil && patch.Done == nil && patch.Position == nil {
+		return s.GetChecklistItem(ctx, todoID, itemID)
+	}
+	var updated ChecklistItem
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		if err := svc.touchTodo(ctx, todoID); err != nil {
+			return err
+		}
+		current, err := svc.GetChecklistItem(ctx, todoID, itemID)
+		if err != nil {
+			return err
+		}
+
+		q := &sqlWhere{}
+		sets := []string{"updated_at = " + q.arg(time.Now().UTC().Truncate(time.Microsecond))}
+		if patch.Title != nil {
+			sets = append(sets, "title = "+q.arg(*patch.Title))
+		}
+		if patch.Done != nil {
+			sets = append(sets, "done = "+q.arg(*patch.Done))
+		}
+		if patch.Position != nil {
+			n, err := svc.countChecklist(ctx, todoID)
+			if err != nil {
+				return err
+			}
+			to := clampPosition(*patch.Position, n)
+			if err := svc.shiftChecklist(ctx, todoID, current.Position, to); err != nil {
+				return err
+			}
+			sets = append(sets, "position = "+q.arg(to))
+		}
+		q.add("todo_id = ?", todoID)
+		q.add("id = ?", itemID)
+		forTenant(ctx, q, "")
+		err = scanChecklistItem(svc.conn().QueryRow(ctx,
+			"UPDATE checklist_item SET "+strings.Join(sets, ", ")+q.String()+" RETURNING "+checklistColumns, q.args...), &updated)
+		if err != nil {
+			return dbError("cập nhật checklist item thất bại", err)
+		}
+
+		if patch.Done == nil || !*patch.Done || current.Done {
+			return nil
+		}
+		todo, err := svc.GetTodo(ctx, todoID)
+		if err != nil {
+			return err
+		}
+		if !checklistCompletes(svc.workflow, todo) {
+			return nil
+		}
+		// Todo còn blocker chưa done thì item vẫn được check, chỉ todo là chưa done.
+		var blocked *BlockedError
+		if _, err := svc.CompleteTodo(ctx, todoID); err != nil && !errors.As(err, &blocked) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// shiftChecklist dồn các item nằm giữa vị trí from và to khi một item được chuyển từ from sang to.
+func (s *DbTodoService) shiftChecklist(ctx context.Context, todoID string, from, to int) error {
+	var err error
+	switch {
+	case to < from:
+		_, err = s.conn().Exec(ctx,
+			"UPDATE checklist_item SET position = position + 1 WHERE todo_id = $1 AND tenant_id = $2 AND position >= $3 AND position < $4",
+			todoID, tenantFrom(ctx), to, from)
+	case to > from:
+		_, err = s.conn().Exec(ctx,
+			"UPDATE checklist_item SET position = position - 1 WHERE todo_id = $1 AND tenant_id = $2 AND position > $3 AND position <= $4",
+			todoID, tenantFrom(ctx), from, to)
+	}
+	if err != nil {
+		return dbError("dồn vị trí checklist thất bại", err)
+	}
+	return nil
+}
+
+func (s *DbTodoService) DeleteChecklistItem(ctx context.Context, todoID, itemID string) error {
+	return s.inTx(ctx, func(svc *DbTodoService) error {
+		if err := svc.touchTodo(ctx, todoID); err != nil {
+			return err
+		}
+		var position int
+		err := svc.conn().QueryRow(ctx,
+			"DELETE FROM checklist_item WHERE todo_id = $1 AND id = $2 AND tenant_id = $3 RETURNING position", todoID, itemID, tenantFrom(ctx)).
+			Scan(&position)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return checklistItemNotFound(itemID)
+			}
+			return dbError("xóa checklist item thất bại", err)
+		}
+		if _, err := svc.conn().Exec(ctx,
+			"UPDATE checklist_item SET position = position - 1 WHERE todo_id = $1 AND tenant_id = $2 AND position > $3",
+			todoID, tenantFrom(ctx), position); err != nil {
+			return dbError("dồn vị trí checklist thất bại", err)
+		}
+		return nil
+	})
+}
+
+// checklistLocked trả về checklist của một todo đang tồn tại mà người dùng trong ctx được thấy; caller phải giữ s.mu.
+func (s *MemoryTodoService) checklistLocked(ctx context.Context, todoID string) ([]ChecklistItem, error) {
+	if _, ok := s.liveLocked(ctx, todoID); !ok {
+		return nil, todoNotFound(todoID)
+	}
+	return s.checklists[todoID], nil
+}
+
+// setChecklistLocked lưu checklist mới (không sửa slice cũ, để bản chụp của bulk vẫn đúng), đánh lại vị trí,
+// cập nhật tiến độ và tăng version của todo; caller phải giữ s.mu.
+func (s *MemoryTodoService) setChecklistLocked(todoID string, items []ChecklistItem, now time.Time) {
+	progress := ChecklistProgress{Total: len(items)}
+	for i := range items {
+		items[i].Position = i + 1
+		if items[i].Done {
+			progress.Done++
+		}
+	}
+	s.checklists[todoID] = items
+	todo := s.todos[todoID]
+	todo.Checklist = progress
+	todo.Version++
+	todo.UpdatedAt = now
+	s.todos[todoID] = todo
+}
+
+func checklistIndex(items []ChecklistItem, itemID string) int {
+	for i, item := range items {
+		if item.ID == itemID {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryTodoService) ListChecklist(ctx context.Context, todoID string) ([]ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items, err := s.checklistLocked(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	return append([]ChecklistItem{}, items...), nil
+}
+
+func (s *MemoryTodoService) GetChecklistItem(ctx context.Context, todoID, itemID string) (*ChecklistItem, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	items, err := s.checklistLocked(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	i := checklistIndex(items, itemID)
+	if i < 0 {
+		return nil, checklistItemNotFound(itemID)
+	}
+	item := items[i]
+	return &item, nil
+}
+
+func (s *MemoryTodoService) AddChecklistItem(ctx context.Context, todoID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	if err := validateChecklistItem(patch, true); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.checklistLocked(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	if len(current) >= maxChecklistItems {
+		return nil, tooManyChecklistItems()
+	}
+	now := time.Now()
+	item := ChecklistItem{ID: generateNewID(), TodoID: todoID, Title: *patch.Title, CreatedAt: now, UpdatedAt: now}
+	at := len(current)
+	if patch.Position != nil {
+		at = clampPosition(*patch.Position, len(current)+1) - 1
+	}
+	items := make([]ChecklistItem, 0, len(current)+1)
+	items = append(items, current[:at]...)
+	items = append(items, item)
+	items = append(items, current[at:]...)
+	s.setChecklistLocked(todoID, items, now)
+	item = items[at]
+	return &item, nil
+}
+
+func (s *MemoryTodoService) UpdateChecklistItem(ctx context.Context, todoID, itemID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	if err := validateChecklistItem(patch, false); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.checklistLocked(ctx, todoID)
+	if err != nil {
+		return nil, err
+	}
+	i := checklistIndex(current, itemID)
+	if i < 0 {
+		return nil, checklistItemNotFound(itemID)
+	}
+	item := current[i]
+	if patch.Title == nil && patch.Done == nil && patch.Position == nil {
+		return &item, nil
+	}
+
+	now := time.Now()
+	checked := patch.Done != nil && *patch.Done && !item.Done
+	if patch.Title != nil {
+		item.Title = *patch.Title
+	}
+	if patch.Done != nil {
+		item.Done = *patch.Done
+	}
+	item.UpdatedAt = now
+	items := make([]ChecklistItem, 0, len(current))
+	items = append(items, current[:i]...)
+	items = append(items, current[i+1:]...)
+	at := i
+	if patch.Position != nil {
+		at = clampPosition(*patch.Position, len(current)) - 1
+	}
+	items = append(items[:at], append([]ChecklistItem{item}, items[at:]...)...)
+	s.setChecklistLocked(todoID, items, now)
+	item = items[at]
+
+	if todo := s.todos[todoID]; checked && checklistCompletes(s.workflow, &todo) {
+		var blocked *BlockedError
+		if _, err := s.setDoneLocked(ctx, todoID, true); err != nil && !errors.As(err, &blocked) {
+			return nil, err
+		}
+	}
+	return &item, nil
+}
+
+func (s *MemoryTodoService) DeleteChecklistItem(ctx context.Context, todoID, itemID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.checklistLocked(ctx, todoID)
+	if err != nil {
+		return err
+	}
+	i := checklistIndex(current, itemID)
+	if i < 0 {
+		return checklistItemNotFound(itemID)
+	}
+	items := make([]ChecklistItem, 0, len(current)-1)
+	items = append(items, current[:i]...)
+	items = append(items, current[i+1:]...)
+	s.setChecklistLocked(todoID, items, time.Now())
+	return nil
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/cockroachdb"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
+	"net"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -15,6 +21,13 @@ type Db struct {
 	conn *pgxpool.Pool
 }
 
+// querier là phần chung của *pgxpool.Pool và pgx.Tx, để cùng một câu lệnh chạy được trong hoặc ngoài transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
 func NewDb() (*Db, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
@@ -43,6 +56,69 @@ func NewDb() (*Db, error) {
 	return &Db{conn: conn}, nil
 }
 
+// dbError gắn lỗi chuẩn (ErrConflict, ErrValidation, ErrUnavailable) vào lỗi trả về từ pgx
+// để handler có thể phân loại bằng errors.Is mà không cần biết về Postgres.
+func dbError(msg string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == "23505", pgErr.Code == "23503", pgErr.Code == "40001":
+			// unique_violation, foreign_key_violation, serialization_failure
+			return fmt.Errorf("%s: %w: %v", msg, ErrConflict, err)
+		case pgErr.Code == "23502", pgErr.Code == "23514", pgErr.Code == "22001", pgErr.Code == "22P02":
+			// not_null_violation, check_violation, string_data_right_truncation, invalid_text_representation
+			return fmt.Errorf("%s: %w: %v", msg, ErrValidation, err)
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "57P"), pgErr.Code == "53300":
+			// connection_exception, operator_intervention, too_many_connections
+			return fmt.Errorf("%s: %w: %v", msg, ErrUnavailable, err)
+		}
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+
+	var netErr net.Error
+	if pgconn.Timeout(err) || errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) {
+		return fmt.Errorf("%s: %w: %v", msg, ErrUnavailable, err)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// isForeignKeyViolation cho biết câu lệnh ghi tham chiếu tới một dòng không tồn tại, ví dụ project_id của todo.
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+// isUniqueViolation cho biết câu lệnh ghi trùng một giá trị UNIQUE đã có, ví dụ email của người dùng.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// sqlWhere gom các điều kiện WHERE và tham số tương ứng; mỗi "?" trong điều kiện được thay bằng $n.
+type sqlWhere struct {
+	conds []string
+	args  []interface{}
+}
+
+func (w *sqlWhere) arg(v interface{}) string {
+	w.args = append(w.args, v)
+	return "$" + strconv.Itoa(len(w.args))
+}
+
+func (w *sqlWhere) add(cond string, args ...interface{}) {
+	for _, a := range args {
+		cond = strings.Replace(cond, "?", w.arg(a), 1)
+	}
+	w.conds = append(w.conds, cond)
+}
+
+func (w *sqlWhere) String() string {
+	if len(w.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(w.conds, " AND ")
+}
+
 func Migrate() error {
 	connString := fmt.Sprintf("cockroachdb://%s:%s@%s:%s/%s", os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_NAME"))
 	m, err := migrate.New(
@@ -1 +1 @@
-DROP TABLE IF EXISTS entries;
\ No newline at end of file
+DROP TABLE IF EXISTS todo;
@@ -0,0 +1,2 @@
+ALTER TABLE todo DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE todo DROP COLUMN IF EXISTS version;
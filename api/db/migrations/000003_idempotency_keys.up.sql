@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) NOT NULL,
+    scope VARCHAR(255) NOT NULL,
+    fingerprint CHAR(64) NOT NULL,
+    status INT NOT NULL DEFAULT 0,
+    headers JSONB,
+    body BYTEA,
+    created_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (key, scope)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
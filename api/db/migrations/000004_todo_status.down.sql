@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS todo_status_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS status_times;
+ALTER TABLE todo DROP COLUMN IF EXISTS status;
@@ -0,0 +1,3 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS status VARCHAR(64) NOT NULL DEFAULT 'backlog';
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS status_times JSONB NOT NULL DEFAULT '{}';
+CREATE INDEX IF NOT EXISTS todo_status_idx ON todo (status);
@@ -0,0 +1 @@
+UPDATE todo SET status = 'backlog', status_times = '{}';
@@ -0,0 +1,5 @@
+UPDATE todo SET status = 'done', done_at = COALESCE(done_at, updated_at),
+    status_times = jsonb_build_object('backlog', created_at, 'done', COALESCE(done_at, updated_at))
+WHERE done;
+UPDATE todo SET status = 'backlog', status_times = jsonb_build_object('backlog', created_at)
+WHERE NOT done;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS todo_priority_idx;
+DROP INDEX IF EXISTS todo_due_at_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS estimate;
+ALTER TABLE todo DROP COLUMN IF EXISTS priority;
+ALTER TABLE todo DROP COLUMN IF EXISTS due_at;
@@ -0,0 +1,5 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS due_at TIMESTAMP;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS priority SMALLINT NOT NULL DEFAULT 0;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS estimate INT;
+CREATE INDEX IF NOT EXISTS todo_due_at_idx ON todo (due_at);
+CREATE INDEX IF NOT EXISTS todo_priority_idx ON todo (priority);
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS tag (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    color VARCHAR(7) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE TABLE IF NOT EXISTS todo_tag (
+    todo_id VARCHAR(255) NOT NULL REFERENCES todo (id) ON DELETE CASCADE,
+    tag_id VARCHAR(255) NOT NULL REFERENCES tag (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS todo_tag_tag_id_idx ON todo_tag (tag_id);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS project (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO project (id, name) VALUES ('default', 'Default') ON CONFLICT (id) DO NOTHING;
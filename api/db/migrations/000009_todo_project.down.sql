@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS todo_project_id_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS project_id;
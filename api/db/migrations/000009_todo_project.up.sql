@@ -0,0 +1,2 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS project_id VARCHAR(255) NOT NULL DEFAULT 'default' REFERENCES project (id);
+CREATE INDEX IF NOT EXISTS todo_project_id_idx ON todo (project_id);
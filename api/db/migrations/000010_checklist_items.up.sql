@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS checklist_item (
+    id VARCHAR(255) PRIMARY KEY,
+    todo_id VARCHAR(255) NOT NULL REFERENCES todo (id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    done BOOLEAN NOT NULL DEFAULT FALSE,
+    position INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS checklist_item_todo_id_position_idx ON checklist_item (todo_id, position);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS todo_dependency (
+    todo_id VARCHAR(255) NOT NULL REFERENCES todo (id) ON DELETE CASCADE,
+    blocker_id VARCHAR(255) NOT NULL REFERENCES todo (id) ON DELETE CASCADE,
+    PRIMARY KEY (todo_id, blocker_id),
+    CHECK (todo_id <> blocker_id)
+);
+CREATE INDEX IF NOT EXISTS todo_dependency_blocker_id_idx ON todo_dependency (blocker_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS todo_series_occurrence_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS occurrence;
+ALTER TABLE todo DROP COLUMN IF EXISTS series_id;
+ALTER TABLE todo DROP COLUMN IF EXISTS recurrence;
@@ -0,0 +1,6 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS recurrence VARCHAR(255);
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS series_id VARCHAR(255);
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS occurrence INT NOT NULL DEFAULT 0;
+
+-- Mỗi occurrence của một series chỉ được sinh một lần, kể cả khi hai request done cùng lúc.
+CREATE UNIQUE INDEX IF NOT EXISTS todo_series_occurrence_idx ON todo (series_id, occurrence);
@@ -0,0 +1,4 @@
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- Janitor xóa hẳn các todo có deleted_at cũ hơn thời gian lưu giữ.
+CREATE INDEX IF NOT EXISTS todo_deleted_at_idx ON todo (deleted_at);
@@ -0,0 +1,12 @@
+-- Lịch sử chỉ được ghi thêm. Không có khóa ngoại tới todo để lịch sử còn lại sau khi todo bị xóa hẳn.
+CREATE TABLE IF NOT EXISTS todo_history (
+    id VARCHAR(255) PRIMARY KEY,
+    todo_id VARCHAR(255) NOT NULL,
+    action VARCHAR(32) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    changes JSONB NOT NULL,
+    version INT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS todo_history_todo_id_idx ON todo_history (todo_id, created_at);
+CREATE INDEX IF NOT EXISTS todo_history_created_at_idx ON todo_history (created_at DESC, id DESC);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS todo_share;
+DROP INDEX IF EXISTS todo_owner_id_idx;
+ALTER TABLE todo DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS users;
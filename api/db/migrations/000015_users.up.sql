@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Todo có từ trước không có owner và vẫn hiện với mọi người dùng.
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS owner_id VARCHAR(255) REFERENCES users (id);
+CREATE INDEX IF NOT EXISTS todo_owner_id_idx ON todo (owner_id);
+
+CREATE TABLE IF NOT EXISTS todo_share (
+    todo_id VARCHAR(255) NOT NULL REFERENCES todo (id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (todo_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS todo_share_user_id_idx ON todo_share (user_id);
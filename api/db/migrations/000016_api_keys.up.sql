@@ -0,0 +1,13 @@
+-- Chỉ sha256 của token được lưu; prefix là vài ký tự đầu của token để người dùng nhận ra key.
+CREATE TABLE IF NOT EXISTS api_key (
+    id VARCHAR(255) PRIMARY KEY,
+    user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS api_key_user_id_idx ON api_key (user_id);
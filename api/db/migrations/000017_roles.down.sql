@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS project_share;
+ALTER TABLE todo_share DROP COLUMN IF EXISTS role;
@@ -0,0 +1,12 @@
+-- Lần chia sẻ có từ trước cho phép sửa todo như owner, nên được chuyển thành editor.
+ALTER TABLE todo_share ADD COLUMN IF NOT EXISTS role VARCHAR(10) NOT NULL DEFAULT 'editor' CHECK (role IN ('viewer', 'editor', 'owner'));
+
+-- Project chưa chia sẻ với ai mở cho mọi người dùng.
+CREATE TABLE IF NOT EXISTS project_share (
+    project_id VARCHAR(255) NOT NULL REFERENCES project (id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (project_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS project_share_user_id_idx ON project_share (user_id);
@@ -0,0 +1,21 @@
+ALTER TABLE users DROP COLUMN IF EXISTS email;
+ALTER TABLE users RENAME COLUMN untenanted_email TO email;
+ALTER TABLE tag DROP COLUMN IF EXISTS name;
+ALTER TABLE tag RENAME COLUMN untenanted_name TO name;
+
+DROP INDEX IF EXISTS api_key_tenant_id_user_id_idx;
+DROP INDEX IF EXISTS todo_history_tenant_id_created_at_idx;
+DROP INDEX IF EXISTS project_tenant_id_idx;
+DROP INDEX IF EXISTS todo_tenant_id_created_at_idx;
+
+ALTER TABLE api_key DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE project_share DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE todo_share DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE todo_history DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE todo_dependency DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE checklist_item DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE project DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE todo_tag DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE tag DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE todo DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,24 @@
+-- Mọi dòng thuộc về một tenant; dữ liệu có từ trước thuộc tenant mặc định.
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE todo_tag ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE project ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE checklist_item ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE todo_dependency ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE todo_history ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE todo_share ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE project_share ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+ALTER TABLE api_key ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(63) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS todo_tenant_id_created_at_idx ON todo (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS project_tenant_id_idx ON project (tenant_id);
+CREATE INDEX IF NOT EXISTS todo_history_tenant_id_created_at_idx ON todo_history (tenant_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS api_key_tenant_id_user_id_idx ON api_key (tenant_id, user_id);
+
+-- Tên tag và email người dùng chỉ cần duy nhất trong một tenant. Ràng buộc UNIQUE cũ không xóa được bằng cùng một
+-- câu lệnh trên CockroachDB và Postgres, nên cột cũ được đổi tên ở đây, chép sang cột mới ở 000019 và xóa ở 000020.
+ALTER TABLE tag RENAME COLUMN name TO untenanted_name;
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS name VARCHAR(50);
+ALTER TABLE users RENAME COLUMN email TO untenanted_email;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255);
@@ -0,0 +1,2 @@
+UPDATE tag SET untenanted_name = name WHERE untenanted_name IS NULL;
+UPDATE users SET untenanted_email = email WHERE untenanted_email IS NULL;
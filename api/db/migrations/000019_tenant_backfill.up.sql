@@ -0,0 +1,2 @@
+UPDATE tag SET name = untenanted_name WHERE name IS NULL;
+UPDATE users SET email = untenanted_email WHERE email IS NULL;
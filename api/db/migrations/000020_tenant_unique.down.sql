@@ -0,0 +1,6 @@
+-- Chỉ quay lại được khi tên tag và email chưa bị trùng giữa các tenant.
+DROP INDEX IF EXISTS users_tenant_id_email_key;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS untenanted_email VARCHAR(255) UNIQUE;
+
+DROP INDEX IF EXISTS tag_tenant_id_name_key;
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS untenanted_name VARCHAR(50) UNIQUE;
@@ -0,0 +1,8 @@
+-- Xóa cột cũ cũng xóa ràng buộc UNIQUE toàn cục của nó.
+ALTER TABLE tag DROP COLUMN IF EXISTS untenanted_name CASCADE;
+ALTER TABLE tag ALTER COLUMN name SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS tag_tenant_id_name_key ON tag (tenant_id, name);
+
+ALTER TABLE users DROP COLUMN IF EXISTS untenanted_email CASCADE;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_id_email_key ON users (tenant_id, email);
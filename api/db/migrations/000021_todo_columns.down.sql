@@ -0,0 +1,2 @@
+ALTER TABLE todo ALTER COLUMN description DROP DEFAULT;
+ALTER TABLE todo RENAME COLUMN description TO descripsion;
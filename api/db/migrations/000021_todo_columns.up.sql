@@ -0,0 +1,6 @@
+-- 000001 tạo cột descripsion (sai chính tả) và các cột cho phép NULL. Cột được đổi tên và có giá trị mặc định ở đây,
+-- giá trị NULL được thay ở 000022 và cột chuyển sang NOT NULL ở 000023.
+ALTER TABLE todo RENAME COLUMN descripsion TO description;
+ALTER TABLE todo ALTER COLUMN description SET DEFAULT '';
+ALTER TABLE todo ALTER COLUMN done SET DEFAULT FALSE;
+ALTER TABLE todo ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
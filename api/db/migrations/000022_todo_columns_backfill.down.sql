@@ -0,0 +1 @@
+-- Giá trị NULL cũ không phân biệt được với giá trị mặc định, nên không có gì để khôi phục.
@@ -0,0 +1,3 @@
+UPDATE todo SET description = '' WHERE description IS NULL;
+UPDATE todo SET done = FALSE WHERE done IS NULL;
+UPDATE todo SET created_at = COALESCE(updated_at, CURRENT_TIMESTAMP) WHERE created_at IS NULL;
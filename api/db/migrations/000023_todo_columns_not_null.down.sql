@@ -0,0 +1,3 @@
+ALTER TABLE todo ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE todo ALTER COLUMN done DROP NOT NULL;
+ALTER TABLE todo ALTER COLUMN description DROP NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE todo ALTER COLUMN description SET NOT NULL;
+ALTER TABLE todo ALTER COLUMN done SET NOT NULL;
+ALTER TABLE todo ALTER COLUMN created_at SET NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS tag_tenant_id_owner_id_idx;
+ALTER TABLE tag DROP COLUMN IF EXISTS owner_id;
@@ -0,0 +1,3 @@
+-- Tag có từ trước không có owner và vẫn hiện với mọi người dùng trong tenant.
+ALTER TABLE tag ADD COLUMN IF NOT EXISTS owner_id VARCHAR(255) REFERENCES users (id);
+CREATE INDEX IF NOT EXISTS tag_tenant_id_owner_id_idx ON tag (tenant_id, owner_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS project_share;
+DROP TABLE IF EXISTS project;
+ALTER TABLE untenanted_project_share RENAME TO project_share;
+ALTER TABLE untenanted_project RENAME TO project;
+ALTER TABLE todo ADD CONSTRAINT todo_project_id_fkey FOREIGN KEY (project_id) REFERENCES project (id);
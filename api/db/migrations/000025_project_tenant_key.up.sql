@@ -0,0 +1,26 @@
+-- Mỗi tenant có project mặc định riêng, nên project được khóa theo (tenant_id, id). Khóa chính không đổi được bằng
+-- cùng một câu lệnh trên CockroachDB và Postgres, nên bảng cũ được đổi tên, chép sang bảng mới ở 000026 và xóa ở 000027.
+ALTER TABLE project RENAME TO untenanted_project;
+ALTER TABLE project_share RENAME TO untenanted_project_share;
+
+CREATE TABLE IF NOT EXISTS project (
+    tenant_id VARCHAR(63) NOT NULL,
+    id VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT project_tenant_id_id_pkey PRIMARY KEY (tenant_id, id)
+);
+
+CREATE TABLE IF NOT EXISTS project_share (
+    tenant_id VARCHAR(63) NOT NULL,
+    project_id VARCHAR(255) NOT NULL,
+    user_id VARCHAR(255) NOT NULL,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT project_share_tenant_id_project_id_user_id_pkey PRIMARY KEY (tenant_id, project_id, user_id),
+    CONSTRAINT project_share_project_fkey FOREIGN KEY (tenant_id, project_id) REFERENCES project (tenant_id, id) ON DELETE CASCADE,
+    CONSTRAINT project_share_user_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS project_share_tenant_id_user_id_idx ON project_share (tenant_id, user_id);
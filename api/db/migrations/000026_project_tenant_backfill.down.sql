@@ -0,0 +1,9 @@
+-- Project mặc định của các tenant gộp lại thành project mặc định dùng chung của DefaultTenantID.
+INSERT INTO untenanted_project (id, name, description, created_at, updated_at, tenant_id)
+    SELECT id, name, description, created_at, updated_at, tenant_id FROM project
+    WHERE id <> 'default' OR tenant_id = 'default'
+    ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO untenanted_project_share (project_id, user_id, role, created_at, tenant_id)
+    SELECT project_id, user_id, role, created_at, tenant_id FROM project_share
+    ON CONFLICT (project_id, user_id) DO NOTHING;
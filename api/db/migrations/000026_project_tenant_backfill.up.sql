@@ -0,0 +1,15 @@
+INSERT INTO project (tenant_id, id, name, description, created_at, updated_at)
+    SELECT tenant_id, id, name, description, created_at, updated_at FROM untenanted_project
+    ON CONFLICT (tenant_id, id) DO NOTHING;
+
+-- Project mặc định trước đây dùng chung cho mọi tenant; mỗi tenant đã có dữ liệu nhận bản riêng của nó.
+INSERT INTO project (tenant_id, id, name)
+    SELECT tenant_id, 'default', 'Default' FROM todo
+    UNION SELECT tenant_id, 'default', 'Default' FROM tag
+    UNION SELECT tenant_id, 'default', 'Default' FROM users
+    UNION SELECT tenant_id, 'default', 'Default' FROM api_key
+    ON CONFLICT (tenant_id, id) DO NOTHING;
+
+INSERT INTO project_share (tenant_id, project_id, user_id, role, created_at)
+    SELECT tenant_id, project_id, user_id, role, created_at FROM untenanted_project_share
+    ON CONFLICT (tenant_id, project_id, user_id) DO NOTHING;
@@ -0,0 +1,20 @@
+ALTER TABLE todo DROP CONSTRAINT IF EXISTS todo_project_fkey;
+DROP INDEX IF EXISTS todo_tenant_id_project_id_idx;
+
+CREATE TABLE IF NOT EXISTS untenanted_project (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default'
+);
+
+CREATE TABLE IF NOT EXISTS untenanted_project_share (
+    project_id VARCHAR(255) NOT NULL REFERENCES untenanted_project (id) ON DELETE CASCADE,
+    user_id VARCHAR(255) NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    PRIMARY KEY (project_id, user_id)
+);
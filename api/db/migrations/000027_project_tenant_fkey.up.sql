@@ -0,0 +1,6 @@
+-- Xóa bảng cũ cũng xóa khóa ngoại todo.project_id trỏ tới nó; khóa ngoại mới giữ todo trong project của tenant mình.
+DROP TABLE IF EXISTS untenanted_project_share;
+DROP TABLE IF EXISTS untenanted_project CASCADE;
+
+CREATE INDEX IF NOT EXISTS todo_tenant_id_project_id_idx ON todo (tenant_id, project_id);
+ALTER TABLE todo ADD CONSTRAINT todo_project_fkey FOREIGN KEY (tenant_id, project_id) REFERENCES project (tenant_id, id);
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS users;
+ALTER TABLE untenanted_users RENAME TO users;
+CREATE UNIQUE INDEX IF NOT EXISTS users_tenant_id_email_key ON users (tenant_id, email);
+
+ALTER TABLE todo ADD CONSTRAINT todo_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users (id);
+ALTER TABLE tag ADD CONSTRAINT tag_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES users (id);
+ALTER TABLE todo_share ADD CONSTRAINT todo_share_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE project_share ADD CONSTRAINT project_share_user_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
+ALTER TABLE api_key ADD CONSTRAINT api_key_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE;
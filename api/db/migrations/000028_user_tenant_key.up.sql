@@ -0,0 +1,13 @@
+-- Cùng một subject của token có thể là người dùng của nhiều tenant, nên users được khóa theo (tenant_id, id). Như
+-- project ở 000025, bảng cũ được đổi tên, chép sang bảng mới ở 000029 và xóa ở 000030.
+ALTER TABLE users RENAME TO untenanted_users;
+
+CREATE TABLE IF NOT EXISTS users (
+    tenant_id VARCHAR(63) NOT NULL,
+    id VARCHAR(255) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT users_tenant_id_id_pkey PRIMARY KEY (tenant_id, id),
+    CONSTRAINT users_tenant_id_email_unique UNIQUE (tenant_id, email)
+);
@@ -0,0 +1,4 @@
+-- Người dùng có mặt ở nhiều tenant chỉ giữ lại một dòng; chia sẻ và API key của các tenant khác vẫn trỏ tới ID đó.
+INSERT INTO untenanted_users (id, name, email, created_at, tenant_id)
+    SELECT id, name, email, created_at, tenant_id FROM users
+    ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,3 @@
+INSERT INTO users (tenant_id, id, name, email, created_at)
+    SELECT tenant_id, id, name, email, created_at FROM untenanted_users
+    ON CONFLICT (tenant_id, id) DO NOTHING;
@@ -0,0 +1,13 @@
+ALTER TABLE todo DROP CONSTRAINT IF EXISTS todo_owner_fkey;
+ALTER TABLE tag DROP CONSTRAINT IF EXISTS tag_owner_fkey;
+ALTER TABLE todo_share DROP CONSTRAINT IF EXISTS todo_share_user_fkey;
+ALTER TABLE project_share DROP CONSTRAINT IF EXISTS project_share_user_fkey;
+ALTER TABLE api_key DROP CONSTRAINT IF EXISTS api_key_user_fkey;
+
+CREATE TABLE IF NOT EXISTS untenanted_users (
+    id VARCHAR(255) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    tenant_id VARCHAR(63) NOT NULL DEFAULT 'default',
+    email VARCHAR(255) NOT NULL
+);
@@ -0,0 +1,8 @@
+-- Xóa bảng cũ cũng xóa các khóa ngoại owner_id và user_id trỏ tới nó; khóa ngoại mới chỉ nhận người dùng cùng tenant.
+DROP TABLE IF EXISTS untenanted_users CASCADE;
+
+ALTER TABLE todo ADD CONSTRAINT todo_owner_fkey FOREIGN KEY (tenant_id, owner_id) REFERENCES users (tenant_id, id);
+ALTER TABLE tag ADD CONSTRAINT tag_owner_fkey FOREIGN KEY (tenant_id, owner_id) REFERENCES users (tenant_id, id);
+ALTER TABLE todo_share ADD CONSTRAINT todo_share_user_fkey FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE;
+ALTER TABLE project_share ADD CONSTRAINT project_share_user_fkey FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE;
+ALTER TABLE api_key ADD CONSTRAINT api_key_user_fkey FOREIGN KEY (tenant_id, user_id) REFERENCES users (tenant_id, id) ON DELETE CASCADE;
@@ -0,0 +1,382 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrDependencyCycle là lỗi khi một blocker mới tạo thành chu trình (kể cả todo tự chặn chính nó).
+var ErrDependencyCycle = fmt.Errorf("dependency would create a cycle: %w", ErrConflict)
+
+// BlockedError là lỗi khi chuyển sang done một todo vẫn còn blocker chưa done.
+type BlockedError struct {
+	ID       string
+	Blockers []string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("todo %s is blocked by open todos: %s", e.ID, strings.Join(e.Blockers, ", "))
+}
+
+func (e *BlockedError) Unwrap() error {
+	return ErrConflict
+}
+
+func dependencyNotFound(blockerID string) error {
+	return &NotFoundError{Resource: "dependency", ID: blockerID}
+}
+
+func dependencyCycle(todoID, blockerID string) error {
+	return fmt.Errorf("todo %s đã (gián tiếp) chặn todo %s: %w", todoID, blockerID, ErrDependencyCycle)
+}
+
+// topoOrder xếp todos sao cho mỗi todo đứng sau mọi blocker của nó (thuật toán Kahn). Blocker nằm ngoài
+// todos được bỏ qua. Trong các todo cùng sẵn sàng, thứ tự đầu vào được giữ nguyên, nên kết quả ổn định.
+func topoOrder(todos []Todo) []Todo {
+	index := make(map[string]int, len(todos))
+	for i, todo := range todos {
+		index[todo.ID] = i
+	}
+	pending := make([]int, len(todos))
+	dependents := make([][]int, len(todos))
+	for i, todo := range todos {
+		for _, blocker := range todo.BlockedBy {
+			if j, ok := index[blocker]; ok {
+				pending[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	var available []int
+	for i := range todos {
+		if pending[i] == 0 {
+			available = append(available, i)
+		}
+	}
+	ordered := make([]Todo, 0, len(todos))
+	placed := make([]bool, len(todos))
+	for len(available) > 0 {
+		i := available[0]
+		available = available[1:]
+		ordered = append(ordered, todos[i])
+		placed[i] = true
+		for _, j := range dependents[i] {
+			if pending[j]--; pending[j] == 0 {
+				at := sort.SearchInts(available, j)
+				available = append(available[:at], append([]int{j}, available[at:]...)...)
+			}
+		}
+	}
+	// Chu trình không thể xảy ra vì AddBlocker từ chối nó; nếu có thì các todo còn lại giữ thứ tự đầu vào.
+	for i, todo := range todos {
+		if !placed[i] {
+			ordered = append(ordered, todo)
+		}
+	}
+	return ordered
+}
+
+// loadBlockers đọc ID các blocker của nhiều todo bằng một câu truy vấn duy nhất.
+func (s *DbTodoService) loadBlockers(ctx context.Context, todos ...*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(todos))
+	byID := make(map[string]*Todo, len(todos))
+	for _, todo := range todos {
+		todo.BlockedBy = []string{}
+		ids = append(ids, todo.ID)
+		byID[todo.ID] = todo
+	}
+
+	rows, err := s.conn().Query(ctx,
+		"SELECT todo_id, blocker_id FROM todo_dependency WHERE todo_id = ANY($1) AND tenant_id = $2 ORDER BY blocker_id", ids, tenantFrom(ctx))
+	if err != nil {
+		return dbError("đọc blocker của todo thất bại", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoID, blockerID string
+		if err := rows.Scan(&todoID, &blockerID); err != nil {
+			return dbError("scan blocker thất bại", err)
+		}
+		if todo, ok := byID[todoID]; ok {
+			todo.BlockedBy = append(todo.BlockedBy, blockerID)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbError("lỗi sau khi đọc blocker", err)
+	}
+	return nil
+}
+
+// checkBlockers trả về BlockedError nếu todo còn blocker chưa done; gọi trước khi chuyển todo sang done.
+func (s *DbTodoService) checkBlockers(ctx context.Context, id string) error {
+	rows, err := s.conn().Query(ctx,
+		"SELECT d.blocker_id FROM todo_dependency d JOIN todo b ON b.id = d.blocker_id AND b.tenant_id = $2 "+
+			"WHERE d.todo_id = $1 AND d.tenant_id = $2 AND NOT b.done ORDER BY d.blocker_id", id, tenantFrom(ctx))
+	if err != nil {
+		return dbError("đọc blocker của todo thất bại", err)
+	}
+	defer rows.Close()
+	var open []string
+	for rows.Next() {
+		var blockerID string
+		if err := rows.Scan(&blockerID); err != nil {
+			return dbError("scan blocker thất bại", err)
+		}
+		open = append(open, blockerID)
+	}
+	if err := rows.Err(); err != nil {
+		return dbError("lỗi sau khi đọc blocker", err)
+	}
+	if len(open) > 0 {
+		return &BlockedError{ID: id, Blockers: open}
+	}
+	return nil
+}
+
+// AddBlocker ghi "todoID bị chặn bởi blockerID". Cạnh đã tồn tại thì todo được trả về nguyên vẹn.
+// Hai todo được khóa theo thứ tự ID trước khi kiểm tra chu trình, nên hai request thêm A→B và B→A
+// cùng lúc được thực hiện lần lượt; chu trình dài hơn tạo đồng thời bị CockroachDB (SERIALIZABLE) hủy bằng lỗi 409.
+func (s *DbTodoService) AddBlocker(ctx context.Context, todoID, blockerID string) (*Todo, error) {
+	if todoID == blockerID {
+		return nil, dependencyCycle(todoID, blockerID)
+	}
+	var updated *Todo
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		w := &sqlWhere{}
+		w.add("id = ANY(?)", []string{todoID, blockerID})
+		w.add("deleted_at IS NULL")
+		visibleTodos(ctx, w, "")
+		rows, err := svc.conn().Query(ctx, "SELECT id FROM todo"+w.String()+" ORDER BY id FOR UPDATE", w.args...)
+		if err != nil {
+			return dbError("khóa todo thất bại", err)
+		}
+		found := map[string]bool{}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return dbError("scan todo thất bại", err)
+			}
+			found[id] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return dbError("lỗi sau khi khóa todo", err)
+		}
+		for _, id := range []string{todoID, blockerID} {
+			if !found[id] {
+				return todoNotFound(id)
+			}
+		}
+
+		var exists, cycle bool
+		err = svc.conn().QueryRow(ctx,
+			"SELECT EXISTS (SELECT 1 FROM todo_dependency WHERE todo_id = $1 AND blocker_id = $2 AND tenant_id = $3)",
+			todoID, blockerID, tenantFrom(ctx)).Scan(&exists)
+		if err != nil {
+			return dbError("kiểm tra blocker thất bại", err)
+		}
+		if !exists {
+			// Cạnh mới tạo chu trình khi todoID đã nằm trong chuỗi blocker (trực tiếp hoặc gián tiếp) của blockerID.
+			err = svc.conn().QueryRow(ctx,
+				"WITH RECURSIVE upstream (id) AS ("+
+					"SELECT blocker_id FROM todo_dependency WHERE todo_id = $1 AND tenant_id = $3 "+
+					"UNION SELECT d.blocker_id FROM todo_dependency d JOIN upstream u ON d.todo_id = u.id WHERE d.tenant_id = $3"+
+					") SELECT EXISTS (SELECT 1 FROM upstream WHERE id = $2)", blockerID, todoID, tenantFrom(ctx)).Scan(&cycle)
+			if err != nil {
+				return dbError("kiểm tra chu trình thất bại", err)
+			}
+			if cycle {
+				return dependencyCycle(todoID, blockerID)
+			}
+			_, err := svc.conn().Exec(ctx, "INSERT INTO todo_dependency (todo_id, blocker_id, tenant_id) VALUES ($1, $2, $3)",
+				todoID, blockerID, tenantFrom(ctx))
+			if err != nil {
+				return dbError("thêm blocker thất bại", err)
+			}
+			if err := svc.touchTodo(ctx, todoID); err != nil {
+				return err
+			}
+		}
+		updated, err = svc.GetTodo(ctx, todoID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *DbTodoService) RemoveBlocker(ctx context.Context, todoID, blockerID string) error {
+	return s.inTx(ctx, func(svc *DbTodoService) error {
+		tag, err := svc.conn().Exec(ctx, "DELETE FROM todo_dependency WHERE todo_id = $1 AND blocker_id = $2 AND tenant_id = $3",
+			todoID, blockerID, tenantFrom(ctx))
+		if err != nil {
+			return dbError("xóa blocker thất bại", err)
+		}
+		if tag.RowsAffected() == 0 {
+			if _, err := svc.GetTodo(ctx, todoID); err != nil {
+				return err
+			}
+			return dependencyNotFound(blockerID)
+		}
+		return svc.touchTodo(ctx, todoID)
+	})
+}
+
+// touchDependents tăng version của các todo bị chặn bởi id, vì xóa id làm blocked_by của chúng thay đổi.
+func (s *DbTodoService) touchDependents(ctx context.Context, id string) error {
+	_, err := s.conn().Exec(ctx,
+		"UPDATE todo SET version = version + 1, updated_at = $2 WHERE tenant_id = $3 AND id IN (SELECT todo_id FROM todo_dependency WHERE blocker_id = $1 AND tenant_id = $3)",
+		id, time.Now().UTC().Truncate(time.Microsecond), tenantFrom(ctx))
+	if err != nil {
+		return dbError("cập nhật todo bị chặn thất bại", err)
+	}
+	return nil
+}
+
+// OrderProjectTodos trả về mọi todo của project theo thứ tự topo (xem topoOrder), todo tạo trước đứng trước.
+func (s *DbTodoService) OrderProjectTodos(ctx context.Context, projectID string) ([]Todo, error) {
+	if _, err := s.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	w := &sqlWhere{}
+	w.add("project_id = ?", projectID)
+	w.add("deleted_at IS NULL")
+	visibleTodos(ctx, w, "")
+	todos, err := s.queryTodos(ctx, "SELECT "+todoColumns+" FROM todo"+w.String()+" ORDER BY created_at, id", w.args...)
+	if err != nil {
+		return nil, err
+	}
+	return topoOrder(todos), nil
+}
+
+// openBlockersLocked trả về các blocker chưa done của todo; caller phải giữ s.mu.
+func (s *MemoryTodoService) openBlockersLocked(todo Todo) []string {
+	var open []string
+	for _, id := range todo.BlockedBy {
+		if blocker, ok := s.todos[id]; ok && !blocker.Done {
+			open = append(open, id)
+		}
+	}
+	return open
+}
+
+// checkBlockersLocked giống DbTodoService.checkBlockers; caller phải giữ s.mu.
+func (s *MemoryTodoService) checkBlockersLocked(todo Todo) error {
+	if open := s.openBlockersLocked(todo); len(open) > 0 {
+		return &BlockedError{ID: todo.ID, Blockers: open}
+	}
+	return nil
+}
+
+// blocksLocked cho biết from có nằm trong chuỗi blocker (trực tiếp hoặc gián tiếp) của id hay không; caller phải giữ s.mu.
+func (s *MemoryTodoService) blocksLocked(from, id string) bool {
+	seen := map[string]bool{}
+	stack := append([]string{}, s.todos[id].BlockedBy...)
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if next == from {
+			return true
+		}
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		stack = append(stack, s.todos[next].BlockedBy...)
+	}
+	return false
+}
+
+func (s *MemoryTodoService) AddBlocker(ctx context.Context, todoID, blockerID string) (*Todo, error) {
+	if todoID == blockerID {
+		return nil, dependencyCycle(todoID, blockerID)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.liveLocked(ctx, todoID)
+	if !ok {
+		return nil, todoNotFound(todoID)
+	}
+	if _, ok := s.liveLocked(ctx, blockerID); !ok {
+		return nil, todoNotFound(blockerID)
+	}
+	if containsString(todo.BlockedBy, blockerID) {
+		return &todo, nil
+	}
+	if s.blocksLocked(todoID, blockerID) {
+		return nil, dependencyCycle(todoID, blockerID)
+	}
+	blockers := append(append([]string{}, todo.BlockedBy...), blockerID)
+	sort.Strings(blockers)
+	todo.BlockedBy = blockers
+	todo.Version++
+	todo.UpdatedAt = time.Now()
+	s.todos[todoID] = todo
+	return &todo, nil
+}
+
+func (s *MemoryTodoService) RemoveBlocker(ctx context.Context, todoID, blockerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.liveLocked(ctx, todoID)
+	if !ok {
+		return todoNotFound(todoID)
+	}
+	if !containsString(todo.BlockedBy, blockerID) {
+		return dependencyNotFound(blockerID)
+	}
+	s.unblockLocked(todo, blockerID)
+	return nil
+}
+
+// withoutString trả về một slice mới không chứa value.
+func withoutString(values []string, value string) []string {
+	kept := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// unblockLocked gỡ blockerID khỏi blocked_by của todo (tạo slice mới, để bản chụp của bulk vẫn đúng); caller phải giữ s.mu.
+func (s *MemoryTodoService) unblockLocked(todo Todo, blockerID string) {
+	todo.BlockedBy = withoutString(todo.BlockedBy, blockerID)
+	todo.Version++
+	todo.UpdatedAt = time.Now()
+	s.todos[todo.ID] = todo
+}
+
+func (s *MemoryTodoService) OrderProjectTodos(ctx context.Context, projectID string) ([]Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.projects[projectID]; !ok {
+		return nil, projectNotFound(projectID)
+	}
+	todos := []Todo{}
+	for _, todo := range s.todos {
+		if todo.ProjectID == projectID && s.visibleLocked(ctx, todo) {
+			todos = append(todos, todo)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].CreatedAt.Equal(todos[j].CreatedAt) {
+			return todos[i].CreatedAt.Before(todos[j].CreatedAt)
+		}
+		return todos[i].ID < todos[j].ID
+	})
+	return topoOrder(todos), nil
+}
@@ -26,7 +26,7 @@ const docTemplate = `{
     "paths": {
         "/todo": {
             "get": {
-                "description": "Retrieve a list of all Todos",
+                "description": "Retrieve a page of Todos. Next/previous pages are advertised in the Link header.",
                 "produces": [
                     "application/json"
                 ],
@@ -34,6 +34,156 @@ const docTemplate = `{
                     "Todos"
                 ],
                 "summary": "Get all Todos",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, capped at 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the Link header",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by done state",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated (e.g. backlog,in_progress)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC 3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Done at or after (RFC 3339)",
+                        "name": "done_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Done before (RFC 3339)",
+                        "name": "done_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Due at or after (RFC 3339)",
+                        "name": "due_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Due before (RFC 3339)",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by overdue state (due in the past, not done or cancelled)",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority, comma-separated (e.g. high,urgent)",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "low",
+                            "normal",
+                            "high",
+                            "urgent"
+                        ],
+                        "type": "string",
+                        "description": "Priority at or above",
+                        "name": "min_priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by tag names, comma-separated (e.g. frontend,qr)",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "any",
+                            "all"
+                        ],
+                        "type": "string",
+                        "description": "Match any (default) or all of the tags",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true: open Todos without open blockers, false: open Todos waiting on a blocker",
+                        "name": "ready",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive title substring",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "done_at",
+                            "title",
+                            "due_at",
+                            "priority",
+                            "urgency"
+                        ],
+                        "type": "string",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order (default desc, asc for due_at)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched page",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -42,6 +192,27 @@ const docTemplate = `{
                             "items": {
                                 "$ref": "#/definitions/main.Todo"
                             }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Weak ETag of the page"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page not modified"
+                    },
+                    "422": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     }
                 }
@@ -67,8 +238,14 @@ const docTemplate = `{
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.Todo"
+                            "$ref": "#/definitions/main.CreateTodoRequest"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -76,12 +253,36 @@ const docTemplate = `{
                         "description": "Created",
                         "schema": {
                             "$ref": "#/definitions/main.Todo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created Todo"
+                            }
                         }
                     },
                     "400": {
                         "description": "Invalid request body",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Your role on the project does not allow adding Todos",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     }
                 }
@@ -89,7 +290,7 @@ const docTemplate = `{
         },
         "/todo/delete/{id}": {
             "delete": {
-                "description": "Delete a Todo by its ID",
+                "description": "Move a Todo to the trash. It disappears from every other route until it is restored with\nPOST /v1/trash/{id}/restore, and is purged after the retention period.",
                 "tags": [
                     "Todos"
                 ],
@@ -101,6 +302,18 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET; required on /v1 routes",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -113,13 +326,31 @@ const docTemplate = `{
                     "400": {
                         "description": "Invalid ID",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can delete the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     },
                     "404": {
                         "description": "Todo not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     }
                 }
@@ -142,6 +373,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched version",
+                        "name": "If-None-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -149,18 +386,27 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/main.Todo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Current version of the Todo"
+                            }
                         }
                     },
+                    "304": {
+                        "description": "Todo not modified"
+                    },
                     "400": {
                         "description": "Invalid ID",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
                         }
                     },
                     "404": {
                         "description": "Todo not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
                         }
                     }
                 }
@@ -168,14 +414,14 @@ const docTemplate = `{
         },
         "/todo/update-status/{id}": {
             "patch": {
-                "description": "Update the status of a Todo by its ID",
+                "description": "Flip the done state of a Todo. Prefer /complete and /reopen, which are safe to retry.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Todos"
                 ],
-                "summary": "Update Todo Status",
+                "summary": "Toggle Todo Status",
                 "parameters": [
                     {
                         "type": "string",
@@ -183,6 +429,18 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET; required on /v1 routes",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -192,10 +450,34 @@ const docTemplate = `{
                             "$ref": "#/definitions/main.Todo"
                         }
                     },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
                     "404": {
                         "description": "Todo not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     }
                 }
@@ -203,9 +485,11 @@ const docTemplate = `{
         },
         "/todo/update/{id}": {
             "patch": {
-                "description": "Update details of a Todo by its ID",
+                "description": "Update only the given fields of a Todo. Accepts JSON Merge Patch (application/merge-patch+json, RFC 7396;\nplain application/json is treated the same) or JSON Patch (application/json-patch+json, RFC 6902).\nid, created_at, done_at, status_times, updated_at and version are read-only.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
                 ],
                 "produces": [
                     "application/json"
@@ -213,7 +497,7 @@ const docTemplate = `{
                 "tags": [
                     "Todos"
                 ],
-                "summary": "Update a Todo",
+                "summary": "Partially update a Todo",
                 "parameters": [
                     {
                         "type": "string",
@@ -223,13 +507,19 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Updated Todo Information",
-                        "name": "todo",
+                        "description": "Fields to change",
+                        "name": "patch",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/main.Todo"
+                            "$ref": "#/definitions/main.TodoMergePatch"
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET; required on /v1 routes",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -242,42 +532,3817 @@ const docTemplate = `{
                     "400": {
                         "description": "Invalid request body",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Your role on the Todo or the target project does not allow the change",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     },
                     "404": {
                         "description": "Todo not found",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A JSON Patch test operation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
                         }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "main.Todo": {
-            "type": "object",
-            "properties": {
-                "created_at": {
-                    "type": "string"
-                },
-                "desc": {
-                    "type": "string"
-                },
-                "done": {
-                    "type": "boolean"
-                },
-                "done_at": {
-                    "type": "string"
-                },
-                "id": {
-                    "type": "string"
-                },
-                "title": {
-                    "type": "string"
+        },
+        "/v1/activity": {
+            "get": {
+                "description": "History entries of every Todo, newest first. The link to the next page is sent in the Link header (rel=\"next\").",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "History"
+                ],
+                "summary": "List recent activity",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, capped at 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the Link header",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only changes made by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created",
+                            "updated",
+                            "status_changed",
+                            "deleted",
+                            "restored",
+                            "purged"
+                        ],
+                        "type": "string",
+                        "description": "Comma-separated actions",
+                        "name": "action",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.HistoryEntry"
+                            }
+                        },
+                        "headers": {
+                            "Link": {
+                                "type": "string",
+                                "description": "Link to the next page"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
                 }
             }
+        },
+        "/v1/api-keys": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Lists the API keys of the authenticated user, including expired and revoked ones. Tokens are never returned again.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "List your API keys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.APIKey"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates an API key for automation scripts acting as the authenticated user. The token is only included in this response; send it as \"Authorization: Bearer \u003ctoken\u003e\".",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Issue an API key",
+                "parameters": [
+                    {
+                        "description": "API key",
+                        "name": "key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.APIKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.APIKey"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/api-keys/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Revoke an API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "API key revoked",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Not authenticated",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "API key not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/projects": {
+            "get": {
+                "description": "All projects in name order, with their open and done todo counts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "List projects",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Project"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Create a project",
+                "parameters": [
+                    {
+                        "description": "Project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ProjectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Project"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created project"
+                            }
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/projects/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Get a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Project"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the name and description of a project.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Rename a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Project",
+                        "name": "project",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ProjectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Project"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can change the project",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Only empty projects can be deleted; move or delete their todos first. The default project cannot be deleted.",
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Delete a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Project deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can delete the project",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Project still has todos, or is the default project",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/projects/{id}/order": {
+            "get": {
+                "description": "Every Todo of the project (not paginated), each one after all of its blockers. Todos that are ready at the same time keep their creation order; blockers in other projects are ignored.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "List the Todos of a project in dependency order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Todo"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/projects/{id}/shares": {
+            "get": {
+                "description": "A shared project gives each user their role on every Todo of the project. A project nobody shared\nhas no shares and everyone is its owner.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "List who a project is shared with",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.ProjectShare"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The project is not shared with you",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/projects/{id}/shares/{user_id}": {
+            "put": {
+                "description": "The user gets the role on every Todo of the project, and editors can add Todos to it. The role defaults\nto editor; sharing again changes the role. Sharing a project nobody shared yet makes you its owner.\nThe default project cannot be shared.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Share a project with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the user to share with",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role of the user",
+                        "name": "share",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ProjectShare"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can share the project",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or user not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Default project, or the change would leave the project without an owner",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Owners can remove anyone; other users can only remove themselves. The last owner cannot be removed\nwhile the project is shared with other users.",
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "Stop sharing a project with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the user",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Share removed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can remove other users",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Project or share not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The project would be left without an owner",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/projects/{id}/todos": {
+            "get": {
+                "description": "Same as GET /v1/todos restricted to one project; every filter, sort and pagination parameter of that endpoint is accepted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Projects"
+                ],
+                "summary": "List the Todos of a project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Project ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, capped at 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the Link header",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by done state",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "done_at",
+                            "title",
+                            "due_at",
+                            "priority",
+                            "urgency"
+                        ],
+                        "type": "string",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order (default desc, asc for due_at)",
+                        "name": "order",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Todo"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page not modified"
+                    },
+                    "404": {
+                        "description": "Project not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/series/{id}": {
+            "get": {
+                "description": "Every occurrence of the series (not paginated) ordered by occurrence number, done ones included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recurrence"
+                ],
+                "summary": "List the occurrences of a recurring Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Series ID (series_id of any occurrence)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Todo"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Series not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Apply a JSON Merge Patch to every open occurrence of the series (not done, not cancelled) in one step;\ndone and cancelled occurrences are kept as history. status, done and due_at belong to a single occurrence\nand are rejected, edit that Todo instead. recurrence null ends the series after the open occurrences.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recurrence"
+                ],
+                "summary": "Edit a whole recurring series",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Series ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.SeriesPatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Todo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Your role on an open occurrence does not allow the change",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Series not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tags": {
+            "get": {
+                "description": "Tags the caller can see in name order: their own tags, tags without an owner and tags on todos they can see. Counts only include todos the caller can see.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "List tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Tag"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Tag names are trimmed, lower-cased and unique. Tags are also created on the fly when a todo is tagged with a new name.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Create a tag",
+                "parameters": [
+                    {
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Tag"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created tag"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "The caller is not a member of the tenant",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A tag with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/tags/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Get a tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Tag"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Renaming a tag renames it on every todo that carries it and bumps their versions, so the caller needs the editor role on each of those todos.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Rename or recolor a tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Tag",
+                        "name": "tag",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TagRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Tag"
+                        }
+                    },
+                    "403": {
+                        "description": "The editor role on every todo carrying the tag is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A tag with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "The tag is removed from every todo that carries it; their versions are bumped. The caller needs the editor role on each of those todos.",
+                "tags": [
+                    "Tags"
+                ],
+                "summary": "Delete a tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Tag ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Tag deleted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "The editor role on every todo carrying the tag is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Tag not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos": {
+            "get": {
+                "description": "Retrieve a page of Todos. Next/previous pages are advertised in the Link header.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Get all Todos",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, capped at 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the Link header",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by done state",
+                        "name": "done",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status, comma-separated (e.g. backlog,in_progress)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created at or after (RFC 3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Created before (RFC 3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Done at or after (RFC 3339)",
+                        "name": "done_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Done before (RFC 3339)",
+                        "name": "done_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Due at or after (RFC 3339)",
+                        "name": "due_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Due before (RFC 3339)",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Filter by overdue state (due in the past, not done or cancelled)",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by priority, comma-separated (e.g. high,urgent)",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "low",
+                            "normal",
+                            "high",
+                            "urgent"
+                        ],
+                        "type": "string",
+                        "description": "Priority at or above",
+                        "name": "min_priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by tag names, comma-separated (e.g. frontend,qr)",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "any",
+                            "all"
+                        ],
+                        "type": "string",
+                        "description": "Match any (default) or all of the tags",
+                        "name": "tag_match",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "true: open Todos without open blockers, false: open Todos waiting on a blocker",
+                        "name": "ready",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive title substring",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "created_at",
+                            "done_at",
+                            "title",
+                            "due_at",
+                            "priority",
+                            "urgency"
+                        ],
+                        "type": "string",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order (default desc, asc for due_at)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched page",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Todo"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Weak ETag of the page"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page not modified"
+                    },
+                    "422": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "503": {
+                        "description": "Storage unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new Todo with provided information",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Create a new Todo",
+                "parameters": [
+                    {
+                        "description": "Todo Information",
+                        "name": "todo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.CreateTodoRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created Todo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Your role on the project does not allow adding Todos",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/bulk": {
+            "post": {
+                "description": "Create, complete, reopen, update or delete up to 100 todos in one request. The whole batch runs in one transaction.\nWith atomic=true any failing item cancels the batch (other items report 424 rolled_back);\notherwise successful items are kept and the response is 207 when some items failed.\nItems may carry a version that acts as If-Match for that item.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Bulk todo operations",
+                "parameters": [
+                    {
+                        "description": "Action and items",
+                        "name": "batch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.BulkRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Every item succeeded",
+                        "schema": {
+                            "$ref": "#/definitions/main.BulkResponse"
+                        }
+                    },
+                    "207": {
+                        "description": "Some items failed, the others were applied",
+                        "schema": {
+                            "$ref": "#/definitions/main.BulkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/events": {
+            "get": {
+                "description": "Server-Sent Events stream of changes to the Todos visible to the caller. Each event is named after the change (created, updated, status_changed, deleted, restored, purged) and carries a TodoEvent as data. Reconnecting clients send Last-Event-ID to receive the events they missed; when that is no longer possible a \"reset\" event tells them to reload the list. Heartbeat comments are sent while idle.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Stream Todo changes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the last event received",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Same as Last-Event-ID, for clients that cannot set headers",
+                        "name": "last_event_id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Event stream",
+                        "schema": {
+                            "$ref": "#/definitions/main.TodoEvent"
+                        }
+                    },
+                    "500": {
+                        "description": "Streaming not supported",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}": {
+            "get": {
+                "description": "Retrieve details of a Todo by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Get a Todo by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched version",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Current version of the Todo"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Todo not modified"
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace title, desc and status of a Todo by its ID. Older clients may send done instead of status;\ndone=true moves the todo to done and done=false reopens a done todo.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Replace a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Updated Todo Information",
+                        "name": "todo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ReplaceTodoRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET, or *",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Your role on the Todo or the target project does not allow the change",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Status transition not allowed by the workflow",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Move a Todo to the trash. It disappears from every other route until it is restored with\nPOST /v1/trash/{id}/restore, and is purged after the retention period.",
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Delete a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET; required on /v1 routes",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Todo deleted successfully",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can delete the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Update only the given fields of a Todo. Accepts JSON Merge Patch (application/merge-patch+json, RFC 7396;\nplain application/json is treated the same) or JSON Patch (application/json-patch+json, RFC 6902).\nid, created_at, done_at, status_times, updated_at and version are read-only.",
+                "consumes": [
+                    "application/json",
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Partially update a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TodoMergePatch"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET; required on /v1 routes",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Your role on the Todo or the target project does not allow the change",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "A JSON Patch test operation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request body too large",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported patch format",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/blockers/{blocker_id}": {
+            "put": {
+                "description": "The Todo cannot be completed while the blocker is open. Adding an existing dependency returns the Todo unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "Mark a Todo as blocked by another Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the blocked Todo",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the blocking Todo",
+                        "name": "blocker_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Current version of the Todo"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "The dependency would create a cycle",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "Dependencies"
+                ],
+                "summary": "Remove a dependency",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the blocked Todo",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the blocking Todo",
+                        "name": "blocker_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Dependency removed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo or dependency not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/checklist": {
+            "get": {
+                "description": "Items in position order. The Todo itself reports the progress as checklist.done / checklist.total.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "List the checklist of a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.ChecklistItem"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Appends the item, or inserts it at position (1-based) and shifts the following items down. Bumps the version of the Todo.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Add a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Checklist item",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ChecklistItemRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChecklistItem"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created checklist item"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/checklist/{item_id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Get a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checklist item ID",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChecklistItem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo or checklist item not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "The following items move up one position. Bumps the version of the Todo.",
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Remove a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checklist item ID",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Checklist item removed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo or checklist item not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Only the given fields change. Moving an item to another position shifts the items in between.\nChecking the last open item completes the Todo when the workflow allows it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Checklist"
+                ],
+                "summary": "Check, rename or move a checklist item",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Checklist item ID",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fields to change",
+                        "name": "item",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.ChecklistItemPatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.ChecklistItem"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo or checklist item not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/complete": {
+            "post": {
+                "description": "Mark a Todo as done. Completing a todo that is already done changes nothing, so retries are safe.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Complete a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Status transition not allowed by the workflow",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/history": {
+            "get": {
+                "description": "Append-only history of the Todo, oldest first: one entry per create, update, status change, delete, restore\nand purge, with the actor, the time and the changed fields ({\"title\": {\"from\": \"a\", \"to\": \"b\"}}).\nThe history stays readable after the Todo is trashed or purged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "History"
+                ],
+                "summary": "Get the change history of a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.HistoryEntry"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/reopen": {
+            "post": {
+                "description": "Move a done Todo back to the reopen status of the workflow. Reopening a todo that is not done changes nothing,\nso retries are safe.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Reopen a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Status transition not allowed by the workflow",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/shares": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List who a Todo is shared with",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.TodoShare"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/shares/{user_id}": {
+            "put": {
+                "description": "The user gets the given role on the Todo: viewer reads it, editor also changes it and its status,\nowner also deletes, restores, purges and shares it. The role defaults to editor; sharing again changes the role.\nOnly owners can share.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Share a Todo with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the user to share with",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role of the user",
+                        "name": "share",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/main.ShareRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TodoShare"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can share the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo or user not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Owners can remove anyone; other users can only remove themselves.",
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Stop sharing a Todo with a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the user",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Share removed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can remove other users",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo or share not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/status": {
+            "post": {
+                "description": "Move a Todo to another status of the workflow (see GET /v1/workflow). done and done_at follow the status.\nMoving a todo to the status it already has changes nothing, so retries are safe.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Change the status of a Todo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Target status",
+                        "name": "transition",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.TransitionRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Status transition not allowed by the workflow",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Unknown status",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/todos/{id}/toggle": {
+            "post": {
+                "description": "Flip the done state of a Todo. Prefer /complete and /reopen, which are safe to retry.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Toggle Todo Status",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from GET; required on /v1 routes",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Retries with the same key replay the first response instead of repeating the change",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid ID",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Viewers cannot change the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "Todo was modified since it was read",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "If-Match is required",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trash": {
+            "get": {
+                "description": "Deleted Todos, with the same filters, sorting and paging as GET /v1/todos. Sorted by deleted_at (newest first) by default.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "List the trash",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, capped at 200)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from the Link header",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Case-insensitive title substring",
+                        "name": "title",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "deleted_at",
+                            "created_at",
+                            "done_at",
+                            "title",
+                            "due_at",
+                            "priority",
+                            "urgency"
+                        ],
+                        "type": "string",
+                        "description": "Sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Sort order (default desc, asc for due_at)",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a previously fetched page",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.Todo"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Weak ETag of the page"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Page not modified"
+                    },
+                    "422": {
+                        "description": "Invalid query parameters",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Permanently delete every Todo in the trash that you are an owner of. This cannot be undone.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Empty the trash",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.TrashPurgeResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trash/{id}": {
+            "delete": {
+                "description": "Purge a deleted Todo with its tags and checklist. This cannot be undone.",
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Permanently delete a Todo in the trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Todo purged",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can purge the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Todo is not in the trash, delete it first",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/trash/{id}/restore": {
+            "post": {
+                "description": "Bring a deleted Todo back with its tags and checklist. Dependencies on it were removed when it was deleted\nand are not restored. Restoring a Todo that is not in the trash returns it unchanged.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Trash"
+                ],
+                "summary": "Restore a Todo from the trash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Todo ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.Todo"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Current version of the Todo"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Only owners can restore the Todo",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Todo not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users": {
+            "get": {
+                "description": "All users in name order. Send a user ID in the X-User-ID header to act as that user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List users",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/main.User"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Only available to tenant administration, i.e. requests without a user when authentication is disabled. With authentication enabled users are created from their token on first sign-in.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Create a user",
+                "parameters": [
+                    {
+                        "description": "User",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/main.UserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created user"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Only tenant administration can create users",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already registered",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    },
+                    "422": {
+                        "description": "Validation failed",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/users/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get a user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.User"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/main.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/v1/workflow": {
+            "get": {
+                "description": "Statuses a Todo can have and the transitions allowed between them.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Todos"
+                ],
+                "summary": "Describe the workflow",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/main.WorkflowResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "main.APIKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "nightly import"
+                },
+                "prefix": {
+                    "description": "Prefix là vài ký tự đầu của token, giúp nhận ra key trong danh sách.",
+                    "type": "string",
+                    "example": "tdk_3q2-7wEj"
+                },
+                "revoked_at": {
+                    "type": "string"
+                },
+                "tenant_id": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.APIKeyRequest": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "nightly import"
+                }
+            }
+        },
+        "main.BulkItemResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/main.Problem"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "todo": {
+                    "$ref": "#/definitions/main.Todo"
+                }
+            }
+        },
+        "main.BulkRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "done",
+                        "undone",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "atomic": {
+                    "type": "boolean"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                }
+            }
+        },
+        "main.BulkResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "atomic": {
+                    "type": "boolean"
+                },
+                "committed": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.BulkItemResponse"
+                    }
+                },
+                "succeeded": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.ChecklistItem": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "position": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Write migration"
+                },
+                "todo_id": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ChecklistItemPatchRequest": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "boolean"
+                },
+                "position": {
+                    "type": "integer",
+                    "example": 2
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Write migration"
+                }
+            }
+        },
+        "main.ChecklistItemRequest": {
+            "type": "object",
+            "properties": {
+                "position": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Write migration"
+                }
+            }
+        },
+        "main.ChecklistProgress": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "total": {
+                    "type": "integer",
+                    "example": 5
+                }
+            }
+        },
+        "main.CreateTodoRequest": {
+            "type": "object",
+            "properties": {
+                "desc": {
+                    "type": "string",
+                    "example": "todo table"
+                },
+                "due_at": {
+                    "type": "string",
+                    "example": "2026-01-31T17:00:00Z"
+                },
+                "estimate": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "high"
+                },
+                "project_id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "frontend",
+                        "qr"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Write migration"
+                }
+            }
+        },
+        "main.FieldChange": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "object"
+                },
+                "to": {
+                    "type": "object"
+                }
+            }
+        },
+        "main.FieldError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "field": {
+                    "type": "string"
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.HistoryEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "enum": [
+                        "created",
+                        "updated",
+                        "status_changed",
+                        "deleted",
+                        "restored",
+                        "purged"
+                    ],
+                    "example": "status_changed"
+                },
+                "actor": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/main.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "todo_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "main.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.FieldError"
+                    }
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.Project": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "Label printing workstream"
+                },
+                "done_count": {
+                    "type": "integer",
+                    "example": 7
+                },
+                "id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "print"
+                },
+                "open_count": {
+                    "type": "integer",
+                    "example": 4
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ProjectRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Label printing workstream"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "print"
+                }
+            }
+        },
+        "main.ProjectShare": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "project_id": {
+                    "type": "string"
+                },
+                "role": {
+                    "enum": [
+                        "viewer",
+                        "editor",
+                        "owner"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Role"
+                        }
+                    ],
+                    "example": "viewer"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ReplaceTodoRequest": {
+            "type": "object",
+            "properties": {
+                "desc": {
+                    "type": "string",
+                    "example": "todo table"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "due_at": {
+                    "type": "string",
+                    "example": "2026-01-31T17:00:00Z"
+                },
+                "estimate": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "high"
+                },
+                "project_id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Status"
+                        }
+                    ],
+                    "example": "in_progress"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "frontend",
+                        "qr"
+                    ]
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Write migration"
+                }
+            }
+        },
+        "main.Role": {
+            "type": "string",
+            "enum": [
+                "",
+                "viewer",
+                "editor",
+                "owner"
+            ],
+            "x-enum-varnames": [
+                "roleNone",
+                "RoleViewer",
+                "RoleEditor",
+                "RoleOwner"
+            ]
+        },
+        "main.SeriesPatchRequest": {
+            "type": "object",
+            "properties": {
+                "desc": {
+                    "type": "string"
+                },
+                "estimate": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "urgent"
+                },
+                "project_id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "ops"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.ShareRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "enum": [
+                        "viewer",
+                        "editor",
+                        "owner"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Role"
+                        }
+                    ],
+                    "example": "viewer"
+                }
+            }
+        },
+        "main.Status": {
+            "type": "string",
+            "enum": [
+                "backlog",
+                "in_progress",
+                "blocked",
+                "done",
+                "cancelled"
+            ],
+            "x-enum-varnames": [
+                "StatusBacklog",
+                "StatusInProgress",
+                "StatusBlocked",
+                "StatusDone",
+                "StatusCancelled"
+            ]
+        },
+        "main.Tag": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "type": "string",
+                    "example": "#1e90ff"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "frontend"
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "todo_count": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "main.TagRequest": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "type": "string",
+                    "example": "#1e90ff"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "frontend"
+                }
+            }
+        },
+        "main.Todo": {
+            "type": "object",
+            "properties": {
+                "blocked_by": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "checklist": {
+                    "$ref": "#/definitions/main.ChecklistProgress"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "desc": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "done_at": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "estimate": {
+                    "type": "integer",
+                    "example": 90
+                },
+                "id": {
+                    "type": "string"
+                },
+                "occurrence": {
+                    "type": "integer",
+                    "example": 1
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "high"
+                },
+                "project_id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "series_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Status"
+                        }
+                    ],
+                    "example": "in_progress"
+                },
+                "status_times": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "frontend",
+                        "qr"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "main.TodoEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string",
+                    "example": "alice"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/main.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "lq3v8k2a-42"
+                },
+                "todo": {
+                    "$ref": "#/definitions/main.Todo"
+                },
+                "todo_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "created",
+                        "updated",
+                        "status_changed",
+                        "deleted",
+                        "restored",
+                        "purged"
+                    ],
+                    "example": "status_changed"
+                },
+                "version": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "main.TodoMergePatch": {
+            "type": "object",
+            "properties": {
+                "desc": {
+                    "type": "string"
+                },
+                "done": {
+                    "type": "boolean"
+                },
+                "due_at": {
+                    "type": "string",
+                    "example": "2026-01-31T17:00:00Z"
+                },
+                "estimate": {
+                    "type": "integer",
+                    "example": 30
+                },
+                "priority": {
+                    "type": "string",
+                    "enum": [
+                        "low",
+                        "normal",
+                        "high",
+                        "urgent"
+                    ],
+                    "example": "urgent"
+                },
+                "project_id": {
+                    "type": "string",
+                    "example": "default"
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "blocked"
+                },
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "frontend",
+                        "qr"
+                    ]
+                },
+                "title": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.TodoShare": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "role": {
+                    "enum": [
+                        "viewer",
+                        "editor",
+                        "owner"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Role"
+                        }
+                    ],
+                    "example": "editor"
+                },
+                "todo_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "main.TransitionRequest": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Status"
+                        }
+                    ],
+                    "example": "in_progress"
+                }
+            }
+        },
+        "main.TrashPurgeResult": {
+            "type": "object",
+            "properties": {
+                "purged": {
+                    "type": "integer",
+                    "example": 3
+                }
+            }
+        },
+        "main.User": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "alice@example.com"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Alice"
+                }
+            }
+        },
+        "main.UserRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "alice@example.com"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "Alice"
+                }
+            }
+        },
+        "main.WorkflowResponse": {
+            "type": "object",
+            "properties": {
+                "initial": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Status"
+                        }
+                    ],
+                    "example": "backlog"
+                },
+                "reopen": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/main.Status"
+                        }
+                    ],
+                    "example": "backlog"
+                },
+                "statuses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/main.Status"
+                    }
+                },
+                "transitions": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "$ref": "#/definitions/main.Status"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "JWT (HS256 or RS256) or API key, sent as \"Bearer \u003ctoken\u003e\".",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     }
 }`
@@ -286,7 +4351,7 @@ const docTemplate = `{
 var SwaggerInfo = &swag.Spec{
 	Version:          "1.0",
 	Host:             "localhost:8080",
-	BasePath:         "/todo",
+	BasePath:         "/",
 	Schemes:          []string{},
 	Title:            "Todo API",
 	Description:      "This is a sample API for managing todos.",
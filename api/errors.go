@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Các lỗi chuẩn mà mọi TodoService trả về. Handler chỉ so sánh bằng errors.Is/errors.As,
+// không bao giờ so sánh chuỗi err.Error().
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrTodoNotFound       = errors.New("todo not found")
+	ErrValidation         = errors.New("validation failed")
+	ErrConflict           = errors.New("conflict")
+	ErrUnavailable        = errors.New("service unavailable")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrInvalidBody        = errors.New("invalid request body")
+	ErrPayloadTooLarge    = errors.New("request body too large")
+	ErrRolledBack         = errors.New("rolled back")
+)
+
+// NotFoundError cho biết tài nguyên (todo, ...) với ID tương ứng không tồn tại.
+type NotFoundError struct {
+	Resource string
+	ID       string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Resource, e.ID)
+}
+
+func (e *NotFoundError) Is(target error) bool {
+	return target == ErrNotFound || (target == ErrTodoNotFound && e.Resource == "todo")
+}
+
+func todoNotFound(id string) error {
+	return &NotFoundError{Resource: "todo", ID: id}
+}
+
+// FieldError mô tả một vi phạm trên một trường của payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError gom tất cả vi phạm của một request để trả về cùng lúc.
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// Add ghi nhận thêm một vi phạm.
+func (e *ValidationError) Add(field, code, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Err trả về nil nếu không có vi phạm nào, để dùng ở cuối hàm validate.
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
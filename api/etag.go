@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type expectedVersionKey struct{}
+
+// withExpectedVersion gắn version mà client mong đợi (từ If-Match) vào context.
+// Các thao tác ghi của TodoService trả về ErrPreconditionFailed nếu version hiện tại khác.
+func withExpectedVersion(ctx context.Context, version int) context.Context {
+	return context.WithValue(ctx, expectedVersionKey{}, version)
+}
+
+// expectedVersion trả về version mong đợi, 0 nghĩa là không kiểm tra.
+func expectedVersion(ctx context.Context) int {
+	version, _ := ctx.Value(expectedVersionKey{}).(int)
+	return version
+}
+
+// checkVersion so sánh version hiện tại với version mong đợi trong context.
+func checkVersion(ctx context.Context, current int) error {
+	if want := expectedVersion(ctx); want != 0 && want != current {
+		return fmt.Errorf("%w: version is %d, If-Match expects %d", ErrPreconditionFailed, current, want)
+	}
+	return nil
+}
+
+// ETag trả về strong ETag của todo, dựa trên cột version.
+func (t Todo) ETag() string {
+	return `"` + strconv.Itoa(t.Version) + `"`
+}
+
+// parseETagVersion đọc version từ một ETag do API này phát hành.
+func parseETagVersion(etag string) (int, bool) {
+	etag = strings.TrimSpace(etag)
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, false
+	}
+	version, err := strconv.Atoi(etag[1 : len(etag)-1])
+	if err != nil || version <= 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// etagMatches so sánh yếu (weak comparison, RFC 9110) một ETag với header If-None-Match.
+func etagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// listETag là weak ETag của một trang danh sách, thay đổi khi bất kỳ todo nào trong trang thay đổi.
+func listETag(page *TodoPage) string {
+	h := sha1.New()
+	for _, todo := range page.Items {
+		fmt.Fprintf(h, "%s:%d;", todo.ID, todo.Version)
+	}
+	fmt.Fprintf(h, "%s|%s", page.PrevCursor, page.NextCursor)
+	return `W/"` + hex.EncodeToString(h.Sum(nil)) + `"`
+}
+
+// applyIfMatch đọc header If-Match và gắn version mong đợi vào context.
+// "*" chỉ yêu cầu todo tồn tại; một ETag không do API phát hành không bao giờ khớp.
+func applyIfMatch(ctx context.Context, w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return ctx, true
+	}
+	version, ok := parseETagVersion(header)
+	if !ok {
+		writeProblem(w, r, http.StatusPreconditionFailed, CodePreconditionFailed, "If-Match does not match the current version of the todo")
+		return ctx, false
+	}
+	return withExpectedVersion(ctx, version), true
+}
+
+// requireIfMatch bắt buộc client gửi If-Match cho các thao tác ghi trên /v1, tránh ghi đè lẫn nhau.
+func requireIfMatch(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-Match") == "" {
+			writeProblem(w, r, http.StatusPreconditionRequired, CodePreconditionRequired, "send If-Match with the ETag from GET, or * to skip the version check")
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultEventBufferSize là số sự kiện gần nhất được giữ lại để client nối lại bằng Last-Event-ID.
+	defaultEventBufferSize = 1024
+	// defaultEventHeartbeat là khoảng cách giữa các comment heartbeat, giữ kết nối qua proxy không bị đóng vì im lặng.
+	defaultEventHeartbeat = 15 * time.Second
+	// eventSubscriberBuffer là số sự kiện chờ gửi tối đa của một client; client chậm hơn bị ngắt và phải nối lại.
+	eventSubscriberBuffer = 64
+	// eventReset là sự kiện báo client không thể nối lại từ Last-Event-ID và phải tải lại danh sách todo.
+	eventReset = "reset"
+)
+
+// TodoEvent là một thay đổi của todo được đẩy qua GET /v1/todos/events. Type giống Action của bản ghi lịch sử tương ứng.
+// Todo là trạng thái sau thay đổi, bỏ trống với deleted và purged.
+type TodoEvent struct {
+	ID        string                 `json:"id" example:"lq3v8k2a-42"`
+	Type      HistoryAction          `json:"type" swaggertype:"string" enums:"created,updated,status_changed,deleted,restored,purged" example:"status_changed"`
+	TodoID    string                 `json:"todo_id"`
+	Actor     string                 `json:"actor" example:"alice"`
+	Version   int                    `json:"version" example:"3"`
+	Changes   map[string]FieldChange `json:"changes"`
+	Todo      *Todo                  `json:"todo,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+
+	tenantID string
+	ownerID  string
+	seq      uint64
+}
+
+// newTodoEvent tạo sự kiện cho bản ghi lịch sử entry của thay đổi từ before sang after.
+func newTodoEvent(ctx context.Context, entry HistoryEntry, before, after *Todo) TodoEvent {
+	event := TodoEvent{
+		Type:      entry.Action,
+		TodoID:    entry.TodoID,
+		Actor:     entry.Actor,
+		Version:   entry.Version,
+		Changes:   entry.Changes,
+		CreatedAt: entry.CreatedAt,
+		tenantID:  tenantFrom(ctx),
+	}
+	if after != nil {
+		event.ownerID = after.OwnerID
+		if after.DeletedAt == nil {
+			todo := *after
+			event.Todo = &todo
+		}
+	} else {
+		event.ownerID = before.OwnerID
+	}
+	return event
+}
+
+// eventBatch gom các sự kiện của một lần gọi TodoService (xem EventTodoService). Backend ghi sự kiện cùng lúc với
+// lịch sử và cắt bỏ các sự kiện của thay đổi bị rollback, nên sau khi lần gọi kết thúc batch chỉ còn các thay đổi
+// đã được lưu.
+type eventBatch struct {
+	events []TodoEvent
+}
+
+type eventBatchKey struct{}
+
+// withEventBatch gắn một eventBatch mới vào ctx.
+func withEventBatch(ctx context.Context) (context.Context, *eventBatch) {
+	batch := &eventBatch{}
+	return context.WithValue(ctx, eventBatchKey{}, batch), batch
+}
+
+// eventsFrom trả về eventBatch của ctx, nil khi không có ai nhận sự kiện.
+func eventsFrom(ctx context.Context) *eventBatch {
+	batch, _ := ctx.Value(eventBatchKey{}).(*eventBatch)
+	return batch
+}
+
+func (b *eventBatch) add(event TodoEvent) {
+	if b != nil {
+		b.events = append(b.events, event)
+	}
+}
+
+// mark trả về vị trí hiện tại để truncate cắt về khi phần thay đổi sau đó bị rollback.
+func (b *eventBatch) mark() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.events)
+}
+
+func (b *eventBatch) truncate(n int) {
+	if b != nil && n < len(b.events) {
+		b.events = b.events[:n]
+	}
+}
+
+// EventBroker phát sự kiện tới các client đang nghe và giữ defaultEventBufferSize sự kiện gần nhất cho việc nối lại.
+// ID của sự kiện có dạng "<epoch>-<seq>"; epoch đổi mỗi lần khởi động nên ID của tiến trình cũ không bị nhầm là còn
+// trong buffer. Broker chỉ thấy thay đổi của tiến trình này.
+type EventBroker struct {
+	mu     sync.Mutex
+	epoch  string
+	seq    uint64
+	size   int
+	buffer []TodoEvent
+	subs   map[*eventSubscriber]struct{}
+}
+
+// eventSubscriber là một client đang nghe. events bị đóng khi client không đọc kịp.
+type eventSubscriber struct {
+	events chan TodoEvent
+}
+
+func NewEventBroker(size int) *EventBroker {
+	if size <= 0 {
+		size = defaultEventBufferSize
+	}
+	return &EventBroker{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		size:  size,
+		subs:  map[*eventSubscriber]struct{}{},
+	}
+}
+
+// Publish gán ID cho các sự kiện, lưu vào buffer và gửi tới mọi client đang nghe.
+func (b *EventBroker) Publish(events ...TodoEvent) {
+	if len(events) == 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, event := range events {
+		b.seq++
+		event.seq = b.seq
+		event.ID = b.epoch + "-" + strconv.FormatUint(b.seq, 10)
+		if len(b.buffer) == b.size {
+			b.buffer = append(b.buffer[:0], b.buffer[1:]...)
+		}
+		b.buffer = append(b.buffer, event)
+		for sub := range b.subs {
+			select {
+			case sub.events <- event:
+			default:
+				// Client không đọc kịp bị ngắt thay vì làm chậm người ghi; nó nối lại bằng Last-Event-ID.
+				close(sub.events)
+				delete(b.subs, sub)
+			}
+		}
+	}
+}
+
+// Subscribe đăng ký một client mới. Với lastEventID khác rỗng, replay là các sự kiện sau lastEventID còn trong
+// buffer; resumed là false khi không nối lại được (ID của tiến trình khác, ID không hợp lệ hoặc đã rơi khỏi buffer).
+func (b *EventBroker) Subscribe(lastEventID string) (sub *eventSubscriber, replay []TodoEvent, resumed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &eventSubscriber{events: make(chan TodoEvent, eventSubscriberBuffer)}
+	b.subs[sub] = struct{}{}
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+	seq, ok := b.parseEventID(lastEventID)
+	if !ok || seq > b.seq {
+		return sub, nil, false
+	}
+	oldest := b.seq + 1
+	if len(b.buffer) > 0 {
+		oldest = b.buffer[0].seq
+	}
+	if seq+1 < oldest {
+		return sub, nil, false
+	}
+	for _, event := range b.buffer {
+		if event.seq > seq {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, true
+}
+
+func (b *EventBroker) Unsubscribe(sub *eventSubscriber) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[sub]; ok {
+		close(sub.events)
+		delete(b.subs, sub)
+	}
+}
+
+func (b *EventBroker) parseEventID(id string) (uint64, bool) {
+	epoch, seq, ok := strings.Cut(id, "-")
+	if !ok || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	return n, err == nil
+}
+
+// EventTodoService phát sự kiện cho các thay đổi được ghi vào lịch sử bởi các thao tác ghi của TodoService bên trong,
+// sau khi thay đổi đã được lưu. Các thao tác đọc được chuyển thẳng xuống service bên trong.
+type EventTodoService struct {
+	TodoService
+	broker *EventBroker
+}
+
+func NewEventTodoService(svc TodoService, broker *EventBroker) *EventTodoService {
+	return &EventTodoService{TodoService: svc, broker: broker}
+}
+
+// publish chạy fn với một eventBatch trong ctx rồi phát các sự kiện còn lại trong batch, kể cả khi fn lỗi: phần
+// thay đổi đã lưu trước lỗi (bulk không atomic, dọn thùng rác nhiều tenant) vẫn cần được báo.
+func (s *EventTodoService) publish(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, batch := withEventBatch(ctx)
+	err := fn(ctx)
+	s.broker.Publish(batch.events...)
+	return err
+}
+
+func (s *EventTodoService) writeTodo(ctx context.Context, fn func(ctx context.Context) (*Todo, error)) (todo *Todo, err error) {
+	err = s.publish(ctx, func(ctx context.Context) error {
+		todo, err = fn(ctx)
+		return err
+	})
+	return todo, err
+}
+
+func (s *EventTodoService) writeBulk(ctx context.Context, fn func(ctx context.Context) ([]BulkResult, error)) (results []BulkResult, err error) {
+	err = s.publish(ctx, func(ctx context.Context) error {
+		results, err = fn(ctx)
+		return err
+	})
+	return results, err
+}
+
+func (s *EventTodoService) CreateTodo(ctx context.Context, todo Todo) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.CreateTodo(ctx, todo) })
+}
+
+func (s *EventTodoService) UpdateTodo(ctx context.Context, id string, todo Todo) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.UpdateTodo(ctx, id, todo) })
+}
+
+func (s *EventTodoService) PatchTodo(ctx context.Context, id string, patch TodoPatch) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.PatchTodo(ctx, id, patch) })
+}
+
+func (s *EventTodoService) DeleteTodo(ctx context.Context, id string) error {
+	return s.publish(ctx, func(ctx context.Context) error { return s.TodoService.DeleteTodo(ctx, id) })
+}
+
+func (s *EventTodoService) UpdateTodoStatus(ctx context.Context, id string) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.UpdateTodoStatus(ctx, id) })
+}
+
+func (s *EventTodoService) CompleteTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.CompleteTodo(ctx, id) })
+}
+
+func (s *EventTodoService) ReopenTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.ReopenTodo(ctx, id) })
+}
+
+func (s *EventTodoService) TransitionTodo(ctx context.Context, id string, status Status) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.TransitionTodo(ctx, id, status) })
+}
+
+func (s *EventTodoService) RestoreTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.writeTodo(ctx, func(ctx context.Context) (*Todo, error) { return s.TodoService.RestoreTodo(ctx, id) })
+}
+
+func (s *EventTodoService) PurgeTodo(ctx context.Context, id string) error {
+	return s.publish(ctx, func(ctx context.Context) error { return s.TodoService.PurgeTodo(ctx, id) })
+}
+
+func (s *EventTodoService) PurgeTrash(ctx context.Context, before time.Time) (purged int, err error) {
+	err = s.publish(ctx, func(ctx context.Context) error {
+		purged, err = s.TodoService.PurgeTrash(ctx, before)
+		return err
+	})
+	return purged, err
+}
+
+// Check item cuối cùng của checklist có thể hoàn thành todo (xem checklistCompletes).
+func (s *EventTodoService) UpdateChecklistItem(ctx context.Context, todoID, itemID string, patch ChecklistItemPatch) (item *ChecklistItem, err error) {
+	err = s.publish(ctx, func(ctx context.Context) error {
+		item, err = s.TodoService.UpdateChecklistItem(ctx, todoID, itemID, patch)
+		return err
+	})
+	return item, err
+}
+
+func (s *EventTodoService) PatchSeries(ctx context.Context, seriesID string, patch TodoPatch) (todos []Todo, err error) {
+	err = s.publish(ctx, func(ctx context.Context) error {
+		todos, err = s.TodoService.PatchSeries(ctx, seriesID, patch)
+		return err
+	})
+	return todos, err
+}
+
+func (s *EventTodoService) BulkCreateTodos(ctx context.Context, todos []Todo, opts BulkOptions) ([]BulkResult, error) {
+	return s.writeBulk(ctx, func(ctx context.Context) ([]BulkResult, error) {
+		return s.TodoService.BulkCreateTodos(ctx, todos, opts)
+	})
+}
+
+func (s *EventTodoService) BulkSetDone(ctx context.Context, items []BulkItem, done bool, opts BulkOptions) ([]BulkResult, error) {
+	return s.writeBulk(ctx, func(ctx context.Context) ([]BulkResult, error) {
+		return s.TodoService.BulkSetDone(ctx, items, done, opts)
+	})
+}
+
+func (s *EventTodoService) BulkPatchTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	return s.writeBulk(ctx, func(ctx context.Context) ([]BulkResult, error) {
+		return s.TodoService.BulkPatchTodos(ctx, items, opts)
+	})
+}
+
+func (s *EventTodoService) BulkDeleteTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	return s.writeBulk(ctx, func(ctx context.Context) ([]BulkResult, error) {
+		return s.TodoService.BulkDeleteTodos(ctx, items, opts)
+	})
+}
+
+// eventVisible cho biết người dùng trong ctx được nhận event: cùng tenant và được thấy todo (xem TodoRole). Todo đã
+// bị xóa hẳn không còn để kiểm tra chia sẻ, nên sự kiện purged chỉ tới owner của nó.
+func (h *APIHandler) eventVisible(ctx context.Context, event TodoEvent) bool {
+	if event.tenantID != tenantFrom(ctx) {
+		return false
+	}
+	userID := userFrom(ctx)
+	if userID == "" || event.ownerID == "" || event.ownerID == userID {
+		return true
+	}
+	if event.Type == HistoryPurged {
+		return false
+	}
+	_, err := h.todoService.TodoRole(ctx, event.TodoID)
+	return err == nil
+}
+
+// @Summary Stream Todo changes
+// @Description Server-Sent Events stream of changes to the Todos visible to the caller. Each event is named after the change (created, updated, status_changed, deleted, restored, purged) and carries a TodoEvent as data. Reconnecting clients send Last-Event-ID to receive the events they missed; when that is no longer possible a "reset" event tells them to reload the list. Heartbeat comments are sent while idle.
+// @Tags Todos
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Param last_event_id query string false "Same as Last-Event-ID, for clients that cannot set headers"
+// @Success 200 {object} TodoEvent "Event stream"
+// @Failure 500 {object} Problem "Streaming not supported"
+// @Router /v1/todos/events [get]
+func (h *APIHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, fmt.Errorf("response writer không hỗ trợ flush"))
+		return
+	}
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	sub, replay, resumed := h.events.Subscribe(lastEventID)
+	defer h.events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ctx := r.Context()
+	if !resumed {
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: {}\n\n", eventReset); err != nil {
+			return
+		}
+	}
+	for _, event := range replay {
+		if err := h.writeEvent(ctx, w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.eventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			if err := h.writeEvent(ctx, w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent ghi event theo định dạng SSE nếu người dùng trong ctx được thấy nó.
+func (h *APIHandler) writeEvent(ctx context.Context, w http.ResponseWriter, event TodoEvent) error {
+	if !h.eventVisible(ctx, event) {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Error encoding event:", err)
+		return nil
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventBroker(t *testing.T) {
+	broker := NewEventBroker(2)
+	first, _, _ := broker.Subscribe("")
+	for _, id := range []string{"a", "b", "c"} {
+		broker.Publish(TodoEvent{Type: HistoryCreated, TodoID: id})
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		event := <-first.events
+		ids = append(ids, event.ID)
+		assert.Equal(t, broker.epoch, strings.Split(event.ID, "-")[0])
+	}
+	assert.Len(t, broker.buffer, 2)
+
+	_, replay, resumed := broker.Subscribe(ids[0])
+	assert.True(t, resumed)
+	require.Len(t, replay, 2)
+	assert.Equal(t, "b", replay[0].TodoID)
+	assert.Equal(t, ids[1], replay[0].ID)
+
+	_, replay, resumed = broker.Subscribe(ids[2])
+	assert.True(t, resumed)
+	assert.Empty(t, replay)
+
+	broker.Publish(TodoEvent{Type: HistoryCreated, TodoID: "d"})
+	for _, id := range []string{ids[0], "0-1", "garbage", broker.epoch + "-99"} {
+		_, replay, resumed = broker.Subscribe(id)
+		assert.False(t, resumed, id)
+		assert.Empty(t, replay, id)
+	}
+
+	// Client không đọc kịp bị ngắt để nối lại sau.
+	slow, _, _ := broker.Subscribe("")
+	for i := 0; i <= eventSubscriberBuffer; i++ {
+		broker.Publish(TodoEvent{Type: HistoryUpdated, TodoID: "d"})
+	}
+	for range slow.events {
+	}
+	_, ok := broker.subs[slow]
+	assert.False(t, ok)
+	broker.Unsubscribe(slow)
+}
+
+func TestEventTodoService(t *testing.T) {
+	broker := NewEventBroker(0)
+	svc := NewEventTodoService(NewTenantTodoService(func() TodoService { return NewMemoryTodoService() }), broker)
+	ctx := context.Background()
+	sub, _, _ := broker.Subscribe("")
+	received := func() []HistoryAction {
+		var types []HistoryAction
+		for {
+			select {
+			case event := <-sub.events:
+				types = append(types, event.Type)
+			default:
+				return types
+			}
+		}
+	}
+
+	todo, err := svc.CreateTodo(ctx, Todo{Title: "print labels"})
+	require.NoError(t, err)
+	event := <-sub.events
+	assert.Equal(t, HistoryCreated, event.Type)
+	assert.Equal(t, todo.ID, event.TodoID)
+	assert.Equal(t, DefaultTenantID, event.tenantID)
+	require.NotNil(t, event.Todo)
+	assert.Equal(t, "print labels", event.Todo.Title)
+
+	title := "print QR labels"
+	_, err = svc.PatchTodo(ctx, todo.ID, TodoPatch{Title: &title})
+	require.NoError(t, err)
+	_, err = svc.TransitionTodo(ctx, todo.ID, StatusInProgress)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteTodo(ctx, todo.ID))
+	_, err = svc.RestoreTodo(ctx, todo.ID)
+	require.NoError(t, err)
+	require.NoError(t, svc.DeleteTodo(ctx, todo.ID))
+	require.NoError(t, svc.PurgeTodo(ctx, todo.ID))
+	assert.Equal(t, []HistoryAction{HistoryUpdated, HistoryStatusChanged, HistoryDeleted, HistoryRestored, HistoryDeleted, HistoryPurged}, received())
+
+	// Thao tác lỗi và batch atomic bị rollback không phát gì.
+	_, err = svc.PatchTodo(ctx, todo.ID, TodoPatch{Title: &title})
+	assert.Error(t, err)
+	results, err := svc.BulkCreateTodos(ctx, []Todo{{Title: "a"}, {Title: ""}}, BulkOptions{Atomic: true})
+	require.NoError(t, err)
+	assert.Error(t, results[1].Err)
+	assert.Empty(t, received())
+
+	results, err = svc.BulkCreateTodos(ctx, []Todo{{Title: "a"}, {Title: ""}}, BulkOptions{})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, []HistoryAction{HistoryCreated}, received())
+}
+
+// sseFrame đọc một frame của stream SSE: các dòng đến dòng trống kế tiếp.
+func sseFrame(t *testing.T, r *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestRouter_Events(t *testing.T) {
+	handler, _ := newMemoryHandler(t)
+	handler.events = NewEventBroker(0)
+	handler.todoService = NewEventTodoService(handler.todoService, handler.events)
+	handler.eventHeartbeat = 50 * time.Millisecond
+	server := httptest.NewServer(newRouter(handler))
+	defer server.Close()
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	send := func(method, path, body, user string) *http.Response {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("If-Match", "*")
+		if user != "" {
+			req.Header.Set(userHeader, user)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+	decode := func(resp *http.Response, v interface{}) {
+		defer resp.Body.Close()
+		require.Less(t, resp.StatusCode, 300)
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	var alice, bob User
+	decode(send(http.MethodPost, "/v1/users", `{"name":"Alice","email":"alice@example.com"}`, ""), &alice)
+	decode(send(http.MethodPost, "/v1/users", `{"name":"Bob","email":"bob@example.com"}`, ""), &bob)
+
+	stream := func(path, lastEventID string) (*http.Response, *bufio.Reader) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		require.NoError(t, err)
+		req.Header.Set(userHeader, alice.ID)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		return resp, bufio.NewReader(resp.Body)
+	}
+	var private, shared, own Todo
+	var share TodoShare
+	decode(send(http.MethodPost, "/v1/todos", `{"title":"shared"}`, bob.ID), &shared)
+	decode(send(http.MethodPut, "/v1/todos/"+shared.ID+"/shares/"+alice.ID, `{"role":"viewer"}`, bob.ID), &share)
+	resp, events := stream("/v1/todos/events", "")
+
+	// Alice chỉ nhận sự kiện của todo mình được thấy, không có todo riêng của Bob.
+	decode(send(http.MethodPost, "/v1/todos", `{"title":"bob only"}`, bob.ID), &private)
+	decode(send(http.MethodPost, "/v1/todos", `{"title":"alice"}`, alice.ID), &own)
+	resp2 := send(http.MethodPost, "/v1/todos/"+shared.ID+"/status", `{"status":"in_progress"}`, bob.ID)
+	resp2.Body.Close()
+	require.Equal(t, http.StatusOK, resp2.StatusCode)
+
+	var ids []string
+	for _, want := range []struct {
+		name string
+		todo string
+	}{{"created", own.ID}, {"status_changed", shared.ID}} {
+		frame := sseFrame(t, events)
+		for len(frame) > 0 && strings.HasPrefix(frame[0], ":") {
+			frame = sseFrame(t, events)
+		}
+		require.Len(t, frame, 3, frame)
+		assert.Equal(t, "event: "+want.name, frame[1])
+		var event TodoEvent
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(frame[2], "data: ")), &event))
+		assert.Equal(t, "id: "+event.ID, frame[0])
+		assert.Equal(t, want.todo, event.TodoID)
+		ids = append(ids, event.ID)
+	}
+	assert.Equal(t, []string{": heartbeat"}, sseFrame(t, events))
+	resp.Body.Close()
+
+	// Nối lại bằng Last-Event-ID nhận các sự kiện bị lỡ, qua cả alias cũ.
+	resp, events = stream("/todo/events", ids[0])
+	assert.Equal(t, `</v1/todos/events>; rel="successor-version"`, resp.Header.Get("Link"))
+	frame := sseFrame(t, events)
+	assert.Equal(t, "id: "+ids[1], frame[0])
+	resp.Body.Close()
+
+	resp, events = stream("/v1/todos/events", "0-1")
+	assert.Equal(t, []string{"event: " + eventReset, "data: {}"}, sseFrame(t, events))
+	resp.Body.Close()
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLen = 255
+	// defaultIdempotencyTTL là thời gian giữ response của một key, đổi bằng biến môi trường IDEMPOTENCY_TTL.
+	defaultIdempotencyTTL = 24 * time.Hour
+)
+
+// replayedHeaders là các header của response gốc được lưu lại và gửi lại khi replay.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Retry-After"}
+
+// IdempotencyRecord là một Idempotency-Key đã nhận cùng response đã trả cho nó.
+type IdempotencyRecord struct {
+	Key         string
+	Scope       string // sha256 của tenant, người dùng, method và path (xem idempotencyScope)
+	Fingerprint string // sha256 của request, để phát hiện key bị dùng lại cho request khác
+	Status      int    // 0 khi request gốc vẫn đang chạy
+	Header      map[string]string
+	Body        []byte
+	ExpiresAt   time.Time
+}
+
+// IdempotencyStore lưu Idempotency-Key và response tương ứng.
+type IdempotencyStore interface {
+	// Reserve giữ chỗ cho key. Trả về (nil, nil) nếu key mới và caller được thực thi request,
+	// record đã lưu nếu request gốc đã xong, ErrIdempotencyInProgress nếu request gốc còn đang chạy
+	// và ErrIdempotencyMismatch nếu key đã được dùng cho một request khác.
+	Reserve(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error)
+	// Complete lưu response của request đã thực thi.
+	Complete(ctx context.Context, rec IdempotencyRecord) error
+	// Release bỏ chỗ đã giữ khi request thất bại, để client thử lại với cùng key.
+	Release(ctx context.Context, key, scope string) error
+	// Purge xóa các key đã hết hạn và trả về số key bị xóa.
+	Purge(ctx context.Context, now time.Time) (int64, error)
+}
+
+var (
+	ErrIdempotencyInProgress = fmt.Errorf("a request with this Idempotency-Key is still in progress: %w", ErrConflict)
+	ErrIdempotencyMismatch   = fmt.Errorf("idempotency key was already used for a different request: %w", ErrValidation)
+)
+
+func checkReserved(existing, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	if existing.Fingerprint != rec.Fingerprint {
+		return nil, ErrIdempotencyMismatch
+	}
+	if existing.Status == 0 {
+		return nil, ErrIdempotencyInProgress
+	}
+	return &existing, nil
+}
+
+type idempotencyKey struct {
+	key, scope string
+}
+
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[idempotencyKey]IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore tạo IdempotencyStore trong bộ nhớ, đi cùng MemoryTodoService.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[idempotencyKey]IdempotencyRecord),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{rec.Key, rec.Scope}
+	if existing, ok := s.records[k]; ok && existing.ExpiresAt.After(time.Now()) {
+		return checkReserved(existing, rec)
+	}
+	rec.Status = 0
+	s.records[k] = rec
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, rec IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[idempotencyKey{rec.Key, rec.Scope}] = rec
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Release(ctx context.Context, key, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := idempotencyKey{key, scope}
+	if s.records[k].Status == 0 {
+		delete(s.records, k)
+	}
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	for k, rec := range s.records {
+		if !rec.ExpiresAt.After(now) {
+			delete(s.records, k)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+type DbIdempotencyStore struct {
+	db *Db
+}
+
+func NewDbIdempotencyStore(db *Db) *DbIdempotencyStore {
+	return &DbIdempotencyStore{
+		db: db,
+	}
+}
+
+func (s *DbIdempotencyStore) Reserve(ctx context.Context, rec IdempotencyRecord) (*IdempotencyRecord, error) {
+	now := time.Now().UTC()
+	// Key đã hết hạn nhưng chưa bị Purge được ghi đè như một key mới.
+	var reserved string
+	err := s.db.conn.QueryRow(ctx,
+		"INSERT INTO idempotency_keys (key, scope, fingerprint, status, created_at, expires_at) VALUES ($1, $2, $3, 0, $4, $5) "+
+			"ON CONFLICT (key, scope) DO UPDATE SET fingerprint = EXCLUDED.fingerprint, status = 0, headers = NULL, body = NULL, "+
+			"created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at WHERE idempotency_keys.expires_at <= $4 "+
+			"RETURNING key",
+		rec.Key, rec.Scope, rec.Fingerprint, now, rec.ExpiresAt.UTC()).Scan(&reserved)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, dbError("giữ chỗ idempotency key thất bại", err)
+	}
+
+	existing := IdempotencyRecord{Key: rec.Key, Scope: rec.Scope}
+	var header []byte
+	err = s.db.conn.QueryRow(ctx,
+		"SELECT fingerprint, status, headers, body, expires_at FROM idempotency_keys WHERE key = $1 AND scope = $2",
+		rec.Key, rec.Scope).Scan(&existing.Fingerprint, &existing.Status, &header, &existing.Body, &existing.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Request gốc vừa Release key giữa hai câu lệnh, client thử lại sẽ được thực thi.
+			return nil, ErrIdempotencyInProgress
+		}
+		return nil, dbError("đọc idempotency key thất bại", err)
+	}
+	if header != nil {
+		if err := json.Unmarshal(header, &existing.Header); err != nil {
+			return nil, fmt.Errorf("đọc header đã lưu của idempotency key thất bại: %w", err)
+		}
+	}
+	return checkReserved(existing, rec)
+}
+
+func (s *DbIdempotencyStore) Complete(ctx context.Context, rec IdempotencyRecord) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.conn.Exec(ctx,
+		"UPDATE idempotency_keys SET status = $1, headers = $2, body = $3 WHERE key = $4 AND scope = $5",
+		rec.Status, string(header), rec.Body, rec.Key, rec.Scope)
+	if err != nil {
+		return dbError("lưu response của idempotency key thất bại", err)
+	}
+	return nil
+}
+
+func (s *DbIdempotencyStore) Release(ctx context.Context, key, scope string) error {
+	_, err := s.db.conn.Exec(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND scope = $2 AND status = 0", key, scope)
+	if err != nil {
+		return dbError("giải phóng idempotency key thất bại", err)
+	}
+	return nil
+}
+
+func (s *DbIdempotencyStore) Purge(ctx context.Context, now time.Time) (int64, error) {
+	tag, err := s.db.conn.Exec(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now.UTC())
+	if err != nil {
+		return 0, dbError("xóa idempotency key hết hạn thất bại", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// capturingWriter ghi response cho client đồng thời giữ lại một bản để lưu theo Idempotency-Key.
+type capturingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *capturingWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *capturingWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// idempotent thực thi handler tối đa một lần cho mỗi Idempotency-Key. Request lặp lại với cùng key
+// nhận lại đúng response gốc (kèm header Idempotent-Replayed: true) thay vì gây thêm tác dụng phụ.
+// Response 5xx không được lưu, để client có thể thử lại với cùng key.
+func (h *APIHandler) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyHeader)
+		if key == "" || h.idempotency == nil {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLen {
+			writeProblem(w, r, http.StatusBadRequest, CodeInvalidIdempotencyKey,
+				fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLen))
+			return
+		}
+
+		body, err := readBodyLimit(w, r, maxBulkBodyBytes)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		rec := IdempotencyRecord{
+			Key:         key,
+			Scope:       idempotencyScope(r),
+			Fingerprint: requestFingerprint(r, body),
+			ExpiresAt:   time.Now().Add(h.idempotencyTTL),
+		}
+		stored, err := h.idempotency.Reserve(r.Context(), rec)
+		if err != nil {
+			if errors.Is(err, ErrIdempotencyInProgress) {
+				w.Header().Set("Retry-After", "1")
+			}
+			writeError(w, r, err)
+			return
+		}
+		if stored != nil {
+			for name, value := range stored.Header {
+				w.Header().Set(name, value)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(stored.Status)
+			w.Write(stored.Body)
+			return
+		}
+
+		// Lưu hoặc giải phóng key bằng context riêng: context của request có thể đã bị hủy khi client ngắt kết nối.
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		cw := &capturingWriter{ResponseWriter: w}
+		completed := false
+		defer func() {
+			if !completed {
+				if err := h.idempotency.Release(ctx, rec.Key, rec.Scope); err != nil {
+					log.Println("Error releasing idempotency key:", err)
+				}
+			}
+		}()
+
+		next(cw, r)
+
+		if cw.status == 0 || cw.status >= http.StatusInternalServerError {
+			return
+		}
+		rec.Status = cw.status
+		rec.Body = cw.body.Bytes()
+		rec.Header = make(map[string]string)
+		for _, name := range replayedHeaders {
+			if value := w.Header().Get(name); value != "" {
+				rec.Header[name] = value
+			}
+		}
+		if err := h.idempotency.Complete(ctx, rec); err != nil {
+			log.Println("Error storing idempotent response:", err)
+			return
+		}
+		completed = true
+	}
+}
+
+// requestFingerprint nhận diện nội dung request: cùng key nhưng khác body hoặc If-Match là một request khác.
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s %s\nIf-Match: %s\n\n", r.Method, r.URL.Path, r.Header.Get("If-Match"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// purgeIdempotencyKeys xóa định kỳ các key đã hết hạn cho đến khi ctx bị hủy.
+func purgeIdempotencyKeys(ctx context.Context, store IdempotencyStore, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if purged, err := store.Purge(ctx, now); err != nil {
+				log.Println("Error purging idempotency keys:", err)
+			} else if purged > 0 {
+				log.Printf("Đã xóa %d idempotency key hết hạn\n", purged)
+			}
+		}
+	}
+}
+
+// idempotencyScope trả về Scope của request: một key chỉ áp dụng cho đúng một route của một người dùng trong một tenant,
+// nên người khác dùng trùng key không nhận được response đã lưu của người đầu. Scope được băm để tenant và path dài
+// không vượt quá độ dài của cột scope.
+func idempotencyScope(r *http.Request) string {
+	ctx := r.Context()
+	sum := sha256.Sum256([]byte(tenantFrom(ctx) + "\n" + userFrom(ctx) + "\n" + r.Method + " " + r.URL.Path))
+	return hex.EncodeToString(sum[:])
+}
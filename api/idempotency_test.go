@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runIdempotencyStoreConformance kiểm tra các hành vi mà mọi IdempotencyStore phải có.
+func runIdempotencyStoreConformance(t *testing.T, newStore func(t *testing.T) IdempotencyStore) {
+	ctx := context.Background()
+	record := func(key, fingerprint string, ttl time.Duration) IdempotencyRecord {
+		return IdempotencyRecord{Key: key, Scope: "POST /v1/todos", Fingerprint: fingerprint, ExpiresAt: time.Now().Add(ttl)}
+	}
+
+	t.Run("ReserveCompleteReplay", func(t *testing.T) {
+		store := newStore(t)
+
+		rec := record("k1", "fp", time.Hour)
+		stored, err := store.Reserve(ctx, rec)
+		require.NoError(t, err)
+		assert.Nil(t, stored, "a new key is reserved for the caller")
+
+		_, err = store.Reserve(ctx, rec)
+		assert.ErrorIs(t, err, ErrIdempotencyInProgress)
+
+		rec.Status = 201
+		rec.Header = map[string]string{"Location": "/v1/todos/1"}
+		rec.Body = []byte(`{"id":"1"}`)
+		require.NoError(t, store.Complete(ctx, rec))
+
+		stored, err = store.Reserve(ctx, record("k1", "fp", time.Hour))
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.Equal(t, 201, stored.Status)
+		assert.Equal(t, "/v1/todos/1", stored.Header["Location"])
+		assert.Equal(t, `{"id":"1"}`, string(stored.Body))
+
+		_, err = store.Reserve(ctx, record("k1", "other", time.Hour))
+		assert.ErrorIs(t, err, ErrIdempotencyMismatch)
+
+		other := record("k1", "fp", time.Hour)
+		other.Scope = "DELETE /v1/todos/1"
+		stored, err = store.Reserve(ctx, other)
+		require.NoError(t, err)
+		assert.Nil(t, stored, "keys are scoped to one route")
+	})
+
+	t.Run("Release", func(t *testing.T) {
+		store := newStore(t)
+
+		rec := record("k2", "fp", time.Hour)
+		_, err := store.Reserve(ctx, rec)
+		require.NoError(t, err)
+		require.NoError(t, store.Release(ctx, rec.Key, rec.Scope))
+
+		stored, err := store.Reserve(ctx, rec)
+		require.NoError(t, err)
+		assert.Nil(t, stored, "a released key can be retried")
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		store := newStore(t)
+
+		expired := record("k3", "fp", -time.Minute)
+		_, err := store.Reserve(ctx, expired)
+		require.NoError(t, err)
+		expired.Status = 200
+		require.NoError(t, store.Complete(ctx, expired))
+
+		stored, err := store.Reserve(ctx, record("k3", "new", time.Hour))
+		require.NoError(t, err)
+		assert.Nil(t, stored, "an expired key is treated as new")
+
+		_, err = store.Reserve(ctx, record("k4", "fp", -time.Minute))
+		require.NoError(t, err)
+		purged, err := store.Purge(ctx, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+	})
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	runIdempotencyStoreConformance(t, func(t *testing.T) IdempotencyStore {
+		return NewMemoryIdempotencyStore()
+	})
+}
+
+func TestDbIdempotencyStore(t *testing.T) {
+	pool := testPool(t)
+
+	runIdempotencyStoreConformance(t, func(t *testing.T) IdempotencyStore {
+		_, err := pool.Exec(context.Background(), "TRUNCATE idempotency_keys")
+		require.NoError(t, err)
+		return NewDbIdempotencyStore(&Db{conn: pool})
+	})
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	handler, store := newMemoryHandler(t)
+	router := newRouter(handler)
+
+	send := func(method, path, key, ifMatch, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(idempotencyHeader, key)
+		}
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	count := func() int {
+		page, err := store.GetAllTodo(context.Background(), TodoQuery{})
+		require.NoError(t, err)
+		return len(page.Items)
+	}
+
+	t.Run("Create is replayed instead of inserting twice", func(t *testing.T) {
+		first := send(http.MethodPost, "/v1/todos", "create-1", "", `{"title":"Once"}`)
+		require.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+		retry := send(http.MethodPost, "/v1/todos", "create-1", "", `{"title":"Once"}`)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, first.Header().Get("Location"), retry.Header().Get("Location"))
+		assert.Equal(t, first.Header().Get("ETag"), retry.Header().Get("ETag"))
+		assert.JSONEq(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, 1, count())
+
+		legacy := send(http.MethodPost, "/todo/create", "create-1", "", `{"title":"Once"}`)
+		assert.Equal(t, http.StatusCreated, legacy.Code, "keys are scoped per route")
+		assert.Equal(t, 2, count())
+	})
+
+	t.Run("Reusing a key for another payload is rejected", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", "create-1", "", `{"title":"Different"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		assert.Equal(t, CodeIdempotencyKeyReused, decodeProblem(t, rr).Code)
+	})
+
+	t.Run("Toggle replay does not flip back", func(t *testing.T) {
+		created, err := store.CreateTodo(context.Background(), Todo{Title: "Flip once"})
+		require.NoError(t, err)
+		path := "/v1/todos/" + created.ID + "/toggle"
+
+		for i := 0; i < 3; i++ {
+			rr := send(http.MethodPost, path, "toggle-1", "*", "")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		}
+		got, err := store.GetTodo(context.Background(), created.ID)
+		require.NoError(t, err)
+		assert.True(t, got.Done)
+		assert.Equal(t, 2, got.Version)
+	})
+
+	t.Run("Delete replay returns the original 204", func(t *testing.T) {
+		created, err := store.CreateTodo(context.Background(), Todo{Title: "Delete once"})
+		require.NoError(t, err)
+		path := "/v1/todos/" + created.ID
+
+		assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, path, "delete-1", "*", "").Code)
+		retry := send(http.MethodDelete, path, "delete-1", "*", "")
+		assert.Equal(t, http.StatusNoContent, retry.Code)
+		assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	})
+
+	t.Run("Bulk", func(t *testing.T) {
+		before := count()
+		body := `{"action":"create","items":[{"title":"a"},{"title":"b"}]}`
+		first := send(http.MethodPost, "/v1/todos/bulk", "bulk-1", "", body)
+		retry := send(http.MethodPost, "/v1/todos/bulk", "bulk-1", "", body)
+		assert.Equal(t, first.Code, retry.Code)
+		var resp BulkResponse
+		require.NoError(t, json.NewDecoder(retry.Body).Decode(&resp))
+		assert.Equal(t, 2, resp.Succeeded)
+		assert.Equal(t, before+2, count())
+	})
+
+	t.Run("Concurrent retry while the original is running", func(t *testing.T) {
+		_, err := handler.idempotency.Reserve(context.Background(), IdempotencyRecord{
+			Key: "slow", Scope: idempotencyScope(httptest.NewRequest(http.MethodPost, "/v1/todos", nil)), Fingerprint: "whatever", ExpiresAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		rr := send(http.MethodPost, "/v1/todos", "slow", "", `{"title":"x"}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code, "a different payload is still a mismatch")
+
+		_, err = handler.idempotency.Reserve(context.Background(), IdempotencyRecord{
+			Key: "slow-same", Scope: idempotencyScope(httptest.NewRequest(http.MethodPost, "/v1/todos", nil)), Fingerprint: requestFingerprint(httptest.NewRequest(http.MethodPost, "/v1/todos", nil), []byte(`{"title":"x"}`)),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+		rr = send(http.MethodPost, "/v1/todos", "slow-same", "", `{"title":"x"}`)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+		assert.Equal(t, CodeIdempotencyInProgress, decodeProblem(t, rr).Code)
+		assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	})
+
+	t.Run("Keys are scoped per user", func(t *testing.T) {
+		users := map[string]string{}
+		for _, name := range []string{"alice", "bob"} {
+			rr := send(http.MethodPost, "/v1/users", "", "", `{"name":"`+name+`","email":"`+name+`@example.com"}`)
+			require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			var user User
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+			users[name] = user.ID
+		}
+		create := func(userID string) Todo {
+			req := httptest.NewRequest(http.MethodPost, "/v1/todos", strings.NewReader(`{"title":"private"}`))
+			req.Header.Set(idempotencyHeader, "shared-key")
+			req.Header.Set(userHeader, userID)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+			var todo Todo
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&todo))
+			return todo
+		}
+
+		alice, bob := create(users["alice"]), create(users["bob"])
+		assert.NotEqual(t, alice.ID, bob.ID)
+		assert.Equal(t, users["alice"], alice.OwnerID)
+		assert.Equal(t, users["bob"], bob.OwnerID)
+	})
+
+	t.Run("Long paths fit the scope column", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/todos/"+strings.Repeat("x", 300)+"/toggle", nil)
+		assert.Len(t, idempotencyScope(req), 64)
+	})
+
+	t.Run("Only 5xx responses release the key", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", "bad-1", "", `{"title":"  "}`)
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		rr = send(http.MethodPost, "/v1/todos", "bad-1", "", `{"title":"  "}`)
+		assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"), "4xx responses are part of the stored outcome")
+
+		mockStore := new(MockTodoStore)
+		failing := newRouter(NewAPIHandler(mockStore, NewMemoryIdempotencyStore()))
+		create := func() *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/v1/todos", strings.NewReader(`{"title":"x"}`))
+			req.Header.Set(idempotencyHeader, "unavailable")
+			rr := httptest.NewRecorder()
+			failing.ServeHTTP(rr, req)
+			return rr
+		}
+
+		mockStore.On("CreateTodo", Todo{Title: "x"}).Return(&Todo{}, ErrUnavailable).Once()
+		assert.Equal(t, http.StatusServiceUnavailable, create().Code)
+
+		mockStore.On("CreateTodo", Todo{Title: "x"}).Return(&Todo{ID: "1", Version: 1}, nil).Once()
+		assert.Equal(t, http.StatusCreated, create().Code, "5xx responses are not stored, the retry runs again")
+		mockStore.AssertExpectations(t)
+	})
+
+	t.Run("Key length is limited", func(t *testing.T) {
+		rr := send(http.MethodPost, "/v1/todos", strings.Repeat("k", maxIdempotencyKeyLen+1), "", `{"title":"x"}`)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		assert.Equal(t, CodeInvalidIdempotencyKey, decodeProblem(t, rr).Code)
+	})
+}
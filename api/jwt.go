@@ -0,0 +1,273 @@
+package main
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// Thuật toán ký JWT được chấp nhận; "none" và mọi thuật toán khác bị từ chối.
+const (
+	algHS256 = "HS256"
+	algRS256 = "RS256"
+)
+
+// jwtLeeway là độ lệch đồng hồ cho phép khi kiểm tra exp và nbf.
+const jwtLeeway = time.Minute
+
+// ErrInvalidToken là lỗi khi bearer token hoặc API key không hợp lệ, hết hạn hoặc đã bị thu hồi.
+var ErrInvalidToken = errors.New("invalid token")
+
+func invalidToken(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidToken, reason)
+}
+
+// jwtKey là một khóa xác minh chữ ký; mỗi khóa chỉ dùng cho đúng một thuật toán, để token không thể
+// đổi alg (ví dụ ký HS256 bằng khóa công khai RSA).
+type jwtKey struct {
+	alg    string
+	secret []byte
+	public *rsa.PublicKey
+}
+
+// jwtKeySet là các khóa xác minh JWT: khóa trong JWKS được chọn theo kid, khóa tĩnh được thử khi token không có kid
+// hoặc kid không có trong JWKS.
+type jwtKeySet struct {
+	byID   map[string]jwtKey
+	static []jwtKey
+}
+
+func (s *jwtKeySet) empty() bool {
+	return len(s.byID) == 0 && len(s.static) == 0
+}
+
+// addSecret thêm khóa HS256 tĩnh.
+func (s *jwtKeySet) addSecret(secret []byte) {
+	s.static = append(s.static, jwtKey{alg: algHS256, secret: secret})
+}
+
+// addPublicKeyPEM thêm khóa RS256 tĩnh từ PEM "PUBLIC KEY" (PKIX) hoặc "RSA PUBLIC KEY" (PKCS #1).
+func (s *jwtKeySet) addPublicKeyPEM(data []byte) error {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return errors.New("không tìm thấy khối PEM")
+	}
+	var public *rsa.PublicKey
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return err
+		}
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("khóa công khai không phải RSA")
+		}
+		public = rsaKey
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return err
+		}
+		public = key
+	default:
+		return fmt.Errorf("khối PEM %q không phải khóa công khai", block.Type)
+	}
+	s.static = append(s.static, jwtKey{alg: algRS256, public: public})
+	return nil
+}
+
+// jwk là một khóa trong JWKS (RFC 7517); chỉ kty RSA (RS256) và oct (HS256) được hỗ trợ.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// addJWKS thêm các khóa của một JWKS; khóa không có kid, khóa mã hóa (use "enc") và kty khác bị bỏ qua.
+func (s *jwtKeySet) addJWKS(data []byte) error {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("JWKS không hợp lệ: %w", err)
+	}
+	if s.byID == nil {
+		s.byID = make(map[string]jwtKey)
+	}
+	for _, k := range set.Keys {
+		if k.Kid == "" || k.Use == "enc" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA" && (k.Alg == "" || k.Alg == algRS256):
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return fmt.Errorf("khóa %s: n không hợp lệ: %w", k.Kid, err)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return fmt.Errorf("khóa %s: e không hợp lệ: %w", k.Kid, err)
+			}
+			exponent := new(big.Int).SetBytes(e)
+			if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 {
+				return fmt.Errorf("khóa %s: modulus hoặc exponent không hợp lệ", k.Kid)
+			}
+			s.byID[k.Kid] = jwtKey{alg: algRS256, public: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}}
+		case k.Kty == "oct" && (k.Alg == "" || k.Alg == algHS256):
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return fmt.Errorf("khóa %s: k không hợp lệ", k.Kid)
+			}
+			s.byID[k.Kid] = jwtKey{alg: algHS256, secret: secret}
+		}
+	}
+	return nil
+}
+
+// candidates trả về các khóa có thể đã ký token với alg và kid cho trước.
+func (s *jwtKeySet) candidates(alg, kid string) []jwtKey {
+	if key, ok := s.byID[kid]; ok && kid != "" {
+		if key.alg != alg {
+			return nil
+		}
+		return []jwtKey{key}
+	}
+	var keys []jwtKey
+	for _, key := range s.static {
+		if key.alg == alg {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func (k jwtKey) verify(signed, signature []byte) bool {
+	switch k.alg {
+	case algHS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case algRS256:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(k.public, crypto.SHA256, digest[:], signature) == nil
+	}
+	return false
+}
+
+// audience là claim aud, một chuỗi hoặc một mảng chuỗi.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*a = audience{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// jwtClaims là các claim được đọc từ token. Name và Email chỉ dùng khi tạo người dùng cho subject mới (xem Authenticator).
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Name      string   `json:"name"`
+	Email     string   `json:"email"`
+	// Tenant là tenant của subject, DefaultTenantID khi vắng.
+	Tenant string `json:"tenant_id"`
+}
+
+// jwtVerifier xác minh chữ ký và các claim thời gian, issuer, audience của JWT dạng compact.
+type jwtVerifier struct {
+	keys *jwtKeySet
+	// issuer và audience rỗng thì không kiểm tra.
+	issuer   string
+	audience string
+}
+
+func (v *jwtVerifier) verify(token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalidToken("token is not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, invalidToken("malformed token header")
+	}
+	if header.Alg != algHS256 && header.Alg != algRS256 {
+		return nil, invalidToken(fmt.Sprintf("signing algorithm %q is not accepted", header.Alg))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, invalidToken("malformed token signature")
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	verified := false
+	for _, key := range v.keys.candidates(header.Alg, header.Kid) {
+		if key.verify(signed, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, invalidToken("token signature does not match any configured key")
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, invalidToken("malformed token claims")
+	}
+	switch {
+	case claims.Subject == "":
+		return nil, invalidToken("token has no subject")
+	case claims.ExpiresAt == nil:
+		return nil, invalidToken("token has no expiry")
+	case now.After(unixTime(*claims.ExpiresAt).Add(jwtLeeway)):
+		return nil, invalidToken("token has expired")
+	case claims.NotBefore != nil && now.Add(jwtLeeway).Before(unixTime(*claims.NotBefore)):
+		return nil, invalidToken("token is not valid yet")
+	case v.issuer != "" && claims.Issuer != v.issuer:
+		return nil, invalidToken("token was issued by another issuer")
+	case v.audience != "" && !containsString(claims.Audience, v.audience):
+		return nil, invalidToken("token is meant for another audience")
+	}
+	return &claims, nil
+}
+
+func decodeSegment(segment string, dst interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// unixTime đổi NumericDate (giây kể từ epoch, có thể có phần lẻ) sang time.Time.
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
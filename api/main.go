@@ -8,51 +8,85 @@
 // @license.name Apache 2.0
 // @license.url http://www.apache.org/licenses/LICENSE-2.0.html
 // @host localhost:8080
-// @BasePath /todo
+// @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT (HS256 or RS256) or API key, sent as "Bearer <token>".
 
 package main
 
 import (
 	_ "api/docs"
+	"context"
 	f "fmt"
-	"github.com/gorilla/mux"
 	"github.com/rs/cors"
-	"github.com/swaggo/http-swagger"
 	"log"
 	"net/http"
 	"os"
+	"time"
 )
 
 func main() {
-	db, err := NewDb()
+	workflow := DefaultWorkflow()
+	if raw := os.Getenv("TODO_WORKFLOW"); raw != "" {
+		wf, err := ParseWorkflow([]byte(raw))
+		if err != nil {
+			log.Fatalf("TODO_WORKFLOW không hợp lệ: %v", err)
+		}
+		workflow = wf
+	}
+
+	backend, err := newBackend(os.Getenv("TODO_STORE"), workflow)
 	if err != nil {
 		f.Printf("Lỗi khi khởi tạo cơ sở dữ liệu: %v\n", err)
 		return
 	}
 
-	if os.Getenv("RUN_MIGRATION") == "true" {
-		if err := Migrate(); err != nil {
-			log.Fatalf("Lỗi khi áp dụng migration: %v", err)
+	// Mọi thao tác ghi đi qua EventTodoService để client của /v1/todos/events nhận được thay đổi.
+	events := NewEventBroker(defaultEventBufferSize)
+	apiHandler := NewAPIHandler(NewEventTodoService(backend.todos, events), backend.idempotency)
+	apiHandler.events = events
+	if ttl := os.Getenv("IDEMPOTENCY_TTL"); ttl != "" {
+		d, err := time.ParseDuration(ttl)
+		if err != nil || d <= 0 {
+			log.Fatalf("IDEMPOTENCY_TTL không hợp lệ: %q", ttl)
 		}
+		apiHandler.idempotencyTTL = d
 	}
+	go purgeIdempotencyKeys(context.Background(), backend.idempotency, time.Hour)
 
-	todoService := NewDbTodoService(db)
-	apiHandler := NewAPIHandler(todoService)
+	apiHandler.apiKeys = backend.apiKeys
+	if os.Getenv("AUTH_DISABLED") == "true" {
+		log.Println("Cảnh báo: xác thực đã bị tắt (AUTH_DISABLED=true), mọi người đều gọi được API và tự chọn người dùng bằng X-User-ID")
+	} else {
+		auth, err := loadAuthenticator(os.Getenv)
+		if err != nil {
+			log.Fatalf("Cấu hình xác thực không hợp lệ: %v", err)
+		}
+		if !auth.acceptsJWT() {
+			log.Println("Chưa cấu hình khóa JWT (JWT_HS256_SECRET, JWT_RS256_PUBLIC_KEY hoặc JWT_JWKS_FILE), chỉ API key được chấp nhận")
+		}
+		apiHandler.auth = auth
+	}
 
-	router := mux.NewRouter()
+	retention := defaultTrashRetention
+	if raw := os.Getenv("TRASH_RETENTION"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			log.Fatalf("TRASH_RETENTION không hợp lệ: %q", raw)
+		}
+		retention = d
+	}
+	go purgeTrash(context.Background(), apiHandler.todoService, retention, time.Hour)
 
-	router.HandleFunc("/todo", apiHandler.GetAllTodo).Methods(http.MethodGet)
-	router.HandleFunc("/todo/getuser/{id}", apiHandler.GetTodo).Methods(http.MethodGet)
-	router.HandleFunc("/todo/create", apiHandler.CreateTodo).Methods(http.MethodPost)
-	router.HandleFunc("/todo/update/{id}", apiHandler.UpdateTodo).Methods(http.MethodPatch)
-	router.HandleFunc("/todo/update-status/{id}", apiHandler.UpdateTodoStatus).Methods(http.MethodPatch)
-	router.HandleFunc("/todo/delete/{id}", apiHandler.DeleteTodo).Methods(http.MethodDelete)
-	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	router := newRouter(apiHandler)
 
 	corsHandler := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PATCH", "DELETE"},
-		AllowedHeaders:   []string{"Authorization", "Content-Type"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "If-Match", "If-None-Match", "Idempotency-Key", "Last-Event-ID", actorHeader, userHeader},
+		ExposedHeaders:   []string{"Location", "Link", "Deprecation", "Sunset", "ETag", "Idempotent-Replayed", "WWW-Authenticate"},
 		AllowCredentials: true,
 	}).Handler(router)
 
@@ -64,3 +98,50 @@ func main() {
 	f.Printf("Server On :%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, corsHandler))
 }
+
+// backend gom các store dùng chung một kiểu lưu trữ.
+type backend struct {
+	todos       TodoService
+	idempotency IdempotencyStore
+	apiKeys     APIKeyStore
+}
+
+// newBackend chọn backend lưu trữ theo biến môi trường TODO_STORE: "db" (mặc định) hoặc "memory".
+func newBackend(store string, workflow *Workflow) (*backend, error) {
+	switch store {
+	case "memory":
+		f.Println("Sử dụng bộ nhớ trong (in-memory), dữ liệu sẽ mất khi tắt server")
+		// Mỗi tenant có một MemoryTodoService riêng (xem TenantTodoService).
+		todos := NewTenantTodoService(func() TodoService {
+			todos := NewMemoryTodoService()
+			todos.workflow = workflow
+			return todos
+		})
+		return &backend{
+			todos:       todos,
+			idempotency: NewMemoryIdempotencyStore(),
+			apiKeys:     NewMemoryAPIKeyStore(),
+		}, nil
+	case "", "db":
+		db, err := NewDb()
+		if err != nil {
+			return nil, err
+		}
+
+		if os.Getenv("RUN_MIGRATION") == "true" {
+			if err := Migrate(); err != nil {
+				log.Fatalf("Lỗi khi áp dụng migration: %v", err)
+			}
+		}
+
+		todos := NewDbTodoService(db)
+		todos.workflow = workflow
+		return &backend{
+			todos:       todos,
+			idempotency: NewDbIdempotencyStore(db),
+			apiKeys:     NewDbAPIKeyStore(db),
+		}, nil
+	default:
+		return nil, f.Errorf("TODO_STORE không hợp lệ: %q (chỉ hỗ trợ \"db\" hoặc \"memory\")", store)
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// TodoMergePatch mô tả body merge patch trong tài liệu swagger; chỉ các trường có mặt mới được ghi.
+// desc, due_at, estimate và recurrence null nghĩa là xóa giá trị.
+type TodoMergePatch struct {
+	Title      *string    `json:"title,omitempty"`
+	Desc       *string    `json:"desc,omitempty"`
+	Done       *bool      `json:"done,omitempty"`
+	Status     *string    `json:"status,omitempty" example:"blocked"`
+	Priority   *string    `json:"priority,omitempty" enums:"low,normal,high,urgent" example:"urgent"`
+	DueAt      *time.Time `json:"due_at,omitempty" example:"2026-01-31T17:00:00Z"`
+	Estimate   *int       `json:"estimate,omitempty" example:"30"`
+	Tags       []string   `json:"tags,omitempty" example:"frontend,qr"`
+	ProjectID  *string    `json:"project_id,omitempty" example:"default"`
+	Recurrence *string    `json:"recurrence,omitempty" example:"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"`
+}
+
+// patchMediaType trả về media type của body PATCH; application/json được coi như merge patch.
+func patchMediaType(r *http.Request) (string, bool) {
+	header := r.Header.Get("Content-Type")
+	if header == "" {
+		return mergePatchContentType, true
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", false
+	}
+	switch mediaType {
+	case mergePatchContentType, "application/json":
+		return mergePatchContentType, true
+	case jsonPatchContentType:
+		return jsonPatchContentType, true
+	}
+	return "", false
+}
+
+// decodeMergePatch đọc một JSON Merge Patch (RFC 7396) thành TodoPatch.
+func decodeMergePatch(body []byte) (TodoPatch, error) {
+	fields, err := decodeObject(body)
+	if err != nil {
+		return TodoPatch{}, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidBody)
+	}
+	normalized, err := patchTodoRules.check(fields, true)
+	if err != nil {
+		return TodoPatch{}, err
+	}
+	return todoPatchFromFields(normalized), nil
+}
+
+// todoPatchFromFields chuyển các trường đã qua patchTodoRules thành TodoPatch; desc, due_at, estimate và recurrence null
+// nghĩa là xóa giá trị.
+func todoPatchFromFields(fields map[string]json.RawMessage) TodoPatch {
+	var patch TodoPatch
+	if raw, ok := fields["title"]; ok {
+		patch.Title = new(string)
+		_ = json.Unmarshal(raw, patch.Title)
+	}
+	if raw, ok := fields["desc"]; ok {
+		patch.Desc = new(string)
+		_ = json.Unmarshal(raw, patch.Desc)
+	}
+	if raw, ok := fields["done"]; ok {
+		patch.Done = new(bool)
+		_ = json.Unmarshal(raw, patch.Done)
+	}
+	if raw, ok := fields["status"]; ok {
+		patch.Status = new(Status)
+		_ = json.Unmarshal(raw, patch.Status)
+	}
+	if raw, ok := fields["priority"]; ok {
+		patch.Priority = new(Priority)
+		_ = json.Unmarshal(raw, patch.Priority)
+	}
+	if raw, ok := fields["due_at"]; ok {
+		patch.DueAt = new(*time.Time)
+		_ = json.Unmarshal(raw, patch.DueAt)
+	}
+	if raw, ok := fields["estimate"]; ok {
+		patch.Estimate = new(*int)
+		_ = json.Unmarshal(raw, patch.Estimate)
+	}
+	if raw, ok := fields["tags"]; ok {
+		patch.Tags = new([]string)
+		_ = json.Unmarshal(raw, patch.Tags)
+	}
+	if raw, ok := fields["project_id"]; ok {
+		patch.ProjectID = new(string)
+		_ = json.Unmarshal(raw, patch.ProjectID)
+	}
+	if raw, ok := fields["recurrence"]; ok {
+		patch.Recurrence = new(*Recurrence)
+		_ = json.Unmarshal(raw, patch.Recurrence)
+	}
+	return patch
+}
+
+func sortedKeys(fields map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// jsonPatchOp là một thao tác JSON Patch (RFC 6902).
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+func parseJSONPatch(body []byte) ([]jsonPatchOp, error) {
+	var ops []jsonPatchOp
+	if err := json.Unmarshal(body, &ops); err != nil {
+		return nil, fmt.Errorf("%w: JSON Patch must be an array of operations", ErrInvalidBody)
+	}
+	for i, op := range ops {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) requires a value", ErrInvalidBody, i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidBody, i, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidBody, i, op.Op)
+		}
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d: %v", ErrInvalidBody, i, err)
+		}
+	}
+	return ops, nil
+}
+
+// todoPatchFromJSONPatch áp dụng các thao tác lên biểu diễn JSON hiện tại của todo
+// và chuyển các trường đã thay đổi thành TodoPatch.
+func todoPatchFromJSONPatch(current Todo, ops []jsonPatchOp) (TodoPatch, error) {
+	verr := &ValidationError{}
+	for _, op := range ops {
+		if op.Op == "test" {
+			continue
+		}
+		for _, path := range []string{op.Path, op.From} {
+			if fe, ok := serverManagedFields[topLevelField(path)]; ok {
+				verr.Add(topLevelField(path), fe.Code, fe.Message)
+			}
+		}
+	}
+	if err := verr.Err(); err != nil {
+		return TodoPatch{}, err
+	}
+
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return TodoPatch{}, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return TodoPatch{}, err
+	}
+	original := doc.(map[string]interface{})
+	patched, err := applyJSONPatch(deepCopy(doc), ops)
+	if err != nil {
+		return TodoPatch{}, err
+	}
+	result, ok := patched.(map[string]interface{})
+	if !ok {
+		return TodoPatch{}, fmt.Errorf("%w: patch must leave the todo a JSON object", ErrValidation)
+	}
+
+	// Chỉ các trường có giá trị khác trước mới được ghi; trường bị remove được coi là null như merge patch.
+	changed := make(map[string]json.RawMessage)
+	for name, before := range original {
+		if after, ok := result[name]; !ok {
+			changed[name] = json.RawMessage("null")
+		} else if !jsonEqual(before, after) {
+			changed[name], _ = json.Marshal(after)
+		}
+	}
+	for name, after := range result {
+		if _, ok := original[name]; !ok {
+			changed[name], _ = json.Marshal(after)
+		}
+	}
+	normalized, err := patchTodoRules.check(changed, true)
+	if err != nil {
+		return TodoPatch{}, err
+	}
+	return todoPatchFromFields(normalized), nil
+}
+
+func topLevelField(path string) string {
+	tokens, err := parsePointer(path)
+	if err != nil || len(tokens) == 0 {
+		return ""
+	}
+	return tokens[0]
+}
+
+// applyJSONPatch áp dụng tuần tự các thao tác RFC 6902 lên một tài liệu JSON đã giải mã.
+// Thao tác "test" thất bại hoặc đường dẫn không tồn tại trả về ErrConflict.
+func applyJSONPatch(doc interface{}, ops []jsonPatchOp) (interface{}, error) {
+	var err error
+	for i, op := range ops {
+		path, _ := parsePointer(op.Path)
+		switch op.Op {
+		case "add", "replace":
+			var value interface{}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return nil, fmt.Errorf("%w: operation %d value is not valid JSON", ErrInvalidBody, i)
+			}
+			doc, err = pointerSet(doc, path, value, op.Op == "add")
+		case "remove":
+			doc, _, err = pointerRemove(doc, path)
+		case "move", "copy":
+			from, _ := parsePointer(op.From)
+			var value interface{}
+			if op.Op == "move" {
+				if isPrefix(from, path) && len(from) < len(path) {
+					return nil, fmt.Errorf("%w: operation %d moves a value into itself", ErrInvalidBody, i)
+				}
+				doc, value, err = pointerRemove(doc, from)
+			} else {
+				value, err = pointerGet(doc, from)
+				value = deepCopy(value)
+			}
+			if err == nil {
+				doc, err = pointerSet(doc, path, value, true)
+			}
+		case "test":
+			var want, got interface{}
+			if err := json.Unmarshal(op.Value, &want); err != nil {
+				return nil, fmt.Errorf("%w: operation %d value is not valid JSON", ErrInvalidBody, i)
+			}
+			got, err = pointerGet(doc, path)
+			if err == nil && !jsonEqual(got, want) {
+				err = fmt.Errorf("test failed at %s", op.Path)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: JSON Patch operation %d (%s): %v", ErrConflict, i, op.Op, err)
+		}
+	}
+	return doc, nil
+}
+
+// parsePointer tách JSON Pointer (RFC 6901) thành các token đã giải mã ~0 và ~1.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if idx > limit {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path member %q does not exist", token)
+			}
+			doc = value
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("cannot traverse into a scalar at %q", token)
+		}
+	}
+	return doc, nil
+}
+
+// pointerSet gán value tại path. Với insert=true (add/move/copy), phần tử mảng được chèn vào thay vì thay thế;
+// với replace, đích phải tồn tại.
+func pointerSet(doc interface{}, path []string, value interface{}, insert bool) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := node[last]; !ok && !insert {
+			return nil, fmt.Errorf("path member %q does not exist", last)
+		}
+		node[last] = value
+		return doc, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), insert)
+		if err != nil {
+			return nil, err
+		}
+		if !insert {
+			node[idx] = value
+			return doc, nil
+		}
+		grown := append(node[:idx:idx], append([]interface{}{value}, node[idx:]...)...)
+		return pointerSet(doc, path[:len(path)-1], grown, false)
+	default:
+		return nil, fmt.Errorf("cannot set a member of a scalar at %q", last)
+	}
+}
+
+func pointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	parent, err := pointerGet(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+	switch node := parent.(type) {
+	case map[string]interface{}:
+		value, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path member %q does not exist", last)
+		}
+		delete(node, last)
+		return doc, value, nil
+	case []interface{}:
+		idx, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := node[idx]
+		shrunk := append(append([]interface{}{}, node[:idx]...), node[idx+1:]...)
+		doc, err = pointerSet(doc, path[:len(path)-1], shrunk, false)
+		return doc, value, err
+	default:
+		return nil, nil, fmt.Errorf("cannot remove a member of a scalar at %q", last)
+	}
+}
+
+func deepCopy(value interface{}) interface{} {
+	raw, _ := json.Marshal(value)
+	var out interface{}
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ra, errA := json.Marshal(a)
+	rb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ra, rb)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Priority là mức ưu tiên của todo. Trong database lưu dạng số để sắp xếp (càng lớn càng gấp), API dùng tên.
+// Giá trị 0 là PriorityNormal, nên todo không chỉ định priority có mức bình thường.
+type Priority int
+
+const (
+	PriorityLow Priority = iota - 1
+	PriorityNormal
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = map[Priority]string{
+	PriorityLow:    "low",
+	PriorityNormal: "normal",
+	PriorityHigh:   "high",
+	PriorityUrgent: "urgent",
+}
+
+// maxEstimateMinutes giới hạn estimate ở một năm làm việc liên tục, đủ lớn cho mọi todo thật.
+const maxEstimateMinutes = 60 * 24 * 365
+
+func priorityNameList() []string {
+	names := make([]string, 0, len(priorityNames))
+	for p := PriorityLow; p <= PriorityUrgent; p++ {
+		names = append(names, priorityNames[p])
+	}
+	return names
+}
+
+// ParsePriority đọc tên mức ưu tiên (low, normal, high, urgent).
+func ParsePriority(name string) (Priority, error) {
+	for p, n := range priorityNames {
+		if n == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("priority must be one of %s", strings.Join(priorityNameList(), ", "))
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+func (p Priority) valid() bool {
+	_, ok := priorityNames[p]
+	return ok
+}
+
+func (p Priority) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.String())
+}
+
+func (p *Priority) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	parsed, err := ParsePriority(name)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// validatePlanning kiểm tra priority và estimate của todo (due_at nào cũng hợp lệ, kể cả trong quá khứ).
+func validatePlanning(verr *ValidationError, priority Priority, estimate *int) {
+	if !priority.valid() {
+		verr.Add("priority", "invalid_value", "priority must be one of "+strings.Join(priorityNameList(), ", "))
+	}
+	if estimate != nil && (*estimate <= 0 || *estimate > maxEstimateMinutes) {
+		verr.Add("estimate", "out_of_range", fmt.Sprintf("estimate must be between 1 and %d minutes", maxEstimateMinutes))
+	}
+}
+
+// isOverdue cho biết todo đã quá hạn tại thời điểm now: có due_at trong quá khứ và chưa done hoặc cancelled.
+func isOverdue(todo Todo, now time.Time) bool {
+	return todo.DueAt != nil && todo.DueAt.Before(now) && !todo.Done && todo.Status != StatusCancelled
+}
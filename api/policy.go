@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role là vai trò của người dùng trên một todo hoặc project. Vai trò cao hơn có mọi quyền của vai trò thấp hơn:
+// viewer đọc, editor sửa và đổi trạng thái, owner còn được xóa, khôi phục, xóa hẳn và chia sẻ.
+type Role string
+
+const (
+	roleNone   Role = ""
+	RoleViewer Role = "viewer"
+	RoleEditor Role = "editor"
+	RoleOwner  Role = "owner"
+)
+
+var roles = []Role{RoleViewer, RoleEditor, RoleOwner}
+
+func (r Role) rank() int {
+	for i, role := range roles {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// atLeast cho biết r có mọi quyền của need.
+func (r Role) atLeast(need Role) bool {
+	return r.rank() >= need.rank()
+}
+
+// higher trả về vai trò cao hơn trong hai vai trò.
+func higher(a, b Role) Role {
+	if b.rank() > a.rank() {
+		return b
+	}
+	return a
+}
+
+// rolesFrom trả về các vai trò có mọi quyền của need, dạng danh sách SQL ('editor', 'owner').
+func rolesFrom(need Role) string {
+	var quoted []string
+	for _, role := range roles {
+		if role.atLeast(need) {
+			quoted = append(quoted, "'"+string(role)+"'")
+		}
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func validateRole(role Role) error {
+	if role.rank() == 0 {
+		verr := &ValidationError{}
+		verr.Add("role", "invalid_value", "role must be one of viewer, editor, owner")
+		return verr
+	}
+	return nil
+}
+
+// ShareRequest là payload của PUT /v1/todos/{id}/shares/{user_id} và PUT /v1/projects/{id}/shares/{user_id};
+// bỏ trống role để chia sẻ với vai trò editor.
+type ShareRequest struct {
+	Role Role `json:"role" enums:"viewer,editor,owner" example:"viewer"`
+}
+
+var shareRules = payloadRules{
+	fields: map[string]fieldRule{
+		"role": {kind: "string", trim: true},
+	},
+	rejected: map[string]FieldError{
+		"user_id":    {Code: "read_only", Message: "the user is taken from the URL"},
+		"created_at": {Code: "read_only", Message: "created_at is set by the server"},
+	},
+}
+
+// decodeShareRole đọc vai trò của một lần chia sẻ từ body, body rỗng là editor như trước khi có vai trò.
+func decodeShareRole(w http.ResponseWriter, r *http.Request) (Role, error) {
+	body, err := readBody(w, r)
+	if err != nil {
+		return roleNone, err
+	}
+	var input ShareRequest
+	if len(bytes.TrimSpace(body)) > 0 {
+		fields, err := decodeObject(body)
+		if err != nil {
+			return roleNone, err
+		}
+		if err := shareRules.decode(fields, &input); err != nil {
+			return roleNone, err
+		}
+	}
+	if input.Role == roleNone {
+		return RoleEditor, nil
+	}
+	return input.Role, nil
+}
+
+// ForbiddenError là lỗi khi người dùng thấy resource nhưng vai trò của họ không đủ cho thao tác.
+type ForbiddenError struct {
+	Resource string
+	ID       string
+	Need     Role
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("the %s role on %s %s is required", e.Need, e.Resource, e.ID)
+}
+
+type requiredRoleKey struct{}
+
+// withRequiredRole giới hạn mọi truy vấn của TodoService trong ctx vào các todo mà người dùng có ít nhất vai trò need,
+// dùng cho thao tác trên nhiều todo cùng lúc mà policy không kiểm tra được từng todo trước (ví dụ dọn thùng rác).
+func withRequiredRole(ctx context.Context, need Role) context.Context {
+	return context.WithValue(ctx, requiredRoleKey{}, need)
+}
+
+// requiredRole trả về vai trò tối thiểu của ctx, mặc định viewer: thấy được là có vai trò viewer.
+func requiredRole(ctx context.Context) Role {
+	if need, _ := ctx.Value(requiredRoleKey{}).(Role); need != roleNone {
+		return need
+	}
+	return RoleViewer
+}
+
+// permission là điều kiện của một thao tác: vai trò tối thiểu trên todo, project, series, tag hoặc tenant được
+// nhắm tới.
+type permission struct {
+	resource string
+	role     Role
+}
+
+var (
+	permReadTodo      = permission{"todo", RoleViewer}
+	permEditTodo      = permission{"todo", RoleEditor}
+	permManageTodo    = permission{"todo", RoleOwner}
+	permReadProject   = permission{"project", RoleViewer}
+	permEditProject   = permission{"project", RoleEditor}
+	permManageProject = permission{"project", RoleOwner}
+	permReadSeries    = permission{"series", RoleViewer}
+	permEditSeries    = permission{"series", RoleEditor}
+	permReadTag       = permission{"tag", RoleViewer}
+	permEditTag       = permission{"tag", RoleEditor}
+	permUseTenant     = permission{"tenant", RoleViewer}
+	permManageTenant  = permission{"tenant", RoleOwner}
+)
+
+// authorize là policy mà mọi handler gọi trước TodoService: trả về nil nếu người dùng trong ctx có quyền perm trên
+// resource id, ForbiddenError nếu vai trò không đủ và NotFoundError nếu họ không thấy resource. Request không có
+// người dùng (xác thực bị tắt và không gửi X-User-ID) không bị giới hạn.
+func (h *APIHandler) authorize(ctx context.Context, perm permission, id string) error {
+	if perm.resource == "" || userFrom(ctx) == "" {
+		return nil
+	}
+	var role Role
+	switch perm.resource {
+	case "todo":
+		// Thấy được todo là có vai trò viewer, TodoService đã trả về 404 cho todo không thấy được.
+		if perm.role == RoleViewer {
+			return nil
+		}
+		r, err := h.todoService.TodoRole(ctx, id)
+		if err != nil {
+			return err
+		}
+		role = r
+	case "project":
+		// Todo không chỉ định project thuộc project mặc định, project này không chia sẻ được nên ai cũng là owner.
+		if id == "" {
+			return nil
+		}
+		r, err := h.todoService.ProjectRole(ctx, id)
+		if err != nil {
+			return err
+		}
+		role = r
+	case "series":
+		if perm.role == RoleViewer {
+			return nil
+		}
+		// Sửa series là sửa mọi occurrence còn phải làm, nên cần vai trò trên từng occurrence.
+		occurrences, err := h.todoService.GetSeries(ctx, id)
+		if err != nil {
+			return err
+		}
+		role = RoleOwner
+		for _, todo := range occurrences {
+			if !pendingOccurrence(todo) {
+				continue
+			}
+			r, err := h.todoService.TodoRole(ctx, todo.ID)
+			if err != nil {
+				return err
+			}
+			if !r.atLeast(role) {
+				role = r
+			}
+		}
+	case "tag":
+		if perm.role == RoleViewer {
+			return nil
+		}
+		// Đổi tên hay xóa tag là sửa mọi todo mang nó, kể cả todo người dùng không thấy.
+		r, err := h.todoService.TagRole(ctx, id)
+		if err != nil {
+			return err
+		}
+		role = r
+	case "tenant":
+		// Người dùng chỉ là thành viên của tenant; quản trị tenant (tạo người dùng) dành cho request không có người
+		// dùng, tức khi xác thực bị tắt, còn người dùng mới được tạo từ token khi đăng nhập lần đầu.
+		role = RoleViewer
+	}
+	if !role.atLeast(perm.role) {
+		return &ForbiddenError{Resource: perm.resource, ID: id, Need: perm.role}
+	}
+	return nil
+}
+
+// authorizeChange kiểm tra quyền sửa todo id và, khi thay đổi chuyển todo sang project khác, quyền thêm todo
+// vào project đó.
+func (h *APIHandler) authorizeChange(ctx context.Context, id string, projectID *string) error {
+	if err := h.authorize(ctx, permEditTodo, id); err != nil {
+		return err
+	}
+	if projectID == nil || userFrom(ctx) == "" {
+		return nil
+	}
+	current, err := h.todoService.GetTodo(ctx, id)
+	if err != nil {
+		return err
+	}
+	if current.ProjectID == *projectID {
+		return nil
+	}
+	return h.authorize(ctx, permEditProject, *projectID)
+}
+
+// authorizeUnshare cho phép owner bỏ chia sẻ với bất kỳ ai, và người được chia sẻ tự rời đi.
+func (h *APIHandler) authorizeUnshare(ctx context.Context, perm permission, id, userID string) error {
+	if userID != "" && userID == userFrom(ctx) {
+		perm.role = RoleViewer
+	}
+	return h.authorize(ctx, perm, id)
+}
+
+// bulkPermission là quyền cần cho mỗi phần tử của một batch.
+func bulkPermission(action string) permission {
+	if action == "delete" {
+		return permManageTodo
+	}
+	return permEditTodo
+}
+
+// authorizeBulk kiểm tra quyền của từng phần tử trong batch theo thứ tự, nil với phần tử được phép.
+func (h *APIHandler) authorizeBulk(ctx context.Context, batch *bulkBatch) ([]error, bool) {
+	var denied []error
+	refused := false
+	deny := func(err error) {
+		denied = append(denied, err)
+		refused = refused || err != nil
+	}
+	if batch.action == "create" {
+		for _, todo := range batch.todos {
+			deny(h.authorize(ctx, permEditProject, todo.ProjectID))
+		}
+		return denied, refused
+	}
+	for _, item := range batch.items {
+		if batch.action == "update" {
+			deny(h.authorizeChange(ctx, item.ID, item.Patch.ProjectID))
+		} else {
+			deny(h.authorize(ctx, bulkPermission(batch.action), item.ID))
+		}
+	}
+	return denied, refused
+}
+
+// allowedBulk chạy run với các phần tử được phép của batch rồi ghép kết quả với lỗi của các phần tử bị từ chối theo
+// thứ tự ban đầu. Batch atomic có phần tử bị từ chối không được chạy.
+func (h *APIHandler) allowedBulk(ctx context.Context, batch *bulkBatch, run func(batch *bulkBatch) ([]BulkResult, error)) ([]BulkResult, error) {
+	denied, refused := h.authorizeBulk(ctx, batch)
+	if !refused {
+		return run(batch)
+	}
+	results := make([]BulkResult, len(denied))
+	allowed := &bulkBatch{action: batch.action, opts: batch.opts}
+	var positions []int
+	for i, err := range denied {
+		if batch.action != "create" {
+			results[i].ID = batch.items[i].ID
+		}
+		switch {
+		case err != nil:
+			results[i].Err = err
+		case batch.opts.Atomic:
+			results[i].Err = ErrRolledBack
+		case batch.action == "create":
+			allowed.todos = append(allowed.todos, batch.todos[i])
+			positions = append(positions, i)
+		default:
+			allowed.items = append(allowed.items, batch.items[i])
+			positions = append(positions, i)
+		}
+	}
+	if len(positions) == 0 {
+		return results, nil
+	}
+	ran, err := run(allowed)
+	if err != nil {
+		return nil, err
+	}
+	for j, i := range positions {
+		results[i] = ran[j]
+	}
+	return results, nil
+}
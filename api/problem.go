@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Mã lỗi ổn định trả về trong trường "code" của problem document, frontend dựa vào đây để rẽ nhánh.
+const (
+	CodeNotFound              = "not_found"
+	CodeTodoNotFound          = "todo_not_found"
+	CodeValidationFailed      = "validation_failed"
+	CodeConflict              = "conflict"
+	CodeServiceUnavailable    = "service_unavailable"
+	CodeInternal              = "internal_error"
+	CodeMethodNotAllowed      = "method_not_allowed"
+	CodeInvalidBody           = "invalid_request_body"
+	CodeMissingID             = "missing_id"
+	CodePreconditionFailed    = "precondition_failed"
+	CodePreconditionRequired  = "precondition_required"
+	CodeUnsupportedMediaType  = "unsupported_media_type"
+	CodePayloadTooLarge       = "payload_too_large"
+	CodeRolledBack            = "rolled_back"
+	CodeInvalidIdempotencyKey = "invalid_idempotency_key"
+	CodeIdempotencyInProgress = "idempotency_in_progress"
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"
+	CodeInvalidTransition     = "invalid_transition"
+	CodeProjectNotEmpty       = "project_not_empty"
+	CodeDefaultProject        = "default_project"
+	CodeBlocked               = "blocked_by_open_todos"
+	CodeDependencyCycle       = "dependency_cycle"
+	CodeNotTrashed            = "todo_not_trashed"
+	CodeInvalidActor          = "invalid_actor"
+	CodeUnknownUser           = "unknown_user"
+	CodeEmailTaken            = "email_taken"
+	CodeUnauthenticated       = "unauthenticated"
+	CodeInvalidToken          = "invalid_token"
+	CodeForbidden             = "forbidden"
+	CodeLastOwner             = "last_owner"
+	CodeInvalidTenant         = "invalid_tenant"
+	CodeTenantMismatch        = "tenant_mismatch"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem là response lỗi theo RFC 7807.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func newProblem(r *http.Request, status int, code, detail string) Problem {
+	return Problem{
+		Type:     "/problems/" + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   detail,
+		Instance: r.URL.Path,
+		Code:     code,
+	}
+}
+
+func writeProblemDocument(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	if err := json.NewEncoder(w).Encode(p); err != nil {
+		log.Println("Error encoding problem:", err)
+	}
+}
+
+// writeProblem trả về một problem document với status và code cho trước.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	writeProblemDocument(w, newProblem(r, status, code, detail))
+}
+
+// writeError ánh xạ lỗi từ TodoService sang problem document tương ứng.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	p := problemFor(r, err)
+	if p.Status == http.StatusServiceUnavailable {
+		w.Header().Set("Retry-After", "5")
+	}
+	writeProblemDocument(w, p)
+}
+
+// problemFor chọn problem document cho một lỗi mà không ghi response, dùng cho cả kết quả từng phần tử của bulk.
+// Lỗi không xác định được log lại và trả về 500 mà không lộ chi tiết nội bộ.
+func problemFor(r *http.Request, err error) Problem {
+	var notFound *NotFoundError
+	var invalid *ValidationError
+	var transition *TransitionError
+	var blocked *BlockedError
+	var forbidden *ForbiddenError
+	switch {
+	case errors.As(err, &forbidden):
+		return newProblem(r, http.StatusForbidden, CodeForbidden, forbidden.Error())
+	case errors.As(err, &notFound):
+		return newProblem(r, http.StatusNotFound, notFound.Resource+"_not_found", notFound.Error())
+	case errors.Is(err, ErrTodoNotFound):
+		return newProblem(r, http.StatusNotFound, CodeTodoNotFound, "todo not found")
+	case errors.Is(err, ErrNotFound):
+		return newProblem(r, http.StatusNotFound, CodeNotFound, "resource not found")
+	case errors.Is(err, ErrIdempotencyMismatch):
+		return newProblem(r, http.StatusUnprocessableEntity, CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request, generate a new key")
+	case errors.Is(err, ErrIdempotencyInProgress):
+		return newProblem(r, http.StatusConflict, CodeIdempotencyInProgress, "a request with this Idempotency-Key is still being processed, retry later")
+	case errors.As(err, &invalid):
+		p := newProblem(r, http.StatusUnprocessableEntity, CodeValidationFailed, "request contains invalid fields")
+		p.Errors = invalid.Fields
+		return p
+	case errors.Is(err, ErrValidation):
+		return newProblem(r, http.StatusUnprocessableEntity, CodeValidationFailed, "request contains invalid fields")
+	case errors.Is(err, ErrInvalidBody):
+		return newProblem(r, http.StatusBadRequest, CodeInvalidBody, err.Error())
+	case errors.Is(err, ErrPayloadTooLarge):
+		return newProblem(r, http.StatusRequestEntityTooLarge, CodePayloadTooLarge, err.Error())
+	case errors.Is(err, ErrPreconditionFailed):
+		return newProblem(r, http.StatusPreconditionFailed, CodePreconditionFailed, "the todo was modified since it was read, fetch it again and retry")
+	case errors.Is(err, ErrRolledBack):
+		return newProblem(r, http.StatusFailedDependency, CodeRolledBack, "not applied because another item of the atomic batch failed")
+	case errors.As(err, &transition):
+		return newProblem(r, http.StatusConflict, CodeInvalidTransition, transition.Error())
+	case errors.As(err, &blocked):
+		return newProblem(r, http.StatusConflict, CodeBlocked, blocked.Error())
+	case errors.Is(err, ErrDependencyCycle):
+		return newProblem(r, http.StatusConflict, CodeDependencyCycle, "the dependency would create a cycle")
+	case errors.Is(err, ErrProjectNotEmpty):
+		return newProblem(r, http.StatusConflict, CodeProjectNotEmpty, "the project still has todos, move them or delete and purge them first")
+	case errors.Is(err, ErrNotTrashed):
+		return newProblem(r, http.StatusConflict, CodeNotTrashed, "the todo is not in the trash, delete it first")
+	case errors.Is(err, ErrEmailTaken):
+		return newProblem(r, http.StatusConflict, CodeEmailTaken, "a user with this email already exists")
+	case errors.Is(err, ErrShareDefaultProject):
+		return newProblem(r, http.StatusConflict, CodeDefaultProject, "the default project cannot be shared")
+	case errors.Is(err, ErrLastOwner):
+		return newProblem(r, http.StatusConflict, CodeLastOwner, "the project must keep at least one owner")
+	case errors.Is(err, ErrDefaultProject):
+		return newProblem(r, http.StatusConflict, CodeDefaultProject, "the default project cannot be deleted")
+	case errors.Is(err, ErrConflict):
+		return newProblem(r, http.StatusConflict, CodeConflict, "request conflicts with the current state of the resource")
+	case errors.Is(err, ErrUnavailable):
+		log.Println("Service unavailable:", err)
+		return newProblem(r, http.StatusServiceUnavailable, CodeServiceUnavailable, "storage is temporarily unavailable, retry later")
+	default:
+		log.Println("Internal error:", err)
+		return newProblem(r, http.StatusInternalServerError, CodeInternal, "internal server error")
+	}
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method "+r.Method+" is not allowed")
+}
@@ -0,0 +1,615 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultProjectID là project mà migration chuyển các todo cũ vào và todo mới thuộc về khi không chỉ định project.
+// Mỗi tenant có project mặc định riêng với ID này, được tạo khi tenant dùng nó lần đầu; project này không xóa được.
+const DefaultProjectID = "default"
+
+const maxProjectNameLength = 100
+
+// Project gom các todo của một luồng công việc (db, api, qr, print...). OpenCount và DoneCount
+// là số todo chưa done và đã done của project, do server tính.
+type Project struct {
+	ID          string    `json:"id" example:"default"`
+	Name        string    `json:"name" example:"print"`
+	Description string    `json:"description" example:"Label printing workstream"`
+	OpenCount   int       `json:"open_count" example:"4"`
+	DoneCount   int       `json:"done_count" example:"7"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ProjectShare cho UserID vai trò Role trên mọi todo của project ProjectID, kể cả todo của người khác.
+// Project chưa chia sẻ với ai mở cho mọi người dùng; người tạo project trong request có người dùng là owner của nó.
+type ProjectShare struct {
+	ProjectID string    `json:"project_id"`
+	UserID    string    `json:"user_id"`
+	Role      Role      `json:"role" enums:"viewer,editor,owner" example:"viewer"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	// ErrProjectNotEmpty là lỗi khi xóa project vẫn còn todo.
+	ErrProjectNotEmpty = fmt.Errorf("project still has todos: %w", ErrConflict)
+	// ErrDefaultProject là lỗi khi xóa project mặc định.
+	ErrDefaultProject = fmt.Errorf("the default project cannot be deleted: %w", ErrConflict)
+	// ErrShareDefaultProject là lỗi khi chia sẻ project mặc định, project này luôn mở cho mọi người dùng.
+	ErrShareDefaultProject = fmt.Errorf("the default project cannot be shared: %w", ErrConflict)
+	// ErrLastOwner là lỗi khi bỏ chia sẻ hoặc hạ vai trò owner cuối cùng của một project đã chia sẻ.
+	ErrLastOwner = fmt.Errorf("the project must keep at least one owner: %w", ErrConflict)
+)
+
+func projectNotFound(id string) error {
+	return &NotFoundError{Resource: "project", ID: id}
+}
+
+// unknownProject là lỗi validate khi todo được gán vào project không tồn tại.
+func unknownProject(id string) error {
+	verr := &ValidationError{}
+	verr.Add("project_id", "unknown_project", fmt.Sprintf("project %s does not exist", id))
+	return verr
+}
+
+func validateProject(project Project) error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(project.Name) == "" {
+		verr.Add("name", "required", "name is required")
+	} else if utf8.RuneCountInString(project.Name) > maxProjectNameLength {
+		verr.Add("name", "too_long", fmt.Sprintf("name must be at most %d characters", maxProjectNameLength))
+	}
+	return verr.Err()
+}
+
+// ProjectRequest là payload của POST /v1/projects và PUT /v1/projects/{id}.
+type ProjectRequest struct {
+	Name        string `json:"name" example:"print"`
+	Description string `json:"description" example:"Label printing workstream"`
+}
+
+var projectRules = payloadRules{
+	fields: map[string]fieldRule{
+		"name":        {kind: "string", required: true, trim: true, maxLength: maxProjectNameLength},
+		"description": {kind: "string"},
+	},
+	rejected: map[string]FieldError{
+		"id":         {Code: "read_only", Message: "id is assigned by the server"},
+		"open_count": {Code: "read_only", Message: "open_count is computed by the server"},
+		"done_count": {Code: "read_only", Message: "done_count is computed by the server"},
+		"created_at": {Code: "read_only", Message: "created_at is set by the server"},
+		"updated_at": {Code: "read_only", Message: "updated_at is set by the server"},
+	},
+}
+
+func (r ProjectRequest) project() Project {
+	return Project{Name: r.Name, Description: r.Description}
+}
+
+// ensureDefaultProject tạo project mặc định của tenant trong ctx khi id là DefaultProjectID và tenant chưa có nó,
+// giống project mặc định mà backend memory tạo cho mỗi tenant mới.
+func (s *DbTodoService) ensureDefaultProject(ctx context.Context, id string) error {
+	if id != DefaultProjectID {
+		return nil
+	}
+	_, err := s.conn().Exec(ctx,
+		"INSERT INTO project (tenant_id, id, name, created_at, updated_at) VALUES ($1, $2, 'Default', $3, $3) ON CONFLICT (tenant_id, id) DO NOTHING",
+		tenantFrom(ctx), DefaultProjectID, time.Now().UTC().Truncate(time.Microsecond))
+	if err != nil {
+		return dbError("tạo project mặc định thất bại", err)
+	}
+	return nil
+}
+
+// checkProject kiểm tra project của todo tồn tại trong tenant của ctx. Khóa ngoại (tenant_id, project_id) của todo
+// cũng bắt project của tenant khác, nhưng lỗi ở đây là lỗi validate rõ ràng hơn.
+func (s *DbTodoService) checkProject(ctx context.Context, id string) error {
+	if err := s.ensureDefaultProject(ctx, id); err != nil {
+		return err
+	}
+	w := &sqlWhere{}
+	w.add("id = ?", id)
+	forTenant(ctx, w, "")
+	var exists bool
+	if err := s.conn().QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM project"+w.String()+")", w.args...).Scan(&exists); err != nil {
+		return dbError("kiểm tra sự tồn tại của project thất bại", err)
+	}
+	if !exists {
+		return unknownProject(id)
+	}
+	return nil
+}
+
+// projectGroupBy kết thúc câu truy vấn của projectSelect, sau điều kiện WHERE.
+const projectGroupBy = " GROUP BY p.id, p.name, p.description, p.created_at, p.updated_at"
+
+// projectSelect đọc project kèm số todo open/done mà người dùng trong ctx được thấy; tham số của nó được thêm vào w.
+func projectSelect(ctx context.Context, w *sqlWhere) string {
+	sql := "SELECT p.id, p.name, p.description, p.created_at, p.updated_at, " +
+		"COUNT(CASE WHEN NOT t.done THEN 1 END), COUNT(CASE WHEN t.done THEN 1 END) " +
+		"FROM project p LEFT JOIN todo t ON t.project_id = p.id AND t.deleted_at IS NULL AND " + visibleTo(ctx, w, "t.")
+	return sql
+}
+
+func scanProject(row pgx.Row, project *Project) error {
+	return row.Scan(&project.ID, &project.Name, &project.Description, &project.CreatedAt, &project.UpdatedAt,
+		&project.OpenCount, &project.DoneCount)
+}
+
+func (s *DbTodoService) ListProjects(ctx context.Context) ([]Project, error) {
+	if err := s.ensureDefaultProject(ctx, DefaultProjectID); err != nil {
+		return nil, err
+	}
+	w := &sqlWhere{}
+	sql := projectSelect(ctx, w)
+	forTenant(ctx, w, "p.")
+	rows, err := s.conn().Query(ctx, sql+w.String()+projectGroupBy+" ORDER BY p.name, p.id", w.args...)
+	if err != nil {
+		return nil, dbError("truy vấn project thất bại", err)
+	}
+	defer rows.Close()
+
+	projects := []Project{}
+	for rows.Next() {
+		var project Project
+		if err := scanProject(rows, &project); err != nil {
+			return nil, dbError("scan project thất bại", err)
+		}
+		projects = append(projects, project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc project", err)
+	}
+	return projects, nil
+}
+
+func (s *DbTodoService) GetProject(ctx context.Context, id string) (*Project, error) {
+	if err := s.ensureDefaultProject(ctx, id); err != nil {
+		return nil, err
+	}
+	var project Project
+	w := &sqlWhere{}
+	sql := projectSelect(ctx, w)
+	w.add("p.id = ?", id)
+	forTenant(ctx, w, "p.")
+	err := scanProject(s.conn().QueryRow(ctx, sql+w.String()+projectGroupBy, w.args...), &project)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, projectNotFound(id)
+		}
+		return nil, dbError("truy vấn project thất bại", err)
+	}
+	return &project, nil
+}
+
+func (s *DbTodoService) CreateProject(ctx context.Context, project Project) (*Project, error) {
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	project.ID = generateNewID()
+	project.Name = strings.TrimSpace(project.Name)
+	project.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	project.UpdatedAt = project.CreatedAt
+	project.OpenCount, project.DoneCount = 0, 0
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		_, err := svc.conn().Exec(ctx, "INSERT INTO project (id, name, description, created_at, updated_at, tenant_id) VALUES ($1, $2, $3, $4, $5, $6)",
+			project.ID, project.Name, project.Description, project.CreatedAt, project.UpdatedAt, tenantFrom(ctx))
+		if err != nil {
+			return dbError("thêm project thất bại", err)
+		}
+		if userID := userFrom(ctx); userID != "" {
+			return svc.insertProjectShare(ctx, ProjectShare{ProjectID: project.ID, UserID: userID, Role: RoleOwner, CreatedAt: project.CreatedAt})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s *DbTodoService) UpdateProject(ctx context.Context, id string, project Project) (*Project, error) {
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	if err := s.ensureDefaultProject(ctx, id); err != nil {
+		return nil, err
+	}
+	tag, err := s.conn().Exec(ctx, "UPDATE project SET name = $2, description = $3, updated_at = $4 WHERE id = $1 AND tenant_id = $5",
+		id, strings.TrimSpace(project.Name), project.Description, time.Now().UTC().Truncate(time.Microsecond), tenantFrom(ctx))
+	if err != nil {
+		return nil, dbError("cập nhật project thất bại", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, projectNotFound(id)
+	}
+	return s.GetProject(ctx, id)
+}
+
+// DeleteProject chỉ xóa project rỗng; todo phải được chuyển đi hoặc xóa trước, todo trong thùng rác phải được xóa hẳn.
+func (s *DbTodoService) DeleteProject(ctx context.Context, id string) error {
+	if id == DefaultProjectID {
+		return ErrDefaultProject
+	}
+	tag, err := s.conn().Exec(ctx,
+		"DELETE FROM project WHERE id = $1 AND tenant_id = $2 AND NOT EXISTS (SELECT 1 FROM todo WHERE project_id = $1 AND tenant_id = $2)",
+		id, tenantFrom(ctx))
+	if err != nil {
+		return dbError("xóa project thất bại", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := s.GetProject(ctx, id); err != nil {
+			return err
+		}
+		return ErrProjectNotEmpty
+	}
+	return nil
+}
+
+// ProjectRole đọc vai trò của người dùng trong ctx trên project.
+func (s *DbTodoService) ProjectRole(ctx context.Context, id string) (Role, error) {
+	if err := s.ensureDefaultProject(ctx, id); err != nil {
+		return roleNone, err
+	}
+	userID := userFrom(ctx)
+	var role Role
+	var shared bool
+	err := s.conn().QueryRow(ctx,
+		"SELECT COALESCE((SELECT role FROM project_share WHERE project_id = p.id AND user_id = $2 AND tenant_id = $3), ''), "+
+			"EXISTS (SELECT 1 FROM project_share WHERE project_id = p.id AND tenant_id = $3) "+
+			"FROM project p WHERE p.id = $1 AND p.tenant_id = $3",
+		id, userID, tenantFrom(ctx)).
+		Scan(&role, &shared)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return roleNone, projectNotFound(id)
+	}
+	if err != nil {
+		return roleNone, dbError("truy vấn vai trò thất bại", err)
+	}
+	if userID == "" || !shared {
+		return RoleOwner, nil
+	}
+	return role, nil
+}
+
+func (s *DbTodoService) ListProjectShares(ctx context.Context, projectID string) ([]ProjectShare, error) {
+	if _, err := s.GetProject(ctx, projectID); err != nil {
+		return nil, err
+	}
+	rows, err := s.conn().Query(ctx,
+		"SELECT project_id, user_id, role, created_at FROM project_share WHERE project_id = $1 AND tenant_id = $2 ORDER BY user_id",
+		projectID, tenantFrom(ctx))
+	if err != nil {
+		return nil, dbError("truy vấn chia sẻ project thất bại", err)
+	}
+	defer rows.Close()
+
+	shares := []ProjectShare{}
+	for rows.Next() {
+		var share ProjectShare
+		if err := rows.Scan(&share.ProjectID, &share.UserID, &share.Role, &share.CreatedAt); err != nil {
+			return nil, dbError("scan chia sẻ project thất bại", err)
+		}
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc chia sẻ project", err)
+	}
+	return shares, nil
+}
+
+func (s *DbTodoService) insertProjectShare(ctx context.Context, share ProjectShare) error {
+	_, err := s.conn().Exec(ctx,
+		"INSERT INTO project_share (project_id, user_id, role, created_at, tenant_id) VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (tenant_id, project_id, user_id) DO UPDATE SET role = EXCLUDED.role",
+		share.ProjectID, share.UserID, share.Role, share.CreatedAt, tenantFrom(ctx))
+	if err != nil {
+		return dbError("chia sẻ project thất bại", err)
+	}
+	return nil
+}
+
+// checkOwners trả về ErrLastOwner nếu project đã chia sẻ nhưng không còn owner nào.
+func (s *DbTodoService) checkOwners(ctx context.Context, projectID string) error {
+	var orphaned bool
+	err := s.conn().QueryRow(ctx,
+		"SELECT EXISTS (SELECT 1 FROM project_share WHERE project_id = $1 AND tenant_id = $2) "+
+			"AND NOT EXISTS (SELECT 1 FROM project_share WHERE project_id = $1 AND tenant_id = $2 AND role = 'owner')",
+		projectID, tenantFrom(ctx)).Scan(&orphaned)
+	if err != nil {
+		return dbError("kiểm tra owner của project thất bại", err)
+	}
+	if orphaned {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// ShareProject chia sẻ project với userID; chia sẻ lại lần nữa chỉ đổi vai trò. Người chia sẻ một project chưa
+// chia sẻ với ai trở thành owner của nó, để project không bị khóa với chính họ.
+func (s *DbTodoService) ShareProject(ctx context.Context, projectID, userID string, role Role) (*ProjectShare, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	if projectID == DefaultProjectID {
+		return nil, ErrShareDefaultProject
+	}
+	share := ProjectShare{ProjectID: projectID, UserID: userID, Role: role, CreatedAt: time.Now().UTC().Truncate(time.Microsecond)}
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		if _, err := svc.GetProject(ctx, projectID); err != nil {
+			return err
+		}
+		if _, err := svc.GetUser(ctx, userID); err != nil {
+			return err
+		}
+		if sharer := userFrom(ctx); sharer != "" && sharer != userID {
+			_, err := svc.conn().Exec(ctx,
+				"INSERT INTO project_share (project_id, user_id, role, created_at, tenant_id) SELECT $1, $2, 'owner', $3, $4 "+
+					"WHERE NOT EXISTS (SELECT 1 FROM project_share WHERE project_id = $1 AND tenant_id = $4)", projectID, sharer, share.CreatedAt, tenantFrom(ctx))
+			if err != nil {
+				return dbError("chia sẻ project thất bại", err)
+			}
+		}
+		if err := svc.insertProjectShare(ctx, share); err != nil {
+			return err
+		}
+		err := svc.conn().QueryRow(ctx, "SELECT created_at FROM project_share WHERE project_id = $1 AND user_id = $2 AND tenant_id = $3",
+			projectID, userID, tenantFrom(ctx)).Scan(&share.CreatedAt)
+		if err != nil {
+			return dbError("truy vấn chia sẻ project thất bại", err)
+		}
+		return svc.checkOwners(ctx, projectID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (s *DbTodoService) UnshareProject(ctx context.Context, projectID, userID string) error {
+	return s.inTx(ctx, func(svc *DbTodoService) error {
+		if _, err := svc.GetProject(ctx, projectID); err != nil {
+			return err
+		}
+		tag, err := svc.conn().Exec(ctx, "DELETE FROM project_share WHERE project_id = $1 AND user_id = $2 AND tenant_id = $3",
+			projectID, userID, tenantFrom(ctx))
+		if err != nil {
+			return dbError("bỏ chia sẻ project thất bại", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return shareNotFound(userID)
+		}
+		return svc.checkOwners(ctx, projectID)
+	})
+}
+
+// countedProjectLocked trả về project kèm số todo open/done người dùng trong ctx được thấy; caller phải giữ s.mu.
+func (s *MemoryTodoService) countedProjectLocked(ctx context.Context, project Project) Project {
+	project.OpenCount, project.DoneCount = 0, 0
+	for _, todo := range s.todos {
+		if todo.ProjectID != project.ID || !s.visibleLocked(ctx, todo) {
+			continue
+		}
+		if todo.Done {
+			project.DoneCount++
+		} else {
+			project.OpenCount++
+		}
+	}
+	return project
+}
+
+// checkProjectLocked kiểm tra project của todo tồn tại; caller phải giữ s.mu.
+func (s *MemoryTodoService) checkProjectLocked(id string) error {
+	if _, ok := s.projects[id]; !ok {
+		return unknownProject(id)
+	}
+	return nil
+}
+
+func (s *MemoryTodoService) ListProjects(ctx context.Context) ([]Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	projects := make([]Project, 0, len(s.projects))
+	for _, project := range s.projects {
+		projects = append(projects, s.countedProjectLocked(ctx, project))
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return projects[i].ID < projects[j].ID
+	})
+	return projects, nil
+}
+
+func (s *MemoryTodoService) GetProject(ctx context.Context, id string) (*Project, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	project, ok := s.projects[id]
+	if !ok {
+		return nil, projectNotFound(id)
+	}
+	project = s.countedProjectLocked(ctx, project)
+	return &project, nil
+}
+
+func (s *MemoryTodoService) CreateProject(ctx context.Context, project Project) (*Project, error) {
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	project.ID = generateNewID()
+	project.Name = strings.TrimSpace(project.Name)
+	project.CreatedAt = time.Now()
+	project.UpdatedAt = project.CreatedAt
+	project.OpenCount, project.DoneCount = 0, 0
+	s.projects[project.ID] = project
+	if userID := userFrom(ctx); userID != "" {
+		s.projectShares[project.ID] = []ProjectShare{{ProjectID: project.ID, UserID: userID, Role: RoleOwner, CreatedAt: project.CreatedAt}}
+	}
+	return &project, nil
+}
+
+func (s *MemoryTodoService) UpdateProject(ctx context.Context, id string, project Project) (*Project, error) {
+	if err := validateProject(project); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.projects[id]
+	if !ok {
+		return nil, projectNotFound(id)
+	}
+	current.Name = strings.TrimSpace(project.Name)
+	current.Description = project.Description
+	current.UpdatedAt = time.Now()
+	s.projects[id] = current
+	current = s.countedProjectLocked(ctx, current)
+	return &current, nil
+}
+
+func (s *MemoryTodoService) DeleteProject(ctx context.Context, id string) error {
+	if id == DefaultProjectID {
+		return ErrDefaultProject
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[id]; !ok {
+		return projectNotFound(id)
+	}
+	// Todo của mọi người dùng đều giữ project lại, kể cả todo trong thùng rác.
+	for _, todos := range []map[string]Todo{s.todos, s.trash} {
+		for _, todo := range todos {
+			if todo.ProjectID == id {
+				return ErrProjectNotEmpty
+			}
+		}
+	}
+	delete(s.projects, id)
+	delete(s.projectShares, id)
+	return nil
+}
+
+func (s *MemoryTodoService) ProjectRole(ctx context.Context, id string) (Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.projects[id]; !ok {
+		return roleNone, projectNotFound(id)
+	}
+	userID := userFrom(ctx)
+	shares := s.projectShares[id]
+	if userID == "" || len(shares) == 0 {
+		return RoleOwner, nil
+	}
+	for _, share := range shares {
+		if share.UserID == userID {
+			return share.Role, nil
+		}
+	}
+	return roleNone, nil
+}
+
+func (s *MemoryTodoService) ListProjectShares(ctx context.Context, projectID string) ([]ProjectShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.projects[projectID]; !ok {
+		return nil, projectNotFound(projectID)
+	}
+	return append([]ProjectShare{}, s.projectShares[projectID]...), nil
+}
+
+// putProjectShareLocked thêm hoặc đổi vai trò của một lần chia sẻ project, giữ thời điểm chia sẻ đầu tiên;
+// caller phải giữ s.mu.
+func (s *MemoryTodoService) putProjectShareLocked(share ProjectShare) ProjectShare {
+	shares := make([]ProjectShare, 0, len(s.projectShares[share.ProjectID])+1)
+	for _, existing := range s.projectShares[share.ProjectID] {
+		if existing.UserID == share.UserID {
+			share.CreatedAt = existing.CreatedAt
+			continue
+		}
+		shares = append(shares, existing)
+	}
+	shares = append(shares, share)
+	sort.Slice(shares, func(i, j int) bool { return shares[i].UserID < shares[j].UserID })
+	s.projectShares[share.ProjectID] = shares
+	return share
+}
+
+// ownerless cho biết shares là danh sách chia sẻ không rỗng nhưng không còn owner.
+func ownerless(shares []ProjectShare) bool {
+	for _, share := range shares {
+		if share.Role == RoleOwner {
+			return false
+		}
+	}
+	return len(shares) > 0
+}
+
+func (s *MemoryTodoService) ShareProject(ctx context.Context, projectID, userID string, role Role) (*ProjectShare, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	if projectID == DefaultProjectID {
+		return nil, ErrShareDefaultProject
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[projectID]; !ok {
+		return nil, projectNotFound(projectID)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return nil, userNotFound(userID)
+	}
+	before := s.projectShares[projectID]
+	now := time.Now()
+	if sharer := userFrom(ctx); sharer != "" && sharer != userID && len(before) == 0 {
+		s.putProjectShareLocked(ProjectShare{ProjectID: projectID, UserID: sharer, Role: RoleOwner, CreatedAt: now})
+	}
+	share := s.putProjectShareLocked(ProjectShare{ProjectID: projectID, UserID: userID, Role: role, CreatedAt: now})
+	if ownerless(s.projectShares[projectID]) {
+		s.projectShares[projectID] = before
+		return nil, ErrLastOwner
+	}
+	return &share, nil
+}
+
+func (s *MemoryTodoService) UnshareProject(ctx context.Context, projectID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.projects[projectID]; !ok {
+		return projectNotFound(projectID)
+	}
+	shares := make([]ProjectShare, 0, len(s.projectShares[projectID]))
+	for _, share := range s.projectShares[projectID] {
+		if share.UserID != userID {
+			shares = append(shares, share)
+		}
+	}
+	if len(shares) == len(s.projectShares[projectID]) {
+		return shareNotFound(userID)
+	}
+	if ownerless(shares) {
+		return ErrLastOwner
+	}
+	s.projectShares[projectID] = shares
+	return nil
+}
@@ -0,0 +1,698 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// Các trường có thể dùng để sắp xếp danh sách todo.
+const (
+	SortCreatedAt = "created_at"
+	SortDoneAt    = "done_at"
+	SortTitle     = "title"
+	SortDueAt     = "due_at"
+	SortPriority  = "priority"
+	SortDeletedAt = "deleted_at"
+	// SortUrgency xếp việc gấp nhất lên đầu: priority cao trước, cùng priority thì hạn gần trước.
+	SortUrgency = "urgency"
+)
+
+const (
+	OrderAsc  = "asc"
+	OrderDesc = "desc"
+)
+
+// Cách lọc theo nhiều tag: todo có ít nhất một (any) hoặc có tất cả (all) các tag.
+const (
+	TagMatchAny = "any"
+	TagMatchAll = "all"
+)
+
+// TodoQuery là tham số lọc, sắp xếp và phân trang cho GetAllTodo.
+// Giá trị rỗng nghĩa là không lọc; mặc định sắp xếp theo created_at giảm dần (deleted_at với thùng rác).
+type TodoQuery struct {
+	Limit         int
+	Cursor        string
+	ProjectID     string
+	Done          *bool
+	Statuses      []Status
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	DoneAfter     *time.Time
+	DoneBefore    *time.Time
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	Overdue       *bool
+	Priorities    []Priority
+	MinPriority   *Priority
+	Tags          []string
+	TagMatch      string
+	// Ready lọc todo chưa done theo blocker: true là không còn blocker chưa done, false là còn.
+	Ready         *bool
+	TitleContains string
+	Sort          string
+	Order         string
+	// Trashed liệt kê các todo trong thùng rác thay vì các todo đang dùng.
+	Trashed bool
+
+	// now là thời điểm dùng cho bộ lọc overdue, được gán trong normalize.
+	now time.Time
+}
+
+// TodoPage là một trang kết quả. Cursor là chuỗi opaque, client chỉ cần gửi lại nguyên văn.
+type TodoPage struct {
+	Items      []Todo `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+type keyKind int
+
+const (
+	keyString keyKind = iota
+	keyTime
+	keyInt
+)
+
+// sortKey mô tả một cột trong thứ tự sắp xếp: biểu thức SQL cho DbTodoService
+// và hàm lấy giá trị tương ứng cho MemoryTodoService và cursor.
+// flip đảo chiều của cột so với order của query, cho các thứ tự sắp xếp nhiều cột như urgency.
+type sortKey struct {
+	column string
+	kind   keyKind
+	desc   bool
+	flip   bool
+	value  func(t Todo) interface{}
+}
+
+// pageCursor là nội dung đã giải mã của cursor: giá trị các sort key của phần tử biên.
+type pageCursor struct {
+	Sort   string            `json:"s"`
+	Order  string            `json:"o"`
+	Values []json.RawMessage `json:"v"`
+	Before bool              `json:"b,omitempty"`
+
+	values []interface{}
+}
+
+var (
+	zeroTime = time.Time{}
+	// farFuture thay cho due_at rỗng khi sắp xếp, để todo không có hạn đứng sau mọi todo có hạn.
+	farFuture = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// normalize điền giá trị mặc định, kiểm tra tham số và giải mã cursor (nếu có).
+func (q TodoQuery) normalize() (TodoQuery, *pageCursor, error) {
+	verr := &ValidationError{}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = defaultPageLimit
+	case q.Limit < 0:
+		verr.Add("limit", "invalid", "limit must be positive")
+	case q.Limit > maxPageLimit:
+		q.Limit = maxPageLimit
+	}
+
+	if q.Sort == "" {
+		q.Sort = SortCreatedAt
+		if q.Trashed {
+			q.Sort = SortDeletedAt
+		}
+	}
+	if q.Order == "" {
+		q.Order = OrderDesc
+		if q.Sort == SortDueAt {
+			// Hạn gần nhất trước.
+			q.Order = OrderAsc
+		}
+	}
+	if q.now.IsZero() {
+		q.now = time.Now().UTC()
+	}
+	if len(q.Tags) > 0 {
+		q.Tags = normalizeTags(q.Tags)
+	}
+	if q.TagMatch == "" {
+		q.TagMatch = TagMatchAny
+	}
+	if q.TagMatch != TagMatchAny && q.TagMatch != TagMatchAll {
+		verr.Add("tag_match", "invalid", "tag_match must be any or all")
+	}
+	if !isSortField(q.Sort) {
+		verr.Add("sort", "invalid", "sort must be one of "+strings.Join(sortFields(), ", "))
+	}
+	if q.Order != OrderAsc && q.Order != OrderDesc {
+		verr.Add("order", "invalid", "order must be asc or desc")
+	}
+	if err := verr.Err(); err != nil {
+		return q, nil, err
+	}
+
+	if q.Cursor == "" {
+		return q, nil, nil
+	}
+	cur, err := decodeCursor(q.Cursor, q.sortKeys())
+	if err != nil || cur.Sort != q.Sort || cur.Order != q.Order {
+		verr.Add("cursor", "invalid", "cursor is malformed or does not match the requested sort")
+		return q, nil, verr
+	}
+	return q, cur, nil
+}
+
+var (
+	dueAtKey = sortKey{column: "COALESCE(due_at, TIMESTAMP '9999-12-31 00:00:00')", kind: keyTime, value: func(t Todo) interface{} {
+		if t.DueAt == nil {
+			return farFuture
+		}
+		return *t.DueAt
+	}}
+	priorityKey = sortKey{column: "priority", kind: keyInt, value: func(t Todo) interface{} { return int(t.Priority) }}
+)
+
+var sortKeyColumns = map[string][]sortKey{
+	SortCreatedAt: {{column: "created_at", kind: keyTime, value: func(t Todo) interface{} { return t.CreatedAt }}},
+	SortDoneAt: {{column: "COALESCE(done_at, TIMESTAMP '0001-01-01 00:00:00')", kind: keyTime, value: func(t Todo) interface{} {
+		if t.DoneAt == nil {
+			return zeroTime
+		}
+		return *t.DoneAt
+	}}},
+	SortDeletedAt: {{column: "COALESCE(deleted_at, TIMESTAMP '0001-01-01 00:00:00')", kind: keyTime, value: func(t Todo) interface{} {
+		if t.DeletedAt == nil {
+			return zeroTime
+		}
+		return *t.DeletedAt
+	}}},
+	SortTitle:    {{column: "title", kind: keyString, value: func(t Todo) interface{} { return t.Title }}},
+	SortDueAt:    {dueAtKey},
+	SortPriority: {priorityKey},
+	SortUrgency:  {priorityKey, {column: dueAtKey.column, kind: keyTime, flip: true, value: dueAtKey.value}},
+}
+
+func isSortField(field string) bool {
+	_, ok := sortKeyColumns[field]
+	return ok
+}
+
+func sortFields() []string {
+	fields := make([]string, 0, len(sortKeyColumns))
+	for field := range sortKeyColumns {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// sortKeys trả về thứ tự sắp xếp đầy đủ, luôn kết thúc bằng id để thứ tự là duy nhất.
+func (q TodoQuery) sortKeys() []sortKey {
+	desc := q.Order == OrderDesc
+	var keys []sortKey
+	for _, key := range sortKeyColumns[q.Sort] {
+		key.desc = desc != key.flip
+		keys = append(keys, key)
+	}
+	return append(keys, sortKey{column: "id", kind: keyString, desc: desc, value: func(t Todo) interface{} { return t.ID }})
+}
+
+func encodeCursor(q TodoQuery, todo Todo, before bool) string {
+	cur := pageCursor{Sort: q.Sort, Order: q.Order, Before: before}
+	for _, key := range q.sortKeys() {
+		raw, _ := json.Marshal(key.value(todo))
+		cur.Values = append(cur.Values, raw)
+	}
+	data, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, keys []sortKey) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cur pageCursor
+	if err := json.Unmarshal(data, &cur); err != nil {
+		return nil, err
+	}
+	if len(cur.Values) != len(keys) {
+		return nil, fmt.Errorf("cursor has %d values, want %d", len(cur.Values), len(keys))
+	}
+	for i, key := range keys {
+		var v interface{}
+		switch key.kind {
+		case keyTime:
+			var t time.Time
+			err = json.Unmarshal(cur.Values[i], &t)
+			v = t.UTC()
+		case keyInt:
+			var n int
+			err = json.Unmarshal(cur.Values[i], &n)
+			v = n
+		default:
+			var str string
+			err = json.Unmarshal(cur.Values[i], &str)
+			v = str
+		}
+		if err != nil {
+			return nil, err
+		}
+		cur.values = append(cur.values, v)
+	}
+	return &cur, nil
+}
+
+func compareKeyValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case time.Time:
+		bv := b.(time.Time)
+		switch {
+		case av.Before(bv):
+			return -1
+		case av.After(bv):
+			return 1
+		}
+		return 0
+	case int:
+		bv := b.(int)
+		switch {
+		case av < bv:
+			return -1
+		case av > bv:
+			return 1
+		}
+		return 0
+	default:
+		return strings.Compare(a.(string), b.(string))
+	}
+}
+
+// compareByKeys so sánh hai todo theo thứ tự sắp xếp, đã tính chiều tăng/giảm.
+func compareByKeys(keys []sortKey, a, b Todo) int {
+	for _, key := range keys {
+		if c := compareKeyValues(key.value(a), key.value(b)); c != 0 {
+			if key.desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// compareToCursor so sánh một todo với vị trí của cursor theo thứ tự sắp xếp.
+func compareToCursor(keys []sortKey, todo Todo, cur *pageCursor) int {
+	for i, key := range keys {
+		if c := compareKeyValues(key.value(todo), cur.values[i]); c != 0 {
+			if key.desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return 0
+}
+
+// matches áp dụng các bộ lọc của query cho một todo (dùng cho MemoryTodoService).
+func (q TodoQuery) matches(todo Todo) bool {
+	if q.ProjectID != "" && todo.ProjectID != q.ProjectID {
+		return false
+	}
+	if q.Done != nil && todo.Done != *q.Done {
+		return false
+	}
+	if len(q.Statuses) > 0 && !containsStatus(q.Statuses, todo.Status) {
+		return false
+	}
+	if q.CreatedAfter != nil && todo.CreatedAt.Before(*q.CreatedAfter) {
+		return false
+	}
+	if q.CreatedBefore != nil && !todo.CreatedAt.Before(*q.CreatedBefore) {
+		return false
+	}
+	if q.DoneAfter != nil && (todo.DoneAt == nil || todo.DoneAt.Before(*q.DoneAfter)) {
+		return false
+	}
+	if q.DoneBefore != nil && (todo.DoneAt == nil || !todo.DoneAt.Before(*q.DoneBefore)) {
+		return false
+	}
+	if q.DueAfter != nil && (todo.DueAt == nil || todo.DueAt.Before(*q.DueAfter)) {
+		return false
+	}
+	if q.DueBefore != nil && (todo.DueAt == nil || !todo.DueAt.Before(*q.DueBefore)) {
+		return false
+	}
+	if q.Overdue != nil && isOverdue(todo, q.now) != *q.Overdue {
+		return false
+	}
+	if len(q.Priorities) > 0 && !containsPriority(q.Priorities, todo.Priority) {
+		return false
+	}
+	if q.MinPriority != nil && todo.Priority < *q.MinPriority {
+		return false
+	}
+	if len(q.Tags) > 0 && !q.matchesTags(todo.Tags) {
+		return false
+	}
+	if q.TitleContains != "" && !strings.Contains(strings.ToLower(todo.Title), strings.ToLower(q.TitleContains)) {
+		return false
+	}
+	return true
+}
+
+func (q TodoQuery) matchesTags(tags []string) bool {
+	found := 0
+	for _, tag := range q.Tags {
+		if containsString(tags, tag) {
+			found++
+		}
+	}
+	if q.TagMatch == TagMatchAll {
+		return found == len(q.Tags)
+	}
+	return found > 0
+}
+
+// where thêm các điều kiện lọc của query vào câu SQL (dùng cho DbTodoService); truy vấn con chỉ đọc dòng của tenant
+// trong ctx.
+func (q TodoQuery) where(ctx context.Context, w *sqlWhere) {
+	if q.Trashed {
+		w.add("deleted_at IS NOT NULL")
+	} else {
+		w.add("deleted_at IS NULL")
+	}
+	if q.ProjectID != "" {
+		w.add("project_id = ?", q.ProjectID)
+	}
+	if q.Done != nil {
+		w.add("done = ?", *q.Done)
+	}
+	if len(q.Statuses) > 0 {
+		statuses := make([]string, 0, len(q.Statuses))
+		for _, s := range q.Statuses {
+			statuses = append(statuses, string(s))
+		}
+		w.add("status = ANY(?)", statuses)
+	}
+	if q.CreatedAfter != nil {
+		w.add("created_at >= ?", q.CreatedAfter.UTC())
+	}
+	if q.CreatedBefore != nil {
+		w.add("created_at < ?", q.CreatedBefore.UTC())
+	}
+	if q.DoneAfter != nil {
+		w.add("done_at >= ?", q.DoneAfter.UTC())
+	}
+	if q.DoneBefore != nil {
+		w.add("done_at < ?", q.DoneBefore.UTC())
+	}
+	if q.DueAfter != nil {
+		w.add("due_at >= ?", q.DueAfter.UTC())
+	}
+	if q.DueBefore != nil {
+		w.add("due_at < ?", q.DueBefore.UTC())
+	}
+	if q.Overdue != nil {
+		// Giống isOverdue: có hạn trong quá khứ, chưa done và chưa bị hủy.
+		overdue := "(due_at IS NOT NULL AND due_at < ? AND NOT done AND status <> ?)"
+		if !*q.Overdue {
+			overdue = "NOT " + overdue
+		}
+		w.add(overdue, q.now, string(StatusCancelled))
+	}
+	if len(q.Priorities) > 0 {
+		priorities := make([]int, 0, len(q.Priorities))
+		for _, p := range q.Priorities {
+			priorities = append(priorities, int(p))
+		}
+		w.add("priority = ANY(?)", priorities)
+	}
+	if q.MinPriority != nil {
+		w.add("priority >= ?", int(*q.MinPriority))
+	}
+	if len(q.Tags) > 0 {
+		// Tên tag trong q.Tags đã được bỏ trùng (normalize), nên đếm số tag khớp là đủ cho all.
+		tenant := w.arg(tenantFrom(ctx))
+		tagged := "id IN (SELECT tt.todo_id FROM todo_tag tt JOIN tag g ON g.id = tt.tag_id AND g.tenant_id = " + tenant +
+			" WHERE tt.tenant_id = " + tenant + " AND g.name = ANY(?)"
+		if q.TagMatch == TagMatchAll {
+			w.add(tagged+" GROUP BY tt.todo_id HAVING COUNT(*) = ?)", q.Tags, len(q.Tags))
+		} else {
+			w.add(tagged+")", q.Tags)
+		}
+	}
+	if q.Ready != nil {
+		tenant := w.arg(tenantFrom(ctx))
+		blocked := "EXISTS (SELECT 1 FROM todo_dependency d JOIN todo b ON b.id = d.blocker_id AND b.tenant_id = " + tenant +
+			" WHERE d.tenant_id = " + tenant + " AND d.todo_id = todo.id AND NOT b.done)"
+		if *q.Ready {
+			blocked = "NOT " + blocked
+		}
+		w.add("NOT done AND " + blocked)
+	}
+	if q.TitleContains != "" {
+		w.add("title ILIKE '%' || ? || '%'", escapeLike(q.TitleContains))
+	}
+}
+
+// keyset thêm điều kiện "sau cursor" (hoặc "trước cursor") theo thứ tự sắp xếp.
+// Với k1..kn, điều kiện là OR_i (k1 = v1 AND ... AND k(i-1) = v(i-1) AND ki >/< vi).
+func keyset(w *sqlWhere, keys []sortKey, cur *pageCursor) {
+	var ors []string
+	for i, key := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, keys[j].column+" = "+w.arg(cur.values[j]))
+		}
+		op := ">"
+		if key.desc != cur.Before {
+			op = "<"
+		}
+		ands = append(ands, key.column+" "+op+" "+w.arg(cur.values[i]))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	w.conds = append(w.conds, "("+strings.Join(ors, " OR ")+")")
+}
+
+// orderBy trả về mệnh đề ORDER BY; khi đi lùi (Before) thì đảo chiều để lấy các phần tử gần cursor nhất.
+func orderBy(keys []sortKey, reverse bool) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		dir := "ASC"
+		if key.desc != reverse {
+			dir = "DESC"
+		}
+		parts = append(parts, key.column+" "+dir)
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// newTodoPage tạo trang kết quả từ tối đa limit+1 phần tử đã lấy theo chiều duyệt.
+func newTodoPage(todos []Todo, q TodoQuery, cur *pageCursor) *TodoPage {
+	hasMore := len(todos) > q.Limit
+	if hasMore {
+		todos = todos[:q.Limit]
+	}
+	backward := cur != nil && cur.Before
+	if backward {
+		for i, j := 0, len(todos)-1; i < j; i, j = i+1, j-1 {
+			todos[i], todos[j] = todos[j], todos[i]
+		}
+	}
+
+	page := &TodoPage{Items: todos}
+	if len(todos) == 0 {
+		return page
+	}
+	if (!backward && hasMore) || backward {
+		page.NextCursor = encodeCursor(q, todos[len(todos)-1], false)
+	}
+	if (!backward && cur != nil) || (backward && hasMore) {
+		page.PrevCursor = encodeCursor(q, todos[0], true)
+	}
+	return page
+}
+
+// paginate lọc, sắp xếp và cắt trang trên dữ liệu trong bộ nhớ.
+func paginate(todos []Todo, q TodoQuery) (*TodoPage, error) {
+	q, cur, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+	keys := q.sortKeys()
+
+	filtered := make([]Todo, 0, len(todos))
+	for _, todo := range todos {
+		if !q.matches(todo) {
+			continue
+		}
+		if cur != nil {
+			c := compareToCursor(keys, todo, cur)
+			if (!cur.Before && c <= 0) || (cur.Before && c >= 0) {
+				continue
+			}
+		}
+		filtered = append(filtered, todo)
+	}
+
+	backward := cur != nil && cur.Before
+	sort.Slice(filtered, func(i, j int) bool {
+		c := compareByKeys(keys, filtered[i], filtered[j])
+		if backward {
+			return c > 0
+		}
+		return c < 0
+	})
+	if len(filtered) > q.Limit+1 {
+		filtered = filtered[:q.Limit+1]
+	}
+	return newTodoPage(filtered, q, cur), nil
+}
+
+func containsStatus(statuses []Status, status Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func containsPriority(priorities []Priority, priority Priority) bool {
+	for _, p := range priorities {
+		if p == priority {
+			return true
+		}
+	}
+	return false
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// parseTodoQuery đọc tham số lọc/sắp xếp/phân trang từ query string,
+// gom tất cả tham số sai vào một ValidationError.
+func parseTodoQuery(r *http.Request) (TodoQuery, error) {
+	values := r.URL.Query()
+	verr := &ValidationError{}
+	q := TodoQuery{
+		Cursor:        values.Get("cursor"),
+		ProjectID:     values.Get("project_id"),
+		TitleContains: values.Get("title"),
+		Sort:          values.Get("sort"),
+		Order:         strings.ToLower(values.Get("order")),
+	}
+
+	if v := values.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			verr.Add("limit", "invalid", "limit must be a positive integer")
+		}
+		q.Limit = n
+	}
+	if v := values.Get("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			verr.Add("done", "invalid", "done must be true or false")
+		}
+		q.Done = &done
+	}
+	// status có thể lặp lại hoặc phân tách bằng dấu phẩy: ?status=backlog,in_progress
+	for _, v := range values["status"] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				q.Statuses = append(q.Statuses, Status(s))
+			}
+		}
+	}
+	// tag giống status: ?tag=frontend,qr; tag_match=all yêu cầu todo có đủ mọi tag.
+	for _, v := range values["tag"] {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				q.Tags = append(q.Tags, name)
+			}
+		}
+	}
+	q.TagMatch = strings.ToLower(values.Get("tag_match"))
+	if v := values.Get("ready"); v != "" {
+		ready, err := strconv.ParseBool(v)
+		if err != nil {
+			verr.Add("ready", "invalid", "ready must be true or false")
+		}
+		q.Ready = &ready
+	}
+	if v := values.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			verr.Add("overdue", "invalid", "overdue must be true or false")
+		}
+		q.Overdue = &overdue
+	}
+	for _, v := range values["priority"] {
+		for _, name := range strings.Split(v, ",") {
+			p, err := ParsePriority(strings.TrimSpace(name))
+			if err != nil {
+				verr.Add("priority", "invalid", err.Error())
+				continue
+			}
+			q.Priorities = append(q.Priorities, p)
+		}
+	}
+	if v := values.Get("min_priority"); v != "" {
+		p, err := ParsePriority(v)
+		if err != nil {
+			verr.Add("min_priority", "invalid", "min_"+err.Error())
+		}
+		q.MinPriority = &p
+	}
+	q.CreatedAfter = parseTimeParam(values, "created_after", verr)
+	q.CreatedBefore = parseTimeParam(values, "created_before", verr)
+	q.DoneAfter = parseTimeParam(values, "done_after", verr)
+	q.DoneBefore = parseTimeParam(values, "done_before", verr)
+	q.DueAfter = parseTimeParam(values, "due_after", verr)
+	q.DueBefore = parseTimeParam(values, "due_before", verr)
+
+	return q, verr.Err()
+}
+
+func parseTimeParam(values url.Values, name string, verr *ValidationError) *time.Time {
+	v := values.Get(name)
+	if v == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		verr.Add(name, "invalid", name+" must be an RFC 3339 timestamp")
+		return nil
+	}
+	return &t
+}
+
+// pageLinks tạo header Link (RFC 8288) cho trang trước/sau, giữ nguyên các tham số lọc khác.
+func pageLinks(r *http.Request, prev, next string) string {
+	var links []string
+	for _, l := range []struct{ rel, cursor string }{{"prev", prev}, {"next", next}} {
+		if l.cursor == "" {
+			continue
+		}
+		u := *r.URL
+		values := u.Query()
+		values.Set("cursor", l.cursor)
+		u.RawQuery = values.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), l.rel))
+	}
+	return strings.Join(links, ", ")
+}
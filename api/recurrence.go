@@ -0,0 +1,558 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency là tần suất lặp lại của một series (FREQ trong RRULE).
+type Frequency string
+
+const (
+	FreqDaily   Frequency = "DAILY"
+	FreqWeekly  Frequency = "WEEKLY"
+	FreqMonthly Frequency = "MONTHLY"
+)
+
+// maxRecurrenceInterval giới hạn INTERVAL ở một năm theo ngày, đủ cho mọi lịch lặp thật.
+const maxRecurrenceInterval = 366
+
+// untilLayout là định dạng UTC của UNTIL (RFC 5545 DATE-TIME), ví dụ 20261231T235959Z.
+const untilLayout = "20060102T150405Z"
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// Recurrence là lịch lặp của todo, một tập con của RRULE (RFC 5545): FREQ (DAILY, WEEKLY, MONTHLY), INTERVAL,
+// BYDAY (với DAILY và WEEKLY), UNTIL hoặc COUNT. API dùng dạng chuỗi, ví dụ "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH".
+// Tuần bắt đầu từ thứ hai (WKST=MO) và mọi phép tính ngày đều theo UTC.
+type Recurrence struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []time.Weekday
+	Until    *time.Time
+	Count    int
+}
+
+// ParseRecurrence đọc một RRULE, có hoặc không có tiền tố "RRULE:". Khóa và giá trị không phân biệt hoa thường.
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimSpace(rule)
+	if len(rule) >= 6 && strings.EqualFold(rule[:6], "RRULE:") {
+		rule = rule[6:]
+	}
+	r := &Recurrence{}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key, value = strings.ToUpper(strings.TrimSpace(key)), strings.ToUpper(strings.TrimSpace(value))
+		if !ok || key == "" || value == "" {
+			return nil, fmt.Errorf("%q is not a KEY=VALUE pair", part)
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("%s is given more than once", key)
+		}
+		seen[key] = true
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(value)
+		case "INTERVAL":
+			// Interval bằng 0 nghĩa là không có INTERVAL, nên INTERVAL=0 phải bị từ chối ngay ở đây.
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxRecurrenceInterval {
+				return nil, fmt.Errorf("INTERVAL must be between 1 and %d", maxRecurrenceInterval)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+			r.Count = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return nil, err
+			}
+			r.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day, ok := weekdayCodes[strings.TrimSpace(code)]
+				if !ok {
+					return nil, fmt.Errorf("BYDAY must list weekdays such as MO,WE,FR")
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		default:
+			return nil, fmt.Errorf("%s is not supported, use FREQ, INTERVAL, BYDAY, UNTIL or COUNT", key)
+		}
+	}
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// parseUntil đọc UNTIL dạng DATE-TIME UTC hoặc DATE; DATE được hiểu là hết ngày đó (UTC).
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse(untilLayout, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a UTC date-time such as 20261231T235959Z or a date such as 20261231")
+}
+
+func (r *Recurrence) validate() error {
+	switch r.Freq {
+	case FreqDaily, FreqWeekly, FreqMonthly:
+	case "":
+		return fmt.Errorf("FREQ is required")
+	default:
+		return fmt.Errorf("FREQ must be DAILY, WEEKLY or MONTHLY")
+	}
+	if r.Interval < 0 || r.Interval > maxRecurrenceInterval {
+		return fmt.Errorf("INTERVAL must be between 1 and %d", maxRecurrenceInterval)
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("COUNT must be a positive number")
+	}
+	if r.Count > 0 && r.Until != nil {
+		return fmt.Errorf("UNTIL and COUNT cannot be combined")
+	}
+	if len(r.ByDay) > 0 && r.Freq == FreqMonthly {
+		return fmt.Errorf("BYDAY is only supported with FREQ=DAILY or FREQ=WEEKLY")
+	}
+	return nil
+}
+
+func (r Recurrence) interval() int {
+	if r.Interval < 1 {
+		return 1
+	}
+	return r.Interval
+}
+
+// days trả về BYDAY đã bỏ trùng, theo thứ tự trong tuần bắt đầu từ thứ hai.
+func (r Recurrence) days() []time.Weekday {
+	seen := map[time.Weekday]bool{}
+	days := make([]time.Weekday, 0, len(r.ByDay))
+	for _, day := range r.ByDay {
+		if !seen[day] {
+			seen[day] = true
+			days = append(days, day)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return weekIndex(days[i]) < weekIndex(days[j]) })
+	return days
+}
+
+// weekIndex là vị trí của ngày trong tuần bắt đầu từ thứ hai (thứ hai là 0, chủ nhật là 6).
+func weekIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// String trả về dạng chuẩn của RRULE, cũng là giá trị được lưu trong cột recurrence.
+func (r Recurrence) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.interval() > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.interval()))
+	}
+	if days := r.days(); len(days) > 0 {
+		codes := make([]string, 0, len(days))
+		for _, day := range days {
+			codes = append(codes, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (r Recurrence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *Recurrence) UnmarshalJSON(data []byte) error {
+	var rule string
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	parsed, err := ParseRecurrence(rule)
+	if err != nil {
+		return err
+	}
+	*r = *parsed
+	return nil
+}
+
+// recurrenceValue là giá trị của cột recurrence: NULL khi todo không lặp lại.
+func recurrenceValue(r *Recurrence) *string {
+	if r == nil {
+		return nil
+	}
+	rule := r.String()
+	return &rule
+}
+
+// next trả về hạn của occurrence tiếp theo sau occurrence thứ n có hạn due.
+// false nghĩa là series đã kết thúc theo COUNT hoặc UNTIL.
+func (r Recurrence) next(due time.Time, n int) (time.Time, bool) {
+	if r.Count > 0 && n >= r.Count {
+		return time.Time{}, false
+	}
+	next, ok := r.step(due.UTC())
+	if !ok || (r.Until != nil && next.After(*r.Until)) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// step tính thời điểm kế tiếp theo FREQ, INTERVAL và BYDAY, giữ nguyên giờ trong ngày của due.
+func (r Recurrence) step(due time.Time) (time.Time, bool) {
+	days := r.days()
+	switch r.Freq {
+	case FreqDaily:
+		// BYDAY lọc các ngày: bước INTERVAL ngày tới khi gặp một ngày được liệt kê. Sau 7 bước các ngày trong tuần
+		// bắt đầu lặp lại, nên không gặp thì không bao giờ gặp (ví dụ INTERVAL=7;BYDAY=TU từ một thứ hai).
+		next := due
+		for i := 0; i < 7; i++ {
+			next = next.AddDate(0, 0, r.interval())
+			if len(days) == 0 || containsWeekday(days, next.Weekday()) {
+				return next, true
+			}
+		}
+		return time.Time{}, false
+	case FreqWeekly:
+		if len(days) == 0 {
+			return due.AddDate(0, 0, 7*r.interval()), true
+		}
+		today := weekIndex(due.Weekday())
+		for _, day := range days {
+			if weekIndex(day) > today {
+				return due.AddDate(0, 0, weekIndex(day)-today), true
+			}
+		}
+		// Hết các ngày của tuần này: sang ngày đầu tiên của tuần cách INTERVAL tuần.
+		return due.AddDate(0, 0, 7*r.interval()-today+weekIndex(days[0])), true
+	case FreqMonthly:
+		// Như RFC 5545, tháng không có ngày đó (ví dụ ngày 31) bị bỏ qua thay vì dồn sang tháng sau.
+		for i := 1; i <= 48; i++ {
+			next := time.Date(due.Year(), due.Month()+time.Month(i*r.interval()), due.Day(),
+				due.Hour(), due.Minute(), due.Second(), due.Nanosecond(), time.UTC)
+			if next.Day() == due.Day() {
+				return next, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func seriesNotFound(id string) error {
+	return &NotFoundError{Resource: "series", ID: id}
+}
+
+// validateRecurrence kiểm tra lịch lặp của todo: hạn của occurrence tiếp theo được tính từ due_at, nên todo lặp lại phải có due_at.
+func validateRecurrence(verr *ValidationError, r *Recurrence, due *time.Time) {
+	if r == nil {
+		return
+	}
+	if err := r.validate(); err != nil {
+		verr.Add("recurrence", "invalid_format", "recurrence is not a supported RRULE: "+err.Error())
+	} else if due == nil {
+		verr.Add("due_at", "required", "due_at is required when recurrence is set")
+	}
+}
+
+// checkSchedule áp dụng validateRecurrence cho todo sau khi patch được áp dụng, vì patch có thể chỉ đổi
+// một trong hai trường recurrence và due_at.
+func checkSchedule(current Todo, patch TodoPatch) error {
+	recurrence, due := current.Recurrence, current.DueAt
+	if patch.Recurrence != nil {
+		recurrence = *patch.Recurrence
+	}
+	if patch.DueAt != nil {
+		due = *patch.DueAt
+	}
+	verr := &ValidationError{}
+	validateRecurrence(verr, recurrence, due)
+	return verr.Err()
+}
+
+// startSeries gán series cho một todo mới: todo lặp lại là occurrence đầu tiên của một series mới.
+func startSeries(todo *Todo) {
+	todo.SeriesID, todo.Occurrence = "", 0
+	if todo.Recurrence != nil {
+		todo.SeriesID, todo.Occurrence = generateNewID(), 1
+	}
+}
+
+// nextOccurrence dựng occurrence tiếp theo của một occurrence vừa done: cùng nội dung, lịch lặp, tag, project và owner,
+// hạn là lần lặp kế tiếp. Checklist và blocker không được chép sang. false khi todo không lặp lại hoặc series đã kết thúc.
+func nextOccurrence(done Todo) (Todo, bool) {
+	if done.Recurrence == nil || done.DueAt == nil || done.SeriesID == "" {
+		return Todo{}, false
+	}
+	due, ok := done.Recurrence.next(*done.DueAt, done.Occurrence)
+	if !ok {
+		return Todo{}, false
+	}
+	return Todo{
+		Title:      done.Title,
+		Desc:       done.Desc,
+		ProjectID:  done.ProjectID,
+		OwnerID:    done.OwnerID,
+		Priority:   done.Priority,
+		DueAt:      &due,
+		Estimate:   done.Estimate,
+		Tags:       append([]string{}, done.Tags...),
+		Recurrence: done.Recurrence,
+		SeriesID:   done.SeriesID,
+		Occurrence: done.Occurrence + 1,
+	}, true
+}
+
+// pendingOccurrence cho biết occurrence còn phải làm (chưa done, chưa bị hủy), tức là bị sửa khi sửa cả series.
+func pendingOccurrence(todo Todo) bool {
+	return !todo.Done && todo.Status != StatusCancelled
+}
+
+// validateSeriesPatch kiểm tra patch áp dụng cho cả series: trạng thái và hạn là của từng occurrence.
+func validateSeriesPatch(patch TodoPatch) error {
+	verr := &ValidationError{}
+	if patch.Status != nil || patch.Done != nil {
+		verr.Add("status", "not_allowed", "complete or transition each occurrence instead")
+	}
+	if patch.DueAt != nil {
+		verr.Add("due_at", "not_allowed", "due dates belong to one occurrence, patch that todo instead")
+	}
+	if err := verr.Err(); err != nil {
+		return err
+	}
+	return validatePatch(patch)
+}
+
+// SeriesPatchRequest là merge patch của PATCH /v1/series/{id}, áp dụng cho mọi occurrence còn phải làm.
+// recurrence null kết thúc series: occurrence hiện tại sẽ không sinh occurrence tiếp theo.
+type SeriesPatchRequest struct {
+	Title      *string  `json:"title,omitempty"`
+	Desc       *string  `json:"desc,omitempty"`
+	Priority   *string  `json:"priority,omitempty" enums:"low,normal,high,urgent" example:"urgent"`
+	Estimate   *int     `json:"estimate,omitempty" example:"30"`
+	Tags       []string `json:"tags,omitempty" example:"ops"`
+	ProjectID  *string  `json:"project_id,omitempty" example:"default"`
+	Recurrence *string  `json:"recurrence,omitempty" example:"FREQ=WEEKLY;BYDAY=MO"`
+}
+
+var seriesPatchRules = payloadRules{
+	fields: map[string]fieldRule{
+		"title":      titleRule,
+		"desc":       {kind: "string", nullable: true},
+		"priority":   priorityRule,
+		"estimate":   estimateRule,
+		"tags":       tagsRule,
+		"project_id": projectRule,
+		"recurrence": recurrenceRule,
+	},
+	rejected: withRejected(serverManagedFields, map[string]FieldError{
+		"done":   {Code: "not_allowed", Message: "complete or transition each occurrence instead"},
+		"status": {Code: "not_allowed", Message: "complete or transition each occurrence instead"},
+		"due_at": {Code: "not_allowed", Message: "due dates belong to one occurrence, patch that todo instead"},
+	}),
+}
+
+// decodeSeriesPatch đọc merge patch của một series thành TodoPatch.
+func decodeSeriesPatch(body []byte) (TodoPatch, error) {
+	fields, err := decodeObject(body)
+	if err != nil {
+		return TodoPatch{}, fmt.Errorf("%w: merge patch must be a JSON object", ErrInvalidBody)
+	}
+	normalized, err := seriesPatchRules.check(fields, true)
+	if err != nil {
+		return TodoPatch{}, err
+	}
+	return todoPatchFromFields(normalized), nil
+}
+
+// spawnNext tạo occurrence tiếp theo khi done vừa được chuyển sang done, trong transaction của lần chuyển đó.
+// Occurrence đã được sinh trước đó (todo bị mở lại rồi done lần nữa) thì không sinh thêm;
+// unique index (series_id, occurrence) chặn cả hai request done cùng lúc. Occurrence mới được chia sẻ với cùng những người
+// như occurrence vừa done.
+func (s *DbTodoService) spawnNext(ctx context.Context, done *Todo) error {
+	next, ok := nextOccurrence(*done)
+	if !ok {
+		return nil
+	}
+	var exists bool
+	err := s.conn().QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM todo WHERE series_id = $1 AND occurrence = $2 AND tenant_id = $3)",
+		next.SeriesID, next.Occurrence, tenantFrom(ctx)).Scan(&exists)
+	if err != nil {
+		return dbError("kiểm tra occurrence tiếp theo thất bại", err)
+	}
+	if exists {
+		return nil
+	}
+	created, err := s.insert(ctx, next)
+	if err != nil {
+		return err
+	}
+	return s.shareNext(ctx, done.ID, created.ID)
+}
+
+// GetSeries trả về mọi occurrence của series theo thứ tự.
+func (s *DbTodoService) GetSeries(ctx context.Context, seriesID string) ([]Todo, error) {
+	w := &sqlWhere{}
+	w.add("series_id = ?", seriesID)
+	w.add("deleted_at IS NULL")
+	visibleTodos(ctx, w, "")
+	todos, err := s.queryTodos(ctx, "SELECT "+todoColumns+" FROM todo"+w.String()+" ORDER BY occurrence", w.args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(todos) == 0 {
+		return nil, seriesNotFound(seriesID)
+	}
+	return todos, nil
+}
+
+// PatchSeries áp dụng patch cho mọi occurrence còn phải làm của series trong một transaction;
+// occurrence đã done hoặc đã hủy được giữ nguyên như lịch sử.
+func (s *DbTodoService) PatchSeries(ctx context.Context, seriesID string, patch TodoPatch) ([]Todo, error) {
+	if err := validateSeriesPatch(patch); err != nil {
+		return nil, err
+	}
+	var todos []Todo
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		occurrences, err := svc.GetSeries(ctx, seriesID)
+		if err != nil {
+			return err
+		}
+		if !patch.empty() {
+			for _, todo := range occurrences {
+				if !pendingOccurrence(todo) {
+					continue
+				}
+				if err := checkSchedule(todo, patch); err != nil {
+					return err
+				}
+				if _, err := svc.write(ctx, todo.ID, patch, nil, false); err != nil {
+					return err
+				}
+			}
+		}
+		todos, err = svc.GetSeries(ctx, seriesID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// spawnNextLocked giống DbTodoService.spawnNext; caller phải giữ s.mu.
+func (s *MemoryTodoService) spawnNextLocked(ctx context.Context, done Todo) error {
+	next, ok := nextOccurrence(done)
+	if !ok {
+		return nil
+	}
+	// Occurrence đã sinh rồi bị xóa vào thùng rác cũng tính, như unique index của DbTodoService.
+	for _, todos := range []map[string]Todo{s.todos, s.trash} {
+		for _, todo := range todos {
+			if todo.SeriesID == next.SeriesID && todo.Occurrence == next.Occurrence {
+				return nil
+			}
+		}
+	}
+	created, err := s.createLocked(ctx, next)
+	if err != nil {
+		return err
+	}
+	if shares := s.shares[done.ID]; len(shares) > 0 {
+		copied := make([]TodoShare, 0, len(shares))
+		for _, share := range shares {
+			share.TodoID = created.ID
+			copied = append(copied, share)
+		}
+		s.shares[created.ID] = copied
+	}
+	return nil
+}
+
+// seriesLocked trả về các occurrence của series theo thứ tự; caller phải giữ s.mu.
+func (s *MemoryTodoService) seriesLocked(ctx context.Context, seriesID string) ([]Todo, error) {
+	todos := []Todo{}
+	for _, todo := range s.todos {
+		if seriesID != "" && todo.SeriesID == seriesID && s.visibleLocked(ctx, todo) {
+			todos = append(todos, todo)
+		}
+	}
+	if len(todos) == 0 {
+		return nil, seriesNotFound(seriesID)
+	}
+	sort.Slice(todos, func(i, j int) bool { return todos[i].Occurrence < todos[j].Occurrence })
+	return todos, nil
+}
+
+func (s *MemoryTodoService) GetSeries(ctx context.Context, seriesID string) ([]Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.seriesLocked(ctx, seriesID)
+}
+
+func (s *MemoryTodoService) PatchSeries(ctx context.Context, seriesID string, patch TodoPatch) ([]Todo, error) {
+	if err := validateSeriesPatch(patch); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	occurrences, err := s.seriesLocked(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if patch.empty() {
+		return occurrences, nil
+	}
+	// Kiểm tra mọi occurrence trước khi ghi, để series không bị sửa dở dang.
+	if patch.ProjectID != nil {
+		if err := s.checkProjectLocked(*patch.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	for _, todo := range occurrences {
+		if pendingOccurrence(todo) {
+			if err := checkSchedule(todo, patch); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, todo := range occurrences {
+		if pendingOccurrence(todo) {
+			if _, err := s.patchLocked(ctx, todo.ID, patch); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.seriesLocked(ctx, seriesID)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence("RRULE:freq=weekly;byday=th,mo,th;interval=2;until=20261231")
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;UNTIL=20261231T235959Z", r.String())
+
+	r, err = ParseRecurrence("FREQ=MONTHLY;INTERVAL=1;COUNT=12")
+	require.NoError(t, err)
+	assert.Equal(t, "FREQ=MONTHLY;COUNT=12", r.String())
+
+	for name, rule := range map[string]string{
+		"empty":             ``,
+		"no freq":           `INTERVAL=2`,
+		"hourly":            `FREQ=HOURLY`,
+		"zero count":        `FREQ=DAILY;COUNT=0`,
+		"until and count":   `FREQ=DAILY;COUNT=3;UNTIL=20261231T000000Z`,
+		"interval too high": `FREQ=DAILY;INTERVAL=1000`,
+		"zero interval":     `FREQ=DAILY;INTERVAL=0`,
+		"negative interval": `FREQ=DAILY;INTERVAL=-2`,
+		"interval not int":  `FREQ=DAILY;INTERVAL=two`,
+		"bad weekday":       `FREQ=WEEKLY;BYDAY=XX`,
+		"ordinal weekday":   `FREQ=WEEKLY;BYDAY=1MO`,
+		"monthly byday":     `FREQ=MONTHLY;BYDAY=MO`,
+		"bad until":         `FREQ=DAILY;UNTIL=tomorrow`,
+		"duplicate key":     `FREQ=DAILY;FREQ=WEEKLY`,
+		"unsupported key":   `FREQ=DAILY;BYHOUR=9`,
+		"not a pair":        `FREQ=DAILY;COUNT`,
+	} {
+		_, err := ParseRecurrence(rule)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+	monday := at(2026, time.October, 19)
+
+	cases := []struct {
+		rule string
+		due  time.Time
+		n    int
+		want time.Time // zero nghĩa là series đã kết thúc
+	}{
+		{"FREQ=DAILY", monday, 1, at(2026, time.October, 20)},
+		{"FREQ=DAILY;INTERVAL=3", monday, 1, at(2026, time.October, 22)},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", at(2026, time.October, 23), 1, at(2026, time.October, 26)},
+		{"FREQ=DAILY;INTERVAL=7;BYDAY=TU", monday, 1, time.Time{}},
+		{"FREQ=WEEKLY", monday, 1, at(2026, time.October, 26)},
+		{"FREQ=WEEKLY;BYDAY=MO,TH", monday, 1, at(2026, time.October, 22)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", at(2026, time.October, 22), 2, at(2026, time.November, 2)},
+		{"FREQ=WEEKLY;BYDAY=SU", monday, 1, at(2026, time.October, 25)},
+		{"FREQ=MONTHLY", at(2026, time.January, 31), 1, at(2026, time.March, 31)},
+		{"FREQ=MONTHLY;INTERVAL=12", at(2028, time.February, 29), 1, at(2032, time.February, 29)},
+		{"FREQ=DAILY;COUNT=2", monday, 1, at(2026, time.October, 20)},
+		{"FREQ=DAILY;COUNT=2", monday, 2, time.Time{}},
+		{"FREQ=WEEKLY;UNTIL=20261025", monday, 1, time.Time{}},
+		{"FREQ=WEEKLY;UNTIL=20261026", monday, 1, at(2026, time.October, 26)},
+	}
+	for _, c := range cases {
+		r, err := ParseRecurrence(c.rule)
+		require.NoError(t, err, c.rule)
+		got, ok := r.next(c.due, c.n)
+		if c.want.IsZero() {
+			assert.False(t, ok, "%s from %v: got %v", c.rule, c.due, got)
+			continue
+		}
+		assert.True(t, ok, c.rule)
+		assert.Equal(t, c.want, got, "%s from %v", c.rule, c.due)
+	}
+}
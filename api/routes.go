@@ -0,0 +1,182 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/swaggo/http-swagger"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Các route kiểu động từ (/todo/create, /todo/getuser/{id}, ...) được giữ lại cho client cũ
+// đến ngày legacySunset, sau đó sẽ bị gỡ bỏ.
+var (
+	legacyDeprecatedAt = time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// newRouter khai báo toàn bộ route của API: resource API /v1 và các alias cũ đã deprecated.
+func newRouter(h *APIHandler) *mux.Router {
+	router := mux.NewRouter()
+	router.Use(requestActor, h.authenticate, requestTenant, h.requestUser)
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusNotFound, CodeNotFound, "no route matches "+r.URL.Path)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeProblem(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "method "+r.Method+" is not allowed")
+	})
+
+	router.HandleFunc("/v1/todos", h.GetAllTodo).Methods(http.MethodGet)
+	router.HandleFunc("/v1/todos", h.idempotent(h.CreateTodo)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/todos/bulk", h.idempotent(h.BulkTodos)).Methods(http.MethodPost)
+	if h.events != nil {
+		router.HandleFunc("/v1/todos/events", h.StreamEvents).Methods(http.MethodGet)
+	}
+	router.HandleFunc("/v1/todos/{id}", h.GetTodo).Methods(http.MethodGet)
+	router.HandleFunc("/v1/todos/{id}", requireIfMatch(h.ReplaceTodo)).Methods(http.MethodPut)
+	router.HandleFunc("/v1/todos/{id}", requireIfMatch(h.UpdateTodo)).Methods(http.MethodPatch)
+	router.HandleFunc("/v1/todos/{id}", requireIfMatch(h.idempotent(h.DeleteTodo))).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/todos/{id}/complete", h.CompleteTodo).Methods(http.MethodPost)
+	router.HandleFunc("/v1/todos/{id}/reopen", h.ReopenTodo).Methods(http.MethodPost)
+	router.HandleFunc("/v1/todos/{id}/status", h.TransitionTodo).Methods(http.MethodPost)
+	router.HandleFunc("/v1/todos/{id}/toggle", requireIfMatch(h.idempotent(h.UpdateTodoStatus))).Methods(http.MethodPost)
+	router.HandleFunc("/v1/todos/{id}/checklist", h.ListChecklist).Methods(http.MethodGet)
+	router.HandleFunc("/v1/todos/{id}/checklist", h.idempotent(h.AddChecklistItem)).Methods(http.MethodPost)
+	router.HandleFunc("/v1/todos/{id}/checklist/{item_id}", h.GetChecklistItem).Methods(http.MethodGet)
+	router.HandleFunc("/v1/todos/{id}/checklist/{item_id}", h.UpdateChecklistItem).Methods(http.MethodPatch)
+	router.HandleFunc("/v1/todos/{id}/checklist/{item_id}", h.DeleteChecklistItem).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/todos/{id}/blockers/{blocker_id}", h.AddBlocker).Methods(http.MethodPut)
+	router.HandleFunc("/v1/todos/{id}/blockers/{blocker_id}", h.RemoveBlocker).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/todos/{id}/history", h.GetTodoHistory).Methods(http.MethodGet)
+	router.HandleFunc("/v1/todos/{id}/shares", h.ListShares).Methods(http.MethodGet)
+	router.HandleFunc("/v1/todos/{id}/shares/{user_id}", h.ShareTodo).Methods(http.MethodPut)
+	router.HandleFunc("/v1/todos/{id}/shares/{user_id}", h.UnshareTodo).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/activity", h.ListActivity).Methods(http.MethodGet)
+
+	router.HandleFunc("/v1/trash", h.ListTrash).Methods(http.MethodGet)
+	router.HandleFunc("/v1/trash", h.EmptyTrash).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/trash/{id}", h.PurgeTodo).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/trash/{id}/restore", h.RestoreTodo).Methods(http.MethodPost)
+
+	router.HandleFunc("/v1/series/{id}", h.GetSeries).Methods(http.MethodGet)
+	router.HandleFunc("/v1/series/{id}", h.PatchSeries).Methods(http.MethodPatch)
+
+	router.HandleFunc("/v1/workflow", h.GetWorkflow).Methods(http.MethodGet)
+
+	router.HandleFunc("/v1/tags", h.ListTags).Methods(http.MethodGet)
+	router.HandleFunc("/v1/tags", h.CreateTag).Methods(http.MethodPost)
+	router.HandleFunc("/v1/tags/{id}", h.GetTag).Methods(http.MethodGet)
+	router.HandleFunc("/v1/tags/{id}", h.UpdateTag).Methods(http.MethodPut)
+	router.HandleFunc("/v1/tags/{id}", h.DeleteTag).Methods(http.MethodDelete)
+
+	router.HandleFunc("/v1/projects", h.ListProjects).Methods(http.MethodGet)
+	router.HandleFunc("/v1/projects", h.CreateProject).Methods(http.MethodPost)
+	router.HandleFunc("/v1/projects/{id}", h.GetProject).Methods(http.MethodGet)
+	router.HandleFunc("/v1/projects/{id}", h.UpdateProject).Methods(http.MethodPut)
+	router.HandleFunc("/v1/projects/{id}", h.DeleteProject).Methods(http.MethodDelete)
+	router.HandleFunc("/v1/projects/{id}/todos", h.ListProjectTodos).Methods(http.MethodGet)
+	router.HandleFunc("/v1/projects/{id}/order", h.OrderProjectTodos).Methods(http.MethodGet)
+	router.HandleFunc("/v1/projects/{id}/shares", h.ListProjectShares).Methods(http.MethodGet)
+	router.HandleFunc("/v1/projects/{id}/shares/{user_id}", h.ShareProject).Methods(http.MethodPut)
+	router.HandleFunc("/v1/projects/{id}/shares/{user_id}", h.UnshareProject).Methods(http.MethodDelete)
+
+	router.HandleFunc("/v1/users", h.ListUsers).Methods(http.MethodGet)
+	router.HandleFunc("/v1/users", h.CreateUser).Methods(http.MethodPost)
+	router.HandleFunc("/v1/users/{id}", h.GetUser).Methods(http.MethodGet)
+
+	if h.apiKeys != nil {
+		router.HandleFunc("/v1/api-keys", h.ListAPIKeys).Methods(http.MethodGet)
+		router.HandleFunc("/v1/api-keys", h.CreateAPIKey).Methods(http.MethodPost)
+		router.HandleFunc("/v1/api-keys/{id}", h.RevokeAPIKey).Methods(http.MethodDelete)
+	}
+
+	router.HandleFunc("/todo", deprecated("/v1/todos", h.GetAllTodo)).Methods(http.MethodGet)
+	router.HandleFunc("/todo/getuser/{id}", deprecated("/v1/todos/{id}", h.GetTodo)).Methods(http.MethodGet)
+	router.HandleFunc("/todo/create", deprecated("/v1/todos", h.idempotent(h.CreateTodo))).Methods(http.MethodPost)
+	router.HandleFunc("/todo/update/{id}", deprecated("/v1/todos/{id}", h.UpdateTodo)).Methods(http.MethodPatch)
+	router.HandleFunc("/todo/update-status/{id}", deprecated("/v1/todos/{id}/toggle", h.idempotent(h.UpdateTodoStatus))).Methods(http.MethodPatch)
+	router.HandleFunc("/todo/delete/{id}", deprecated("/v1/todos/{id}", h.idempotent(h.DeleteTodo))).Methods(http.MethodDelete)
+	if h.events != nil {
+		router.HandleFunc("/todo/events", deprecated("/v1/todos/events", h.StreamEvents)).Methods(http.MethodGet)
+	}
+
+	router.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	return router
+}
+
+// deprecated bọc handler của route cũ, gửi header Deprecation (RFC 9745), Sunset (RFC 8594)
+// và Link rel="successor-version" trỏ tới route /v1 tương ứng.
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "@"+strconv.FormatInt(legacyDeprecatedAt.Unix(), 10))
+		w.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+		w.Header().Add("Link", "<"+strings.Replace(successor, "{id}", mux.Vars(r)["id"], 1)+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// todoID đọc ID của todo từ biến {id} của route.
+func todoID(r *http.Request) string {
+	return mux.Vars(r)["id"]
+}
+
+func todoLocation(id string) string {
+	return "/v1/todos/" + id
+}
+
+// checklistItemID đọc ID của checklist item từ biến {item_id} của route; ID của todo vẫn là {id}.
+func checklistItemID(r *http.Request) string {
+	return mux.Vars(r)["item_id"]
+}
+
+// blockerID đọc ID của todo chặn từ biến {blocker_id} của route.
+func blockerID(r *http.Request) string {
+	return mux.Vars(r)["blocker_id"]
+}
+
+func checklistItemLocation(todoID, itemID string) string {
+	return todoLocation(todoID) + "/checklist/" + itemID
+}
+
+// seriesID đọc ID của series todo lặp lại từ biến {id} của route.
+func seriesID(r *http.Request) string {
+	return mux.Vars(r)["id"]
+}
+
+// tagID đọc ID của tag từ biến {id} của route.
+func tagID(r *http.Request) string {
+	return mux.Vars(r)["id"]
+}
+
+func tagLocation(id string) string {
+	return "/v1/tags/" + id
+}
+
+// projectID đọc ID của project từ biến {id} của route.
+func projectID(r *http.Request) string {
+	return mux.Vars(r)["id"]
+}
+
+func projectLocation(id string) string {
+	return "/v1/projects/" + id
+}
+
+// userID đọc ID của người dùng từ biến {id} của route.
+func userID(r *http.Request) string {
+	return mux.Vars(r)["id"]
+}
+
+// shareUserID đọc ID của người được chia sẻ từ biến {user_id} của route; ID của todo vẫn là {id}.
+func shareUserID(r *http.Request) string {
+	return mux.Vars(r)["user_id"]
+}
+
+func userLocation(id string) string {
+	return "/v1/users/" + id
+}
+
+// apiKeyID đọc ID của API key từ biến {id} của route.
+func apiKeyID(r *http.Request) string {
+	return mux.Vars(r)["id"]
+}
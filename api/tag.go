@@ -0,0 +1,578 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Tag là nhãn gắn cho todo, ví dụ "frontend" hay "print". Tên tag được chuẩn hóa về chữ thường
+// và là duy nhất; todo chỉ lưu tên tag. TodoCount là số todo đang mang tag, do server tính. OwnerID là người dùng
+// đã tạo tag, rỗng với tag có từ trước khi có người dùng.
+type Tag struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name" example:"frontend"`
+	Color     string    `json:"color" example:"#1e90ff"`
+	OwnerID   string    `json:"owner_id"`
+	TodoCount int       `json:"todo_count" example:"3"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	maxTagNameLength = 50
+	maxTagsPerTodo   = 20
+)
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+func tagNotFound(id string) error {
+	return &NotFoundError{Resource: "tag", ID: id}
+}
+
+func normalizeTagName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// normalizeTags chuẩn hóa, bỏ trùng và sắp xếp danh sách tên tag; kết quả không bao giờ nil.
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = normalizeTagName(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		tags = append(tags, name)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// validateTagName kiểm tra tên tag sau khi chuẩn hóa. Tag được lọc bằng tên trong query string (?tag=a,b)
+// nên tên không được chứa dấu phẩy.
+func validateTagName(verr *ValidationError, field, name string) {
+	name = normalizeTagName(name)
+	switch {
+	case name == "":
+		verr.Add(field, "required", field+" must not be empty")
+	case utf8.RuneCountInString(name) > maxTagNameLength:
+		verr.Add(field, "too_long", fmt.Sprintf("%s must be at most %d characters", field, maxTagNameLength))
+	case strings.Contains(name, ","):
+		verr.Add(field, "invalid_format", field+" must not contain commas")
+	}
+}
+
+// validateTags kiểm tra danh sách tag của một todo, lỗi được báo theo vị trí (tags[1]).
+func validateTags(verr *ValidationError, names []string) {
+	for i, name := range names {
+		validateTagName(verr, fmt.Sprintf("tags[%d]", i), name)
+	}
+	if n := len(normalizeTags(names)); n > maxTagsPerTodo {
+		verr.Add("tags", "too_many", fmt.Sprintf("a todo can have at most %d tags", maxTagsPerTodo))
+	}
+}
+
+func validateTag(tag Tag) error {
+	verr := &ValidationError{}
+	validateTagName(verr, "name", tag.Name)
+	if tag.Color != "" && !tagColorPattern.MatchString(strings.ToLower(tag.Color)) {
+		verr.Add("color", "invalid_format", "color must be a hex color such as #1e90ff")
+	}
+	return verr.Err()
+}
+
+// TagRequest là payload của POST /v1/tags và PUT /v1/tags/{id}. Color là mã hex (#rrggbb), có thể để trống.
+type TagRequest struct {
+	Name  string `json:"name" example:"frontend"`
+	Color string `json:"color" example:"#1e90ff"`
+}
+
+var tagRules = payloadRules{
+	fields: map[string]fieldRule{
+		"name":  {kind: "string", required: true, trim: true, maxLength: maxTagNameLength},
+		"color": {kind: "string", trim: true},
+	},
+	rejected: map[string]FieldError{
+		"id":         {Code: "read_only", Message: "id is assigned by the server"},
+		"created_at": {Code: "read_only", Message: "created_at is set by the server"},
+		"todo_count": {Code: "read_only", Message: "todo_count is computed by the server"},
+		"owner_id":   {Code: "read_only", Message: "owner_id is set by the server"},
+	},
+}
+
+func (r TagRequest) tag() Tag {
+	return Tag{Name: r.Name, Color: r.Color}
+}
+
+// tagColumns là các cột mà scanTag đọc; câu truy vấn phải dùng tagJoin và GROUP BY theo tag g.
+const tagColumns = "g.id, g.name, g.color, g.owner_id, g.created_at, COUNT(tt.todo_id)"
+
+const tagGroupBy = " GROUP BY g.id, g.name, g.color, g.owner_id, g.created_at"
+
+// tagJoin nối tag với todo_tag, bỏ qua todo trong thùng rác và todo người dùng trong ctx không được thấy khi đếm;
+// tham số của nó được thêm vào w.
+func tagJoin(ctx context.Context, w *sqlWhere) string {
+	return " LEFT JOIN todo_tag tt ON tt.tag_id = g.id AND tt.tenant_id = " + w.arg(tenantFrom(ctx)) +
+		" AND tt.todo_id IN (SELECT id FROM todo WHERE deleted_at IS NULL AND " + visibleTo(ctx, w, "") + ")"
+}
+
+// visibleTag thêm vào w điều kiện chọn tag người dùng trong ctx được thấy: tag của họ, tag không có owner và tag
+// đang gắn cho một todo họ thấy, kể cả todo trong thùng rác.
+func visibleTag(ctx context.Context, w *sqlWhere) {
+	forTenant(ctx, w, "g.")
+	userID := userFrom(ctx)
+	if userID == "" {
+		return
+	}
+	w.add("(g.owner_id IS NULL OR g.owner_id = " + w.arg(userID) +
+		" OR g.id IN (SELECT tag_id FROM todo_tag WHERE tenant_id = " + w.arg(tenantFrom(ctx)) +
+		" AND todo_id IN (SELECT id FROM todo WHERE " + visibleTo(ctx, w, "") + ")))")
+}
+
+func scanTag(row pgx.Row, tag *Tag) error {
+	var ownerID *string
+	if err := row.Scan(&tag.ID, &tag.Name, &tag.Color, &ownerID, &tag.CreatedAt, &tag.TodoCount); err != nil {
+		return err
+	}
+	tag.OwnerID = ""
+	if ownerID != nil {
+		tag.OwnerID = *ownerID
+	}
+	return nil
+}
+
+// inTx chạy fn trong một transaction. Service đang ở trong transaction (bulk) thì dùng luôn transaction đó.
+// Sự kiện fn ghi vào eventBatch của ctx bị bỏ khi transaction không được commit.
+func (s *DbTodoService) inTx(ctx context.Context, fn func(svc *DbTodoService) error) error {
+	if s.tx != nil {
+		return fn(s)
+	}
+	tx, err := s.db.conn.Begin(ctx)
+	if err != nil {
+		return dbError("bắt đầu transaction thất bại", err)
+	}
+	defer tx.Rollback(ctx)
+
+	events := eventsFrom(ctx)
+	mark := events.mark()
+	if err := fn(&DbTodoService{db: s.db, tx: tx, workflow: s.workflow}); err != nil {
+		events.truncate(mark)
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		events.truncate(mark)
+		return dbError("commit transaction thất bại", err)
+	}
+	return nil
+}
+
+// loadTags đọc tag của nhiều todo bằng một câu truy vấn duy nhất (tránh N+1 khi list).
+func (s *DbTodoService) loadTags(ctx context.Context, todos ...*Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(todos))
+	byID := make(map[string]*Todo, len(todos))
+	for _, todo := range todos {
+		todo.Tags = []string{}
+		ids = append(ids, todo.ID)
+		byID[todo.ID] = todo
+	}
+
+	rows, err := s.conn().Query(ctx,
+		"SELECT tt.todo_id, g.name FROM todo_tag tt JOIN tag g ON g.id = tt.tag_id AND g.tenant_id = $2 WHERE tt.todo_id = ANY($1) AND tt.tenant_id = $2 ORDER BY g.name",
+		ids, tenantFrom(ctx))
+	if err != nil {
+		return dbError("đọc tag của todo thất bại", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoID, name string
+		if err := rows.Scan(&todoID, &name); err != nil {
+			return dbError("scan tag thất bại", err)
+		}
+		if todo, ok := byID[todoID]; ok {
+			todo.Tags = append(todo.Tags, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return dbError("lỗi sau khi đọc tag", err)
+	}
+	return nil
+}
+
+// setTags thay toàn bộ tag của một todo; tag chưa tồn tại được tạo mới. Tên phải đã qua normalizeTags.
+func (s *DbTodoService) setTags(ctx context.Context, todoID string, names []string) error {
+	if len(names) > 0 {
+		q := &sqlWhere{}
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		tenant := q.arg(tenantFrom(ctx))
+		owner := "NULLIF(" + q.arg(userFrom(ctx)) + ", '')"
+		values := make([]string, 0, len(names))
+		for _, name := range names {
+			values = append(values, "("+q.arg(generateNewID())+", "+q.arg(name)+", '', "+q.arg(now)+", "+tenant+", "+owner+")")
+		}
+		_, err := s.conn().Exec(ctx,
+			"INSERT INTO tag (id, name, color, created_at, tenant_id, owner_id) VALUES "+strings.Join(values, ", ")+" ON CONFLICT (tenant_id, name) DO NOTHING",
+			q.args...)
+		if err != nil {
+			return dbError("tạo tag thất bại", err)
+		}
+	}
+	if _, err := s.conn().Exec(ctx, "DELETE FROM todo_tag WHERE todo_id = $1 AND tenant_id = $2", todoID, tenantFrom(ctx)); err != nil {
+		return dbError("xóa tag của todo thất bại", err)
+	}
+	if len(names) > 0 {
+		_, err := s.conn().Exec(ctx,
+			"INSERT INTO todo_tag (todo_id, tag_id, tenant_id) SELECT $1, id, tenant_id FROM tag WHERE name = ANY($2) AND tenant_id = $3",
+			todoID, names, tenantFrom(ctx))
+		if err != nil {
+			return dbError("gắn tag cho todo thất bại", err)
+		}
+	}
+	return nil
+}
+
+// touchTagged tăng version của các todo mang tag, vì đổi tên hoặc xóa tag làm thay đổi nội dung (và ETag) của chúng.
+func (s *DbTodoService) touchTagged(ctx context.Context, tagID string) error {
+	_, err := s.conn().Exec(ctx,
+		"UPDATE todo SET version = version + 1, updated_at = $2 WHERE tenant_id = $3 AND id IN (SELECT todo_id FROM todo_tag WHERE tag_id = $1 AND tenant_id = $3)",
+		tagID, time.Now().UTC().Truncate(time.Microsecond), tenantFrom(ctx))
+	if err != nil {
+		return dbError("cập nhật todo mang tag thất bại", err)
+	}
+	return nil
+}
+
+func (s *DbTodoService) ListTags(ctx context.Context) ([]Tag, error) {
+	w := &sqlWhere{}
+	join := tagJoin(ctx, w)
+	visibleTag(ctx, w)
+	rows, err := s.conn().Query(ctx,
+		"SELECT "+tagColumns+" FROM tag g"+join+w.String()+tagGroupBy+" ORDER BY g.name", w.args...)
+	if err != nil {
+		return nil, dbError("truy vấn tag thất bại", err)
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := scanTag(rows, &tag); err != nil {
+			return nil, dbError("scan tag thất bại", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc tag", err)
+	}
+	return tags, nil
+}
+
+func (s *DbTodoService) GetTag(ctx context.Context, id string) (*Tag, error) {
+	var tag Tag
+	w := &sqlWhere{}
+	join := tagJoin(ctx, w)
+	w.add("g.id = ?", id)
+	visibleTag(ctx, w)
+	err := scanTag(s.conn().QueryRow(ctx,
+		"SELECT "+tagColumns+" FROM tag g"+join+w.String()+tagGroupBy, w.args...), &tag)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, tagNotFound(id)
+		}
+		return nil, dbError("truy vấn tag thất bại", err)
+	}
+	return &tag, nil
+}
+
+func (s *DbTodoService) CreateTag(ctx context.Context, tag Tag) (*Tag, error) {
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+	tag.ID = generateNewID()
+	tag.Name = normalizeTagName(tag.Name)
+	tag.Color = strings.ToLower(tag.Color)
+	tag.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	tag.TodoCount = 0
+	tag.OwnerID = userFrom(ctx)
+	_, err := s.conn().Exec(ctx,
+		"INSERT INTO tag (id, name, color, created_at, tenant_id, owner_id) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''))",
+		tag.ID, tag.Name, tag.Color, tag.CreatedAt, tenantFrom(ctx), tag.OwnerID)
+	if err != nil {
+		return nil, dbError(fmt.Sprintf("thêm tag %q thất bại", tag.Name), err)
+	}
+	return &tag, nil
+}
+
+// UpdateTag đổi tên và màu của tag; đổi tên cập nhật luôn mọi todo đang mang tag.
+func (s *DbTodoService) UpdateTag(ctx context.Context, id string, tag Tag) (*Tag, error) {
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+	var updated *Tag
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		current, err := svc.GetTag(ctx, id)
+		if err != nil {
+			return err
+		}
+		name := normalizeTagName(tag.Name)
+		_, err = svc.conn().Exec(ctx, "UPDATE tag SET name = $2, color = $3 WHERE id = $1 AND tenant_id = $4",
+			id, name, strings.ToLower(tag.Color), tenantFrom(ctx))
+		if err != nil {
+			return dbError(fmt.Sprintf("cập nhật tag %q thất bại", name), err)
+		}
+		if name != current.Name {
+			if err := svc.touchTagged(ctx, id); err != nil {
+				return err
+			}
+		}
+		updated, err = svc.GetTag(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// DeleteTag xóa tag và gỡ nó khỏi mọi todo.
+func (s *DbTodoService) DeleteTag(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(svc *DbTodoService) error {
+		if _, err := svc.GetTag(ctx, id); err != nil {
+			return err
+		}
+		if err := svc.touchTagged(ctx, id); err != nil {
+			return err
+		}
+		tag, err := svc.conn().Exec(ctx, "DELETE FROM tag WHERE id = $1 AND tenant_id = $2", id, tenantFrom(ctx))
+		if err != nil {
+			return dbError("xóa tag thất bại", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return tagNotFound(id)
+		}
+		return nil
+	})
+}
+
+// TagRole trả về vai trò thấp nhất của người dùng trong ctx trên các todo mang tag, kể cả todo trong thùng rác, vì
+// đổi tên hay xóa tag là sửa mọi todo đó. Tag chưa gắn cho todo nào thuộc toàn quyền người thấy nó.
+func (s *DbTodoService) TagRole(ctx context.Context, id string) (Role, error) {
+	if _, err := s.GetTag(ctx, id); err != nil {
+		return roleNone, err
+	}
+	rows, err := s.conn().Query(ctx, "SELECT todo_id FROM todo_tag WHERE tag_id = $1 AND tenant_id = $2", id, tenantFrom(ctx))
+	if err != nil {
+		return roleNone, dbError("truy vấn todo mang tag thất bại", err)
+	}
+	var todoIDs []string
+	for rows.Next() {
+		var todoID string
+		if err := rows.Scan(&todoID); err != nil {
+			rows.Close()
+			return roleNone, dbError("scan todo mang tag thất bại", err)
+		}
+		todoIDs = append(todoIDs, todoID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return roleNone, dbError("lỗi sau khi đọc todo mang tag", err)
+	}
+
+	role := RoleOwner
+	for _, todoID := range todoIDs {
+		r, err := s.TodoRole(ctx, todoID)
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return roleNone, nil
+		}
+		if err != nil {
+			return roleNone, err
+		}
+		if !r.atLeast(role) {
+			role = r
+		}
+	}
+	return role, nil
+}
+
+// ensureTagsLocked tạo các tag chưa tồn tại, thuộc về người dùng trong ctx; caller phải giữ s.mu.
+func (s *MemoryTodoService) ensureTagsLocked(ctx context.Context, names []string) {
+	for _, name := range names {
+		if _, ok := s.tagByNameLocked(name); !ok {
+			id := generateNewID()
+			s.tags[id] = Tag{ID: id, Name: name, OwnerID: userFrom(ctx), CreatedAt: time.Now()}
+		}
+	}
+}
+
+// tagVisibleLocked giống visibleTag của DbTodoService; caller phải giữ s.mu.
+func (s *MemoryTodoService) tagVisibleLocked(ctx context.Context, tag Tag) bool {
+	userID := userFrom(ctx)
+	if userID == "" || tag.OwnerID == "" || tag.OwnerID == userID {
+		return true
+	}
+	for _, todos := range []map[string]Todo{s.todos, s.trash} {
+		for _, todo := range todos {
+			if containsString(todo.Tags, tag.Name) && s.visibleLocked(ctx, todo) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s *MemoryTodoService) tagByNameLocked(name string) (Tag, bool) {
+	for _, tag := range s.tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return Tag{}, false
+}
+
+// countedTagLocked trả về tag kèm số todo người dùng trong ctx được thấy đang mang nó; caller phải giữ s.mu.
+func (s *MemoryTodoService) countedTagLocked(ctx context.Context, tag Tag) Tag {
+	tag.TodoCount = 0
+	for _, todo := range s.todos {
+		if containsString(todo.Tags, tag.Name) && s.visibleLocked(ctx, todo) {
+			tag.TodoCount++
+		}
+	}
+	return tag
+}
+
+// retagLocked thay tên tag from bằng to (to rỗng là gỡ tag) trên mọi todo đang mang nó, kể cả todo trong thùng rác;
+// caller phải giữ s.mu.
+func (s *MemoryTodoService) retagLocked(from, to string) {
+	now := time.Now()
+	for _, todos := range []map[string]Todo{s.todos, s.trash} {
+		for id, todo := range todos {
+			if !containsString(todo.Tags, from) {
+				continue
+			}
+			tags := withoutString(todo.Tags, from)
+			if to != "" {
+				tags = append(tags, to)
+			}
+			todo.Tags = normalizeTags(tags)
+			todo.Version++
+			todo.UpdatedAt = now
+			todos[id] = todo
+		}
+	}
+}
+
+func (s *MemoryTodoService) ListTags(ctx context.Context) ([]Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tags := make([]Tag, 0, len(s.tags))
+	for _, tag := range s.tags {
+		if s.tagVisibleLocked(ctx, tag) {
+			tags = append(tags, s.countedTagLocked(ctx, tag))
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags, nil
+}
+
+func (s *MemoryTodoService) GetTag(ctx context.Context, id string) (*Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag, ok := s.tags[id]
+	if !ok || !s.tagVisibleLocked(ctx, tag) {
+		return nil, tagNotFound(id)
+	}
+	tag = s.countedTagLocked(ctx, tag)
+	return &tag, nil
+}
+
+func (s *MemoryTodoService) CreateTag(ctx context.Context, tag Tag) (*Tag, error) {
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag.Name = normalizeTagName(tag.Name)
+	if _, exists := s.tagByNameLocked(tag.Name); exists {
+		return nil, fmt.Errorf("tag %q đã tồn tại: %w", tag.Name, ErrConflict)
+	}
+	tag.ID = generateNewID()
+	tag.Color = strings.ToLower(tag.Color)
+	tag.CreatedAt = time.Now()
+	tag.TodoCount = 0
+	tag.OwnerID = userFrom(ctx)
+	s.tags[tag.ID] = tag
+	return &tag, nil
+}
+
+func (s *MemoryTodoService) UpdateTag(ctx context.Context, id string, tag Tag) (*Tag, error) {
+	if err := validateTag(tag); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.tags[id]
+	if !ok || !s.tagVisibleLocked(ctx, current) {
+		return nil, tagNotFound(id)
+	}
+	name := normalizeTagName(tag.Name)
+	if other, exists := s.tagByNameLocked(name); exists && other.ID != id {
+		return nil, fmt.Errorf("tag %q đã tồn tại: %w", name, ErrConflict)
+	}
+	if name != current.Name {
+		s.retagLocked(current.Name, name)
+	}
+	current.Name = name
+	current.Color = strings.ToLower(tag.Color)
+	s.tags[id] = current
+	current = s.countedTagLocked(ctx, current)
+	return &current, nil
+}
+
+func (s *MemoryTodoService) DeleteTag(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tag, ok := s.tags[id]
+	if !ok || !s.tagVisibleLocked(ctx, tag) {
+		return tagNotFound(id)
+	}
+	s.retagLocked(tag.Name, "")
+	delete(s.tags, id)
+	return nil
+}
+
+func (s *MemoryTodoService) TagRole(ctx context.Context, id string) (Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tag, ok := s.tags[id]
+	if !ok || !s.tagVisibleLocked(ctx, tag) {
+		return roleNone, tagNotFound(id)
+	}
+	role := RoleOwner
+	for _, todos := range []map[string]Todo{s.todos, s.trash} {
+		for _, todo := range todos {
+			if !containsString(todo.Tags, tag.Name) {
+				continue
+			}
+			if r := s.roleLocked(ctx, todo); !r.atLeast(role) {
+				role = r
+			}
+		}
+	}
+	return role, nil
+}
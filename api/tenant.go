@@ -0,0 +1,630 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTenantID là tenant của request không chỉ định tenant và của dữ liệu có từ trước migration 000018.
+	DefaultTenantID   = "default"
+	tenantHeader      = "X-Tenant-ID"
+	maxTenantIDLength = 63
+	// anyTenant là tenant của ctx dành cho tác vụ hệ thống chạy trên mọi tenant (xem withAllTenants). Nó không phải
+	// ID hợp lệ, nên câu truy vấn lỡ dùng nó làm điều kiện không khớp dòng nào.
+	anyTenant = "*"
+)
+
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ErrMissingTenant là lỗi khi một câu lệnh SQL của DbTodoService không lọc theo tenant_id (xem tenantGuard).
+var ErrMissingTenant = errors.New("câu lệnh SQL không lọc theo tenant_id")
+
+// validateTenantID kiểm tra ID tenant đến từ header hoặc token.
+func validateTenantID(id string) error {
+	if len(id) > maxTenantIDLength || !tenantIDPattern.MatchString(id) {
+		return fmt.Errorf("tenant ID must be 1 to %d lowercase letters, digits, '-' or '_'", maxTenantIDLength)
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+func withTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// tenantFrom trả về tenant của ctx, DefaultTenantID khi ctx không có tenant.
+func tenantFrom(ctx context.Context) string {
+	if id, _ := ctx.Value(tenantKey{}).(string); id != "" {
+		return id
+	}
+	return DefaultTenantID
+}
+
+// withAllTenants đánh dấu ctx của một tác vụ hệ thống (ví dụ dọn thùng rác) chạy lần lượt trên mọi tenant.
+func withAllTenants(ctx context.Context) context.Context {
+	return withTenant(ctx, anyTenant)
+}
+
+// forAllTenants cho biết ctx được tạo bởi withAllTenants.
+func forAllTenants(ctx context.Context) bool {
+	return tenantFrom(ctx) == anyTenant
+}
+
+// forTenant thêm vào w điều kiện chọn các dòng của tenant trong ctx.
+func forTenant(ctx context.Context, w *sqlWhere, table string) {
+	w.add(table+"tenant_id = ?", tenantFrom(ctx))
+}
+
+// requestTenant chọn tenant của request: tenant của token khi xác thực được bật (authenticate đã gắn vào context),
+// nếu không thì header X-Tenant-ID, mặc định DefaultTenantID. Header khác tenant của token bị từ chối thay vì bỏ qua,
+// để client không ghi nhầm vào tenant khác với tenant nó định dùng.
+func requestTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := strings.TrimSpace(r.Header.Get(tenantHeader))
+		if p, ok := principalFrom(r.Context()); ok {
+			if header != "" && header != p.TenantID {
+				writeProblem(w, r, http.StatusForbidden, CodeTenantMismatch,
+					fmt.Sprintf("the credentials belong to tenant %q, not %q", p.TenantID, header))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		tenantID := DefaultTenantID
+		if header != "" {
+			if err := validateTenantID(header); err != nil {
+				writeProblem(w, r, http.StatusBadRequest, CodeInvalidTenant, err.Error())
+				return
+			}
+			tenantID = header
+		}
+		next.ServeHTTP(w, r.WithContext(withTenant(r.Context(), tenantID)))
+	})
+}
+
+// tenantGuard bọc querier của DbTodoService và từ chối câu lệnh có bảng không được lọc theo tenant, để một điều kiện
+// tenant bị quên làm câu lệnh thất bại thay vì đọc hoặc ghi dữ liệu của tenant khác. Trong mỗi câu truy vấn con, mỗi
+// bảng sau FROM, JOIN, UPDATE, USING hoặc dấu phẩy của danh sách FROM cần một so sánh tenant_id của riêng nó
+// (g.tenant_id = ... với bảng có alias), và INSERT phải ghi cột tenant_id. Chỉ so sánh được AND ở mức ngoài cùng của
+// WHERE hoặc ON mới được tính: phép gán trong SET và điều kiện nằm trong một nhánh OR không lọc gì. Đây là lưới an
+// toàn cho điều kiện bị quên, không phải bộ phân tích SQL đầy đủ.
+type tenantGuard struct {
+	q querier
+}
+
+func (g tenantGuard) check(sql string) error {
+	if table := unfilteredTable(sql); table != "" {
+		return fmt.Errorf("%w: bảng %s trong %s", ErrMissingTenant, table, sql)
+	}
+	return nil
+}
+
+// sqlTable là một bảng được câu lệnh đọc hoặc ghi, alias rỗng khi bảng không có alias.
+type sqlTable struct {
+	name  string
+	alias string
+}
+
+// sqlScope là phần câu lệnh trong một cặp ngoặc (hoặc cả câu lệnh): các bảng của nó và số so sánh tenant_id theo
+// tiền tố bảng ("" với tenant_id không có tiền tố).
+type sqlScope struct {
+	tables  []sqlTable
+	filters map[string]int
+	// clause là mệnh đề đang đọc (xem sqlClauses, "(" với phần đầu của một cặp ngoặc). pending là các so sánh
+	// tenant_id của mệnh đề, chỉ được chuyển vào filters khi mệnh đề là WHERE, ON hoặc "(" và không có OR ở mức này.
+	clause  string
+	pending map[string]int
+	or      bool
+	// insert là bảng đích của INSERT INTO, columns đánh dấu danh sách cột của nó.
+	insert       string
+	insertTenant bool
+	columns      bool
+	hasTenant    bool
+}
+
+func newSQLScope(clause string) *sqlScope {
+	return &sqlScope{filters: map[string]int{}, pending: map[string]int{}, clause: clause}
+}
+
+// begin kết thúc mệnh đề đang đọc và bắt đầu mệnh đề clause.
+func (s *sqlScope) begin(clause string) {
+	if (s.clause == "WHERE" || s.clause == "ON" || s.clause == "(") && !s.or {
+		for prefix, n := range s.pending {
+			s.filters[prefix] += n
+		}
+	}
+	s.clause, s.pending, s.or = clause, map[string]int{}, false
+}
+
+// sqlClauses là các từ khóa bắt đầu một mệnh đề, theo mệnh đề chúng bắt đầu. Bảng được đọc trong mệnh đề FROM.
+var sqlClauses = map[string]string{
+	"SELECT": "SELECT", "FROM": "FROM", "JOIN": "FROM", "LEFT": "FROM", "RIGHT": "FROM", "INNER": "FROM", "FULL": "FROM",
+	"CROSS": "FROM", "NATURAL": "FROM", "UPDATE": "FROM", "USING": "FROM", "WHERE": "WHERE", "ON": "ON", "SET": "SET",
+	"GROUP": "GROUP", "ORDER": "ORDER", "LIMIT": "LIMIT", "OFFSET": "OFFSET", "RETURNING": "RETURNING", "FOR": "FOR",
+	"UNION": "UNION", "INTERSECT": "UNION", "EXCEPT": "UNION", "HAVING": "HAVING", "WINDOW": "WINDOW", "VALUES": "VALUES",
+	"INSERT": "INSERT", "INTO": "INSERT", "DELETE": "DELETE", "WITH": "WITH", "DO": "DO", "CONFLICT": "CONFLICT",
+}
+
+// sqlKeywords là các từ khóa có thể đứng sau tên bảng, tức không phải alias.
+var sqlKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "FULL": true, "CROSS": true, "NATURAL": true,
+	"ON": true, "SET": true, "GROUP": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "USING": true,
+	"RETURNING": true, "VALUES": true, "FOR": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "HAVING": true,
+	"WINDOW": true, "DEFAULT": true, "SELECT": true, "AND": true, "OR": true, "AS": true,
+}
+
+// sqlTokens tách câu lệnh thành từ (kể cả tiền tố bảng, "g.tenant_id") và ký tự đơn; chuỗi '...' thành một token.
+func sqlTokens(sql string) []string {
+	var tokens []string
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == '\'':
+			j := i + 1
+			for j < len(sql) && (sql[j] != '\'' || j+1 < len(sql) && sql[j+1] == '\'') {
+				if sql[j] == '\'' {
+					j++
+				}
+				j++
+			}
+			if j < len(sql) {
+				j++
+			}
+			tokens = append(tokens, sql[i:j])
+			i = j
+		case isSQLWord(c):
+			j := i
+			for j < len(sql) && isSQLWord(sql[j]) {
+				j++
+			}
+			tokens = append(tokens, sql[i:j])
+			i = j
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens
+}
+
+func isSQLWord(c byte) bool {
+	return c == '_' || c == '$' || c == '.' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// unfilteredTable trả về bảng đầu tiên của sql không được lọc theo tenant_id, rỗng khi mọi bảng đều được lọc.
+// Điều kiện trong ngoặc không chứa bảng (ví dụ (a AND b)) thuộc về mệnh đề bao quanh nó; tên của WITH được bỏ qua.
+func unfilteredTable(sql string) string {
+	tokens := sqlTokens(sql)
+	upper := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return strings.ToUpper(tokens[i])
+	}
+	ctes := map[string]bool{}
+	stack := []*sqlScope{newSQLScope("")}
+	for i, token := range tokens {
+		scope := stack[len(stack)-1]
+		switch {
+		case token == "(":
+			child := newSQLScope("(")
+			if scope.insert != "" && upper(i-1) == strings.ToUpper(scope.insert) {
+				child.columns = true
+			}
+			stack = append(stack, child)
+			continue
+		case token == ")":
+			if len(stack) == 1 {
+				return "?"
+			}
+			scope.begin("")
+			stack = stack[:len(stack)-1]
+			parent := stack[len(stack)-1]
+			switch {
+			case scope.columns:
+				parent.insertTenant = scope.hasTenant
+			case len(scope.tables) == 0:
+				for prefix, n := range scope.filters {
+					parent.pending[prefix] += n
+				}
+			default:
+				if table := scope.unfiltered(); table != "" {
+					return table
+				}
+			}
+			continue
+		}
+
+		column := strings.ToLower(token)
+		if column == "tenant_id" || strings.HasSuffix(column, ".tenant_id") {
+			scope.hasTenant = true
+			compared := upper(i+1) == "=" || upper(i+1) == "IN" ||
+				upper(i-1) == "=" && upper(i-2) != "<" && upper(i-2) != ">" && upper(i-2) != "!"
+			if compared && upper(i-1) != "NOT" {
+				scope.pending[strings.TrimSuffix(strings.TrimSuffix(column, "tenant_id"), ".")]++
+			}
+			continue
+		}
+		prev := upper(i - 1)
+		switch {
+		case prev == "WITH" || prev == "RECURSIVE" || prev == "," && scope.clause == "WITH":
+			ctes[column] = true
+		case prev == "INTO":
+			scope.insert = token
+		case prev == "FROM" || prev == "JOIN" || prev == "USING" || prev == "," && scope.clause == "FROM" ||
+			prev == "UPDATE" && scope.clause == "FROM":
+			if upper(i+1) == "(" || sqlKeywords[upper(i)] || ctes[column] || !isSQLWord(token[0]) {
+				break
+			}
+			table := sqlTable{name: column}
+			next := i + 1
+			if upper(next) == "AS" {
+				next++
+			}
+			if alias := upper(next); alias != "" && isSQLWord(alias[0]) && !sqlKeywords[alias] {
+				table.alias = strings.ToLower(tokens[next])
+			}
+			scope.tables = append(scope.tables, table)
+		}
+		switch word := upper(i); {
+		case word == "OR":
+			scope.or = true
+		case word == "UPDATE" && (prev == "FOR" || prev == "DO"):
+			scope.begin(prev)
+		case word == "ON" && upper(i+1) == "CONFLICT":
+			scope.begin("CONFLICT")
+		case sqlClauses[word] != "":
+			scope.begin(sqlClauses[word])
+		}
+	}
+	if len(stack) != 1 {
+		return "?"
+	}
+	stack[0].begin("")
+	return stack[0].unfiltered()
+}
+
+// unfiltered trả về bảng đầu tiên của scope không có so sánh tenant_id riêng. tenant_id không có tiền tố lọc được
+// một bảng không có alias, hoặc bảng duy nhất của scope.
+func (s *sqlScope) unfiltered() string {
+	if s.insert != "" && !s.insertTenant {
+		return s.insert
+	}
+	unqualified := s.filters[""]
+	for _, table := range s.tables {
+		if s.filters[table.alias] > 0 && table.alias != "" || s.filters[table.name] > 0 {
+			continue
+		}
+		if (table.alias == "" || len(s.tables) == 1) && unqualified > 0 {
+			unqualified--
+			continue
+		}
+		return table.name
+	}
+	return ""
+}
+
+func (g tenantGuard) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if err := g.check(sql); err != nil {
+		return nil, err
+	}
+	return g.q.Exec(ctx, sql, args...)
+}
+
+func (g tenantGuard) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	if err := g.check(sql); err != nil {
+		return nil, err
+	}
+	return g.q.Query(ctx, sql, args...)
+}
+
+func (g tenantGuard) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if err := g.check(sql); err != nil {
+		return errRow{err}
+	}
+	return g.q.QueryRow(ctx, sql, args...)
+}
+
+// errRow là pgx.Row của một câu lệnh bị từ chối trước khi chạy.
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+	return r.err
+}
+
+// TenantTodoService tách dữ liệu của các tenant bằng cách giữ một TodoService riêng cho mỗi tenant, tạo khi tenant
+// ghi lần đầu. Mỗi tenant có store riêng nên không có điều kiện lọc nào để quên; dùng cho backend memory.
+type TenantTodoService struct {
+	mu        sync.Mutex
+	tenants   map[string]TodoService
+	newTenant func() TodoService
+	// empty trả lời các thao tác đọc của tenant chưa có store và không bao giờ bị ghi.
+	empty TodoService
+	// workflow là workflow chung của mọi tenant, lấy từ store đầu tiên khi tạo TenantTodoService.
+	workflow *Workflow
+}
+
+func NewTenantTodoService(newTenant func() TodoService) *TenantTodoService {
+	empty := newTenant()
+	return &TenantTodoService{tenants: make(map[string]TodoService), newTenant: newTenant, empty: empty, workflow: empty.Workflow()}
+}
+
+// view trả về TodoService của tenant trong ctx cho thao tác chỉ đọc. Tenant chưa có store được trả lời từ store rỗng
+// dùng chung, để request đọc với một X-Tenant-ID bất kỳ không tạo thêm store.
+func (s *TenantTodoService) view(ctx context.Context) TodoService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if svc, ok := s.tenants[tenantFrom(ctx)]; ok {
+		return svc
+	}
+	return s.empty
+}
+
+// of trả về TodoService của tenant trong ctx cho thao tác ghi, tạo store khi tenant chưa có.
+func (s *TenantTodoService) of(ctx context.Context) TodoService {
+	tenantID := tenantFrom(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	svc, ok := s.tenants[tenantID]
+	if !ok {
+		svc = s.newTenant()
+		s.tenants[tenantID] = svc
+	}
+	return svc
+}
+
+// each gọi fn với ctx và TodoService của từng tenant đã có dữ liệu, theo thứ tự ID.
+func (s *TenantTodoService) each(ctx context.Context, fn func(ctx context.Context, svc TodoService) error) error {
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.tenants))
+	for id := range s.tenants {
+		if id != anyTenant {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+	sort.Strings(ids)
+	for _, id := range ids {
+		ctx := withTenant(ctx, id)
+		if err := fn(ctx, s.of(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TenantTodoService) GetAllTodo(ctx context.Context, query TodoQuery) (*TodoPage, error) {
+	return s.view(ctx).GetAllTodo(ctx, query)
+}
+
+func (s *TenantTodoService) GetTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.view(ctx).GetTodo(ctx, id)
+}
+
+func (s *TenantTodoService) CreateTodo(ctx context.Context, todo Todo) (*Todo, error) {
+	return s.of(ctx).CreateTodo(ctx, todo)
+}
+
+func (s *TenantTodoService) UpdateTodo(ctx context.Context, id string, todo Todo) (*Todo, error) {
+	return s.of(ctx).UpdateTodo(ctx, id, todo)
+}
+
+func (s *TenantTodoService) PatchTodo(ctx context.Context, id string, patch TodoPatch) (*Todo, error) {
+	return s.of(ctx).PatchTodo(ctx, id, patch)
+}
+
+func (s *TenantTodoService) UpdateTodoStatus(ctx context.Context, id string) (*Todo, error) {
+	return s.of(ctx).UpdateTodoStatus(ctx, id)
+}
+
+func (s *TenantTodoService) CompleteTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.of(ctx).CompleteTodo(ctx, id)
+}
+
+func (s *TenantTodoService) ReopenTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.of(ctx).ReopenTodo(ctx, id)
+}
+
+func (s *TenantTodoService) TransitionTodo(ctx context.Context, id string, status Status) (*Todo, error) {
+	return s.of(ctx).TransitionTodo(ctx, id, status)
+}
+
+// Workflow giống nhau ở mọi tenant.
+func (s *TenantTodoService) Workflow() *Workflow {
+	return s.workflow
+}
+
+func (s *TenantTodoService) DeleteTodo(ctx context.Context, id string) error {
+	return s.of(ctx).DeleteTodo(ctx, id)
+}
+
+func (s *TenantTodoService) BulkCreateTodos(ctx context.Context, todos []Todo, opts BulkOptions) ([]BulkResult, error) {
+	return s.of(ctx).BulkCreateTodos(ctx, todos, opts)
+}
+
+func (s *TenantTodoService) BulkSetDone(ctx context.Context, items []BulkItem, done bool, opts BulkOptions) ([]BulkResult, error) {
+	return s.of(ctx).BulkSetDone(ctx, items, done, opts)
+}
+
+func (s *TenantTodoService) BulkPatchTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	return s.of(ctx).BulkPatchTodos(ctx, items, opts)
+}
+
+func (s *TenantTodoService) BulkDeleteTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error) {
+	return s.of(ctx).BulkDeleteTodos(ctx, items, opts)
+}
+
+func (s *TenantTodoService) ListTags(ctx context.Context) ([]Tag, error) {
+	return s.view(ctx).ListTags(ctx)
+}
+
+func (s *TenantTodoService) GetTag(ctx context.Context, id string) (*Tag, error) {
+	return s.view(ctx).GetTag(ctx, id)
+}
+
+func (s *TenantTodoService) CreateTag(ctx context.Context, tag Tag) (*Tag, error) {
+	return s.of(ctx).CreateTag(ctx, tag)
+}
+
+func (s *TenantTodoService) UpdateTag(ctx context.Context, id string, tag Tag) (*Tag, error) {
+	return s.of(ctx).UpdateTag(ctx, id, tag)
+}
+
+func (s *TenantTodoService) DeleteTag(ctx context.Context, id string) error {
+	return s.of(ctx).DeleteTag(ctx, id)
+}
+
+func (s *TenantTodoService) TagRole(ctx context.Context, id string) (Role, error) {
+	return s.view(ctx).TagRole(ctx, id)
+}
+
+func (s *TenantTodoService) ListProjects(ctx context.Context) ([]Project, error) {
+	return s.view(ctx).ListProjects(ctx)
+}
+
+func (s *TenantTodoService) GetProject(ctx context.Context, id string) (*Project, error) {
+	return s.view(ctx).GetProject(ctx, id)
+}
+
+func (s *TenantTodoService) CreateProject(ctx context.Context, project Project) (*Project, error) {
+	return s.of(ctx).CreateProject(ctx, project)
+}
+
+func (s *TenantTodoService) UpdateProject(ctx context.Context, id string, project Project) (*Project, error) {
+	return s.of(ctx).UpdateProject(ctx, id, project)
+}
+
+func (s *TenantTodoService) DeleteProject(ctx context.Context, id string) error {
+	return s.of(ctx).DeleteProject(ctx, id)
+}
+
+func (s *TenantTodoService) ProjectRole(ctx context.Context, id string) (Role, error) {
+	return s.view(ctx).ProjectRole(ctx, id)
+}
+
+func (s *TenantTodoService) ListProjectShares(ctx context.Context, projectID string) ([]ProjectShare, error) {
+	return s.view(ctx).ListProjectShares(ctx, projectID)
+}
+
+func (s *TenantTodoService) ShareProject(ctx context.Context, projectID, userID string, role Role) (*ProjectShare, error) {
+	return s.of(ctx).ShareProject(ctx, projectID, userID, role)
+}
+
+func (s *TenantTodoService) UnshareProject(ctx context.Context, projectID, userID string) error {
+	return s.of(ctx).UnshareProject(ctx, projectID, userID)
+}
+
+func (s *TenantTodoService) ListChecklist(ctx context.Context, todoID string) ([]ChecklistItem, error) {
+	return s.view(ctx).ListChecklist(ctx, todoID)
+}
+
+func (s *TenantTodoService) GetChecklistItem(ctx context.Context, todoID, itemID string) (*ChecklistItem, error) {
+	return s.view(ctx).GetChecklistItem(ctx, todoID, itemID)
+}
+
+func (s *TenantTodoService) AddChecklistItem(ctx context.Context, todoID string, item ChecklistItemPatch) (*ChecklistItem, error) {
+	return s.of(ctx).AddChecklistItem(ctx, todoID, item)
+}
+
+func (s *TenantTodoService) UpdateChecklistItem(ctx context.Context, todoID, itemID string, patch ChecklistItemPatch) (*ChecklistItem, error) {
+	return s.of(ctx).UpdateChecklistItem(ctx, todoID, itemID, patch)
+}
+
+func (s *TenantTodoService) DeleteChecklistItem(ctx context.Context, todoID, itemID string) error {
+	return s.of(ctx).DeleteChecklistItem(ctx, todoID, itemID)
+}
+
+func (s *TenantTodoService) AddBlocker(ctx context.Context, todoID, blockerID string) (*Todo, error) {
+	return s.of(ctx).AddBlocker(ctx, todoID, blockerID)
+}
+
+func (s *TenantTodoService) RemoveBlocker(ctx context.Context, todoID, blockerID string) error {
+	return s.of(ctx).RemoveBlocker(ctx, todoID, blockerID)
+}
+
+func (s *TenantTodoService) OrderProjectTodos(ctx context.Context, projectID string) ([]Todo, error) {
+	return s.view(ctx).OrderProjectTodos(ctx, projectID)
+}
+
+func (s *TenantTodoService) GetSeries(ctx context.Context, seriesID string) ([]Todo, error) {
+	return s.view(ctx).GetSeries(ctx, seriesID)
+}
+
+func (s *TenantTodoService) PatchSeries(ctx context.Context, seriesID string, patch TodoPatch) ([]Todo, error) {
+	return s.of(ctx).PatchSeries(ctx, seriesID, patch)
+}
+
+func (s *TenantTodoService) RestoreTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.of(ctx).RestoreTodo(ctx, id)
+}
+
+func (s *TenantTodoService) PurgeTodo(ctx context.Context, id string) error {
+	return s.of(ctx).PurgeTodo(ctx, id)
+}
+
+// PurgeTrash với ctx của withAllTenants dọn thùng rác của từng tenant.
+func (s *TenantTodoService) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if !forAllTenants(ctx) {
+		return s.of(ctx).PurgeTrash(ctx, before)
+	}
+	total := 0
+	err := s.each(ctx, func(ctx context.Context, svc TodoService) error {
+		purged, err := svc.PurgeTrash(ctx, before)
+		total += purged
+		return err
+	})
+	return total, err
+}
+
+func (s *TenantTodoService) TodoHistory(ctx context.Context, todoID string) ([]HistoryEntry, error) {
+	return s.view(ctx).TodoHistory(ctx, todoID)
+}
+
+func (s *TenantTodoService) ListActivity(ctx context.Context, query ActivityQuery) (*ActivityPage, error) {
+	return s.view(ctx).ListActivity(ctx, query)
+}
+
+func (s *TenantTodoService) ListUsers(ctx context.Context) ([]User, error) {
+	return s.view(ctx).ListUsers(ctx)
+}
+
+func (s *TenantTodoService) GetUser(ctx context.Context, id string) (*User, error) {
+	return s.view(ctx).GetUser(ctx, id)
+}
+
+func (s *TenantTodoService) CreateUser(ctx context.Context, user User) (*User, error) {
+	return s.of(ctx).CreateUser(ctx, user)
+}
+
+func (s *TenantTodoService) ListShares(ctx context.Context, todoID string) ([]TodoShare, error) {
+	return s.view(ctx).ListShares(ctx, todoID)
+}
+
+func (s *TenantTodoService) ShareTodo(ctx context.Context, todoID, userID string, role Role) (*TodoShare, error) {
+	return s.of(ctx).ShareTodo(ctx, todoID, userID, role)
+}
+
+func (s *TenantTodoService) UnshareTodo(ctx context.Context, todoID, userID string) error {
+	return s.of(ctx).UnshareTodo(ctx, todoID, userID)
+}
+
+func (s *TenantTodoService) TodoRole(ctx context.Context, id string) (Role, error) {
+	return s.view(ctx).TodoRole(ctx, id)
+}
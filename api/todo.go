@@ -6,161 +6,1034 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v4"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
+// Todo là một công việc. Done và DoneAt được suy ra từ Status (done khi và chỉ khi status là "done")
+// và được giữ lại cho client cũ; StatusTimes là thời điểm gần nhất todo chuyển vào từng trạng thái.
+// DueAt và Estimate (số phút) là tùy chọn. Tags là tên các tag theo thứ tự chữ cái.
+// ProjectID rỗng khi tạo nghĩa là DefaultProjectID. Checklist là tiến độ các item của todo, do server tính.
+// BlockedBy là ID các todo phải done trước khi todo này được done, theo thứ tự ID.
+// Todo có Recurrence là một occurrence của series SeriesID (Occurrence đếm từ 1); done nó sinh ra occurrence tiếp theo.
+// DeletedAt khác nil khi todo nằm trong thùng rác. OwnerID là người dùng tạo todo (xem User), rỗng với todo
+// được tạo không kèm người dùng; todo như vậy hiện với mọi người dùng.
 type Todo struct {
-	ID        string     `json:"id"`
-	Title     string     `json:"title"`
-	Desc      string     `json:"desc"`
-	Done      bool       `json:"done"`
-	CreatedAt time.Time  `json:"created_at"`
-	DoneAt    *time.Time `json:"done_at"`
+	ID          string               `json:"id"`
+	Title       string               `json:"title"`
+	Desc        string               `json:"desc"`
+	ProjectID   string               `json:"project_id" example:"default"`
+	OwnerID     string               `json:"owner_id"`
+	Done        bool                 `json:"done"`
+	Status      Status               `json:"status" example:"in_progress"`
+	Priority    Priority             `json:"priority" swaggertype:"string" enums:"low,normal,high,urgent" example:"high"`
+	DueAt       *time.Time           `json:"due_at"`
+	Estimate    *int                 `json:"estimate" example:"90"`
+	Tags        []string             `json:"tags" example:"frontend,qr"`
+	Checklist   ChecklistProgress    `json:"checklist"`
+	BlockedBy   []string             `json:"blocked_by"`
+	Recurrence  *Recurrence          `json:"recurrence" swaggertype:"string" example:"FREQ=WEEKLY;BYDAY=MO"`
+	SeriesID    string               `json:"series_id"`
+	Occurrence  int                  `json:"occurrence" example:"1"`
+	CreatedAt   time.Time            `json:"created_at"`
+	DoneAt      *time.Time           `json:"done_at"`
+	StatusTimes map[Status]time.Time `json:"status_times"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+	DeletedAt   *time.Time           `json:"deleted_at"`
+	Version     int                  `json:"version"`
+}
+
+const maxTitleLength = 255
+
+// TodoPatch là cập nhật một phần: trường nil được giữ nguyên giá trị hiện tại.
+// Status và Done cùng mô tả trạng thái, xem Workflow.target.
+// DueAt và Estimate có thể xóa được: nil là giữ nguyên, trỏ tới nil là xóa giá trị.
+// Tags thay toàn bộ danh sách tag. Recurrence trỏ tới nil là bỏ lịch lặp.
+type TodoPatch struct {
+	Title      *string
+	Desc       *string
+	Done       *bool
+	Status     *Status
+	Priority   *Priority
+	DueAt      **time.Time
+	Estimate   **int
+	Tags       *[]string
+	ProjectID  *string
+	Recurrence **Recurrence
+}
+
+func (p TodoPatch) empty() bool {
+	return p.Title == nil && p.Desc == nil && p.Done == nil && p.Status == nil &&
+		p.Priority == nil && p.DueAt == nil && p.Estimate == nil && p.Tags == nil && p.ProjectID == nil &&
+		p.Recurrence == nil
 }
 
 type TodoService interface {
-	GetAllTodo(ctx context.Context) ([]Todo, error)
+	GetAllTodo(ctx context.Context, query TodoQuery) (*TodoPage, error)
 	GetTodo(ctx context.Context, id string) (*Todo, error)
 	CreateTodo(ctx context.Context, todo Todo) (*Todo, error)
 	UpdateTodo(ctx context.Context, id string, todo Todo) (*Todo, error)
+	PatchTodo(ctx context.Context, id string, patch TodoPatch) (*Todo, error)
+	// DeleteTodo chuyển todo vào thùng rác: mọi phương thức khác coi nó như không tồn tại cho tới khi được khôi phục.
 	DeleteTodo(ctx context.Context, id string) error
-	UpdateTodoStatus(ctx context.Context, id string) error
+	UpdateTodoStatus(ctx context.Context, id string) (*Todo, error)
+	CompleteTodo(ctx context.Context, id string) (*Todo, error)
+	ReopenTodo(ctx context.Context, id string) (*Todo, error)
+	// TransitionTodo chuyển todo sang status theo Workflow; trả về ErrInvalidTransition nếu bước chuyển không được phép.
+	TransitionTodo(ctx context.Context, id string, status Status) (*Todo, error)
+	Workflow() *Workflow
+
+	// RestoreTodo đưa todo ra khỏi thùng rác; thùng rác được liệt kê bằng GetAllTodo với TodoQuery.Trashed.
+	RestoreTodo(ctx context.Context, id string) (*Todo, error)
+	// PurgeTodo xóa hẳn một todo trong thùng rác; trả về ErrNotTrashed nếu todo chưa bị xóa.
+	PurgeTodo(ctx context.Context, id string) error
+	// PurgeTrash xóa hẳn các todo vào thùng rác trước before, trả về số todo bị xóa.
+	PurgeTrash(ctx context.Context, before time.Time) (int, error)
+
+	ListTags(ctx context.Context) ([]Tag, error)
+	GetTag(ctx context.Context, id string) (*Tag, error)
+	CreateTag(ctx context.Context, tag Tag) (*Tag, error)
+	// UpdateTag đổi tên/màu của tag; đổi tên tăng version của mọi todo mang tag.
+	UpdateTag(ctx context.Context, id string, tag Tag) (*Tag, error)
+	DeleteTag(ctx context.Context, id string) error
+	// TagRole trả về vai trò thấp nhất của người dùng trong ctx trên các todo mang tag.
+	TagRole(ctx context.Context, id string) (Role, error)
+
+	ListProjects(ctx context.Context) ([]Project, error)
+	GetProject(ctx context.Context, id string) (*Project, error)
+	CreateProject(ctx context.Context, project Project) (*Project, error)
+	UpdateProject(ctx context.Context, id string, project Project) (*Project, error)
+	// DeleteProject trả về ErrProjectNotEmpty nếu project còn todo và ErrDefaultProject với project mặc định.
+	DeleteProject(ctx context.Context, id string) error
+	// ProjectRole trả về vai trò của người dùng trong ctx trên project: owner với project chưa chia sẻ với ai,
+	// roleNone khi project đã được chia sẻ nhưng không với họ.
+	ProjectRole(ctx context.Context, id string) (Role, error)
+	ListProjectShares(ctx context.Context, projectID string) ([]ProjectShare, error)
+	// ShareProject trả về ErrShareDefaultProject với project mặc định.
+	ShareProject(ctx context.Context, projectID, userID string, role Role) (*ProjectShare, error)
+	UnshareProject(ctx context.Context, projectID, userID string) error
+
+	ListChecklist(ctx context.Context, todoID string) ([]ChecklistItem, error)
+	GetChecklistItem(ctx context.Context, todoID, itemID string) (*ChecklistItem, error)
+	AddChecklistItem(ctx context.Context, todoID string, patch ChecklistItemPatch) (*ChecklistItem, error)
+	// UpdateChecklistItem tự chuyển todo sang done khi item cuối cùng được check (xem checklistCompletes).
+	UpdateChecklistItem(ctx context.Context, todoID, itemID string, patch ChecklistItemPatch) (*ChecklistItem, error)
+	DeleteChecklistItem(ctx context.Context, todoID, itemID string) error
+
+	// AddBlocker ghi "todoID bị chặn bởi blockerID"; trả về ErrDependencyCycle nếu cạnh mới tạo thành chu trình.
+	// Todo còn blocker chưa done thì không chuyển sang done được (BlockedError).
+	AddBlocker(ctx context.Context, todoID, blockerID string) (*Todo, error)
+	RemoveBlocker(ctx context.Context, todoID, blockerID string) error
+	// OrderProjectTodos trả về các todo của project, mỗi todo đứng sau mọi blocker của nó.
+	OrderProjectTodos(ctx context.Context, projectID string) ([]Todo, error)
+
+	// GetSeries trả về các occurrence của một todo lặp lại theo thứ tự occurrence.
+	GetSeries(ctx context.Context, seriesID string) ([]Todo, error)
+	// PatchSeries sửa mọi occurrence còn phải làm của series; status, done và due_at không được phép.
+	PatchSeries(ctx context.Context, seriesID string, patch TodoPatch) ([]Todo, error)
+
+	// TodoHistory trả về lịch sử thay đổi của todo, cũ nhất trước; lịch sử còn lại sau khi todo bị xóa hẳn.
+	TodoHistory(ctx context.Context, todoID string) ([]HistoryEntry, error)
+	// ListActivity trả về lịch sử của mọi todo, mới nhất trước.
+	ListActivity(ctx context.Context, query ActivityQuery) (*ActivityPage, error)
+
+	ListUsers(ctx context.Context) ([]User, error)
+	GetUser(ctx context.Context, id string) (*User, error)
+	// CreateUser trả về ErrEmailTaken nếu email đã được đăng ký. ID do caller đặt được giữ nguyên (người dùng
+	// tạo từ subject của JWT), ID rỗng thì được sinh mới.
+	CreateUser(ctx context.Context, user User) (*User, error)
+	// Người được chia sẻ todo hoặc project của nó thấy todo; mọi phương thức khác coi todo người dùng trong ctx
+	// không được thấy (xem userFrom) như không tồn tại. Vai trò chỉ được kiểm tra ở policy của APIHandler (xem authorize).
+	ListShares(ctx context.Context, todoID string) ([]TodoShare, error)
+	ShareTodo(ctx context.Context, todoID, userID string, role Role) (*TodoShare, error)
+	UnshareTodo(ctx context.Context, todoID, userID string) error
+	// TodoRole trả về vai trò của người dùng trong ctx trên todo, kể cả todo trong thùng rác.
+	TodoRole(ctx context.Context, id string) (Role, error)
+
+	BulkCreateTodos(ctx context.Context, todos []Todo, opts BulkOptions) ([]BulkResult, error)
+	BulkSetDone(ctx context.Context, items []BulkItem, done bool, opts BulkOptions) ([]BulkResult, error)
+	BulkPatchTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error)
+	BulkDeleteTodos(ctx context.Context, items []BulkItem, opts BulkOptions) ([]BulkResult, error)
 }
 
 type DbTodoService struct {
 	db *Db
-	mu sync.Mutex
+	// tx khác nil khi service đang chạy bên trong một transaction (xem runBulk).
+	tx       querier
+	workflow *Workflow
+}
+
+type MemoryTodoService struct {
+	mu    sync.RWMutex
+	todos map[string]Todo
+	// trash là các todo trong thùng rác; checklist của chúng vẫn nằm trong checklists để được khôi phục cùng.
+	trash      map[string]Todo
+	tags       map[string]Tag
+	projects   map[string]Project
+	checklists map[string][]ChecklistItem
+	// history chỉ được ghi thêm, theo thứ tự thời gian (xem recordLocked).
+	history []HistoryEntry
+	users   map[string]User
+	// shares là các lần chia sẻ của từng todo theo thứ tự user_id, projectShares tương tự với project.
+	shares        map[string][]TodoShare
+	projectShares map[string][]ProjectShare
+	workflow      *Workflow
 }
 
 func NewDbTodoService(db *Db) *DbTodoService {
 	return &DbTodoService{
-		db: db,
+		db:       db,
+		workflow: DefaultWorkflow(),
+	}
+}
+
+// conn trả về transaction hiện tại nếu có, nếu không thì dùng pool. Mọi câu lệnh đi qua tenantGuard nên phải lọc
+// hoặc ghi cột tenant_id.
+func (s *DbTodoService) conn() querier {
+	if s.tx != nil {
+		return tenantGuard{s.tx}
+	}
+	return tenantGuard{s.db.conn}
+}
+
+// NewMemoryTodoService tạo TodoService lưu dữ liệu trong bộ nhớ, dùng để demo và test không cần database.
+func NewMemoryTodoService() *MemoryTodoService {
+	now := time.Now()
+	return &MemoryTodoService{
+		todos:         make(map[string]Todo),
+		trash:         make(map[string]Todo),
+		tags:          make(map[string]Tag),
+		projects:      map[string]Project{DefaultProjectID: {ID: DefaultProjectID, Name: "Default", CreatedAt: now, UpdatedAt: now}},
+		checklists:    make(map[string][]ChecklistItem),
+		users:         make(map[string]User),
+		shares:        make(map[string][]TodoShare),
+		projectShares: make(map[string][]ProjectShare),
+		workflow:      DefaultWorkflow(),
+	}
+}
+
+// todoColumns là danh sách cột theo đúng thứ tự mà scanTodo đọc.
+const todoColumns = "id, title, description, done, created_at, done_at, updated_at, version, status, status_times, priority, due_at, estimate, project_id, recurrence, series_id, occurrence, deleted_at, owner_id"
+
+func scanTodo(row pgx.Row, todo *Todo) error {
+	var status string
+	var statusTimes []byte
+	var priority int
+	var recurrence, seriesID, ownerID *string
+	if err := row.Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt, &todo.DoneAt, &todo.UpdatedAt, &todo.Version,
+		&status, &statusTimes, &priority, &todo.DueAt, &todo.Estimate, &todo.ProjectID, &recurrence, &seriesID, &todo.Occurrence, &todo.DeletedAt,
+		&ownerID); err != nil {
+		return err
+	}
+	todo.Priority = Priority(priority)
+	todo.Recurrence = nil
+	if recurrence != nil {
+		rule, err := ParseRecurrence(*recurrence)
+		if err != nil {
+			return fmt.Errorf("đọc recurrence của todo %s thất bại: %w", todo.ID, err)
+		}
+		todo.Recurrence = rule
+	}
+	todo.SeriesID = ""
+	if seriesID != nil {
+		todo.SeriesID = *seriesID
+	}
+	todo.OwnerID = ""
+	if ownerID != nil {
+		todo.OwnerID = *ownerID
+	}
+	times, err := decodeStatusTimes(statusTimes)
+	if err != nil {
+		return fmt.Errorf("đọc status_times của todo %s thất bại: %w", todo.ID, err)
+	}
+	todo.Status = Status(status)
+	todo.StatusTimes = times
+	return nil
+}
+
+// queryTodos chạy một câu SELECT todoColumns và đọc kết quả cùng các phần ở bảng khác (xem loadDetails).
+func (s *DbTodoService) queryTodos(ctx context.Context, sql string, args ...interface{}) ([]Todo, error) {
+	rows, err := s.conn().Query(ctx, sql, args...)
+	if err != nil {
+		return nil, dbError("truy vấn thất bại", err)
+	}
+	defer rows.Close()
+
+	todos := []Todo{}
+	for rows.Next() {
+		var todo Todo
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, dbError("scan thất bại", err)
+		}
+		todos = append(todos, todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc rows", err)
+	}
+	items := make([]*Todo, 0, len(todos))
+	for i := range todos {
+		items = append(items, &todos[i])
+	}
+	if err := s.loadDetails(ctx, items...); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// loadDetails đọc các phần của todo nằm ở bảng khác (tag, tiến độ checklist, blocker).
+func (s *DbTodoService) loadDetails(ctx context.Context, todos ...*Todo) error {
+	if err := s.loadTags(ctx, todos...); err != nil {
+		return err
+	}
+	if err := s.loadChecklistProgress(ctx, todos...); err != nil {
+		return err
 	}
+	return s.loadBlockers(ctx, todos...)
 }
+
 func generateNewID() string {
 	return uuid.New().String()
 }
 
-func (s *DbTodoService) GetAllTodo(ctx context.Context) ([]Todo, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// validateTodo kiểm tra các ràng buộc của bảng todo trước khi ghi.
+func validateTodo(todo Todo) error {
+	verr := &ValidationError{}
+	validateTitle(verr, todo.Title)
+	validatePlanning(verr, todo.Priority, todo.Estimate)
+	validateTags(verr, todo.Tags)
+	validateRecurrence(verr, todo.Recurrence, todo.DueAt)
+	return verr.Err()
+}
+
+func validateTitle(verr *ValidationError, title string) {
+	if strings.TrimSpace(title) == "" {
+		verr.Add("title", "required", "title is required")
+	} else if utf8.RuneCountInString(title) > maxTitleLength {
+		verr.Add("title", "too_long", fmt.Sprintf("title must be at most %d characters", maxTitleLength))
+	}
+}
+
+// storedTime làm tròn thời điểm về micro giây theo UTC như cột TIMESTAMP lưu, để hai backend trả về cùng giá trị.
+func storedTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	stored := t.UTC().Truncate(time.Microsecond)
+	return &stored
+}
+
+// updateCurrent đọc todo rồi chạy update với điều kiện version vừa đọc, cho các thay đổi phụ thuộc
+// giá trị hiện tại (chuyển trạng thái, toggle). Khi client không gửi If-Match mà todo bị ghi xen giữa
+// lúc đọc và ghi thì đọc lại và thử lại vài lần; thay đổi xen giữa không bao giờ bị ghi đè.
+func updateCurrent(ctx context.Context, get func(ctx context.Context, id string) (*Todo, error), id string,
+	update func(ctx context.Context, current *Todo) (*Todo, error)) (*Todo, error) {
+	pinned := expectedVersion(ctx) != 0
+	for attempt := 1; ; attempt++ {
+		current, err := get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(ctx, current.Version); err != nil {
+			return nil, err
+		}
+
+		todo, err := update(withExpectedVersion(ctx, current.Version), current)
+		if errors.Is(err, ErrPreconditionFailed) && !pinned {
+			if attempt < 3 {
+				continue
+			}
+			return nil, fmt.Errorf("todo %s bị thay đổi liên tục trong lúc cập nhật: %w", id, ErrConflict)
+		}
+		return todo, err
+	}
+}
+
+// toggleTodo đảo trạng thái done bằng CompleteTodo hoặc ReopenTodo (xem updateCurrent).
+func toggleTodo(ctx context.Context, svc TodoService, id string) (*Todo, error) {
+	return updateCurrent(ctx, svc.GetTodo, id, func(ctx context.Context, current *Todo) (*Todo, error) {
+		if current.Done {
+			return svc.ReopenTodo(ctx, id)
+		}
+		return svc.CompleteTodo(ctx, id)
+	})
+}
+
+// replacePatch chuyển payload của UpdateTodo (PUT) thành patch ghi mọi trường, kể cả xóa due_at, estimate, tag và lịch lặp.
+// Khi có Status thì status quyết định, Done chỉ dùng cho client cũ không gửi status.
+// ProjectID rỗng giữ nguyên project: PUT không làm todo rơi về project mặc định.
+func replacePatch(todo Todo) TodoPatch {
+	patch := TodoPatch{Title: &todo.Title, Desc: &todo.Desc, Priority: &todo.Priority, DueAt: &todo.DueAt, Estimate: &todo.Estimate, Tags: &todo.Tags,
+		Recurrence: &todo.Recurrence}
+	if todo.Status != "" {
+		patch.Status = &todo.Status
+	} else {
+		patch.Done = &todo.Done
+	}
+	if todo.ProjectID != "" {
+		patch.ProjectID = &todo.ProjectID
+	}
+	return patch
+}
+
+// validatePatch áp dụng cùng ràng buộc với validateTodo nhưng chỉ cho các trường có trong patch.
+func validatePatch(patch TodoPatch) error {
+	verr := &ValidationError{}
+	if patch.Title != nil {
+		validateTitle(verr, *patch.Title)
+	}
+	if patch.Priority != nil {
+		validatePlanning(verr, *patch.Priority, nil)
+	}
+	if patch.Estimate != nil {
+		validatePlanning(verr, PriorityNormal, *patch.Estimate)
+	}
+	if patch.Tags != nil {
+		validateTags(verr, *patch.Tags)
+	}
+	if patch.Recurrence != nil && *patch.Recurrence != nil {
+		if err := (*patch.Recurrence).validate(); err != nil {
+			verr.Add("recurrence", "invalid_format", "recurrence is not a supported RRULE: "+err.Error())
+		}
+	}
+	return verr.Err()
+}
+
+func (s *DbTodoService) GetAllTodo(ctx context.Context, query TodoQuery) (*TodoPage, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	rows, err := s.db.conn.Query(ctx, "SELECT id, title, description, done, created_at FROM todo ORDER BY created_at DESC")
+	query, cur, err := query.normalize()
 	if err != nil {
-		return nil, fmt.Errorf("truy vấn thất bại: %v", err)
+		return nil, err
+	}
+	keys := query.sortKeys()
+
+	where := &sqlWhere{}
+	query.where(ctx, where)
+	visibleTodos(ctx, where, "")
+	if cur != nil {
+		keyset(where, keys, cur)
+	}
+	sql := "SELECT " + todoColumns + " FROM todo" + where.String() +
+		orderBy(keys, cur != nil && cur.Before) + " LIMIT " + strconv.Itoa(query.Limit+1)
+
+	rows, err := s.conn().Query(ctx, sql, where.args...)
+	if err != nil {
+		return nil, dbError("truy vấn thất bại", err)
 	}
 
 	defer rows.Close()
 
-	var todos []Todo
+	todos := []Todo{}
 
 	for rows.Next() {
 		var todo Todo
-		err := rows.Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt)
-		if err != nil {
-			return nil, fmt.Errorf("scan thất bại: %v", err)
+		if err := scanTodo(rows, &todo); err != nil {
+			return nil, dbError("scan thất bại", err)
 		}
 		todos = append(todos, todo)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("lỗi sau khi đọc rows: %v", err)
+		return nil, dbError("lỗi sau khi đọc rows", err)
 	}
-	return todos, nil
+	page := newTodoPage(todos, query, cur)
+	items := make([]*Todo, 0, len(page.Items))
+	for i := range page.Items {
+		items = append(items, &page.Items[i])
+	}
+	if err := s.loadDetails(ctx, items...); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 func (s *DbTodoService) GetTodo(ctx context.Context, id string) (*Todo, error) {
 	var todo Todo
-	err := s.db.conn.QueryRow(context.Background(), "SELECT id, title, description, done, created_at, done_at FROM todo WHERE id = $1", id).Scan(&todo.ID, &todo.Title, &todo.Desc, &todo.Done, &todo.CreatedAt, &todo.DoneAt)
+	w := &sqlWhere{}
+	liveTodo(ctx, w, id)
+	err := scanTodo(s.conn().QueryRow(ctx, "SELECT "+todoColumns+" FROM todo"+w.String(), w.args...), &todo)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, errors.New("not found users")
+			return nil, todoNotFound(id)
 		}
-		return nil, fmt.Errorf("truy vấn thất bại: %v", err)
+		return nil, dbError("truy vấn thất bại", err)
+	}
+	if err := s.loadDetails(ctx, &todo); err != nil {
+		return nil, err
 	}
 	return &todo, nil
 }
+func (s *DbTodoService) Workflow() *Workflow {
+	return s.workflow
+}
+
 func (s *DbTodoService) CreateTodo(ctx context.Context, todo Todo) (*Todo, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := validateTodo(todo); err != nil {
+		return nil, err
+	}
+	startSeries(&todo)
+	todo.OwnerID = userFrom(ctx)
+	return s.insert(ctx, todo)
+}
+
+// insert ghi một todo mới đã được validate; SeriesID, Occurrence và OwnerID do caller gán (xem startSeries, spawnNext).
+func (s *DbTodoService) insert(ctx context.Context, todo Todo) (*Todo, error) {
 	todo.ID = generateNewID()
-	todo.Done = false
-	_, err := s.db.conn.Exec(context.Background(),
-		"INSERT INTO todo (id, title, description, done, created_at) VALUES ($1, $2, $3, $4, $5)",
-		todo.ID, todo.Title, todo.Desc, todo.Done, time.Now())
+	todo.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	todo.UpdatedAt = todo.CreatedAt
+	todo.Version = 1
+	todo.DueAt = storedTime(todo.DueAt)
+	todo.Tags = normalizeTags(todo.Tags)
+	todo.Checklist = ChecklistProgress{}
+	todo.BlockedBy = []string{}
+	if todo.ProjectID == "" {
+		todo.ProjectID = DefaultProjectID
+	}
+	s.workflow.start(&todo, todo.CreatedAt)
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		if err := svc.checkProject(ctx, todo.ProjectID); err != nil {
+			return err
+		}
+		_, err := svc.conn().Exec(ctx,
+			"INSERT INTO todo (id, title, description, done, created_at, updated_at, version, status, status_times, priority, due_at, estimate, project_id, "+
+				"recurrence, series_id, occurrence, owner_id, tenant_id) "+
+				"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NULLIF($15, ''), $16, NULLIF($17, ''), $18)",
+			todo.ID, todo.Title, todo.Desc, todo.Done, todo.CreatedAt, todo.UpdatedAt, todo.Version,
+			string(todo.Status), encodeStatusTimes(todo.StatusTimes), int(todo.Priority), todo.DueAt, todo.Estimate, todo.ProjectID,
+			recurrenceValue(todo.Recurrence), todo.SeriesID, todo.Occurrence, todo.OwnerID, tenantFrom(ctx))
+		if isForeignKeyViolation(err) {
+			return unknownProject(todo.ProjectID)
+		}
+		if err != nil {
+			return dbError("thêm todo thất bại", err)
+		}
+		if len(todo.Tags) > 0 {
+			if err := svc.setTags(ctx, todo.ID, todo.Tags); err != nil {
+				return err
+			}
+		}
+		return svc.record(ctx, nil, &todo)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("thêm todo thất bại: %v", err)
+		return nil, err
 	}
 	return &todo, nil
 }
-func (s *DbTodoService) UpdateTodo(ctx context.Context, id string, todo Todo) (*Todo, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
 
-	var doneAt *time.Time
+// UpdateTodo ghi đè title, desc và trạng thái (xem replacePatch).
+func (s *DbTodoService) UpdateTodo(ctx context.Context, id string, todo Todo) (*Todo, error) {
+	if err := validateTodo(todo); err != nil {
+		return nil, err
+	}
+	return s.PatchTodo(ctx, id, replacePatch(todo))
+}
 
-	if todo.Done {
-		now := time.Now()
-		doneAt = &now
-	} else {
-		doneAt = nil
+// PatchTodo chỉ ghi các cột có trong patch. Đổi status hoặc done cần biết trạng thái hiện tại
+// để kiểm tra workflow, đổi recurrence hoặc due_at cần biết trường còn lại (xem checkSchedule),
+// nên được ghi có điều kiện version vừa đọc (xem updateCurrent).
+func (s *DbTodoService) PatchTodo(ctx context.Context, id string, patch TodoPatch) (*Todo, error) {
+	if err := validatePatch(patch); err != nil {
+		return nil, err
+	}
+	if patch.empty() {
+		todo, err := s.GetTodo(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkVersion(ctx, todo.Version); err != nil {
+			return nil, err
+		}
+		return todo, nil
+	}
+	if patch.Status == nil && patch.Done == nil && patch.Recurrence == nil && patch.DueAt == nil {
+		return s.write(ctx, id, patch, nil, false)
 	}
-	_, err := s.db.conn.Exec(context.Background(),
-		"UPDATE todo SET title = $1, description = $2, done = $3, done_at = $4 WHERE id = $5",
-		todo.Title, todo.Desc, todo.Done, doneAt, id) // Thêm doneAt vào câu lệnh
+
+	return updateCurrent(ctx, s.GetTodo, id, func(ctx context.Context, current *Todo) (*Todo, error) {
+		if err := checkSchedule(*current, patch); err != nil {
+			return nil, err
+		}
+		to, err := s.workflow.target(current.Status, patch.Status, patch.Done)
+		if err != nil {
+			return nil, err
+		}
+		completes := to == StatusDone && current.Status != StatusDone
+		if completes {
+			if err := s.checkBlockers(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		moved := *current
+		if err := s.workflow.moveTo(&moved, to, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+			return nil, err
+		}
+		return s.write(ctx, id, patch, &moved, completes)
+	})
+}
+
+// write chạy một câu UPDATE cho các trường không phải trạng thái của patch và, nếu moved khác nil, các cột trạng thái đã tính sẵn
+// (status, status_times, done, done_at). Version luôn tăng và được kiểm tra theo If-Match trong ctx.
+// Dòng todo được khóa và đọc trước trong cùng transaction để ghi lịch sử (xem record), cùng với bảng todo_tag nếu patch có Tags.
+// completes là todo vừa được chuyển sang done: occurrence tiếp theo của series được sinh trong cùng transaction (xem spawnNext).
+func (s *DbTodoService) write(ctx context.Context, id string, patch TodoPatch, moved *Todo, completes bool) (*Todo, error) {
+	var updated *Todo
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		w := &sqlWhere{}
+		liveTodo(ctx, w, id)
+		todos, err := svc.queryTodos(ctx, "SELECT "+todoColumns+" FROM todo"+w.String()+" FOR UPDATE", w.args...)
+		if err != nil {
+			return err
+		}
+		if len(todos) == 0 {
+			return todoNotFound(id)
+		}
+		if updated, err = svc.update(ctx, id, patch, moved); err != nil {
+			return err
+		}
+		if err := svc.record(ctx, &todos[0], updated); err != nil || !completes {
+			return err
+		}
+		return svc.spawnNext(ctx, updated)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("cập nhật todo thất bại: %v", err)
+		return nil, err
 	}
+	return updated, nil
+}
 
-	var updatedTodo Todo
-	err = s.db.conn.QueryRow(context.Background(),
-		"SELECT id, title, description, done, created_at, done_at FROM todo WHERE id = $1", id).
-		Scan(&updatedTodo.ID, &updatedTodo.Title, &updatedTodo.Desc, &updatedTodo.Done, &updatedTodo.CreatedAt, &updatedTodo.DoneAt)
+func (s *DbTodoService) update(ctx context.Context, id string, patch TodoPatch, moved *Todo) (*Todo, error) {
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	q := &sqlWhere{}
+	sets := []string{"version = version + 1", "updated_at = " + q.arg(now)}
+	if patch.Title != nil {
+		sets = append(sets, "title = "+q.arg(*patch.Title))
+	}
+	if patch.Desc != nil {
+		sets = append(sets, "description = "+q.arg(*patch.Desc))
+	}
+	if patch.Priority != nil {
+		sets = append(sets, "priority = "+q.arg(int(*patch.Priority)))
+	}
+	if patch.DueAt != nil {
+		sets = append(sets, "due_at = "+q.arg(storedTime(*patch.DueAt)))
+	}
+	if patch.Estimate != nil {
+		sets = append(sets, "estimate = "+q.arg(*patch.Estimate))
+	}
+	if patch.ProjectID != nil {
+		if err := s.checkProject(ctx, *patch.ProjectID); err != nil {
+			return nil, err
+		}
+		sets = append(sets, "project_id = "+q.arg(*patch.ProjectID))
+	}
+	if patch.Recurrence != nil {
+		sets = append(sets, "recurrence = "+q.arg(recurrenceValue(*patch.Recurrence)))
+		if *patch.Recurrence != nil {
+			// Todo thường được đặt lịch lặp trở thành occurrence đầu tiên của một series mới.
+			sets = append(sets, "series_id = COALESCE(series_id, "+q.arg(generateNewID())+")",
+				"occurrence = GREATEST(occurrence, 1)")
+		}
+	}
+	if moved != nil {
+		sets = append(sets,
+			"status = "+q.arg(string(moved.Status)),
+			"status_times = "+q.arg(encodeStatusTimes(moved.StatusTimes)),
+			"done = "+q.arg(moved.Done),
+			"done_at = "+q.arg(moved.DoneAt))
+	}
+	liveTodo(ctx, q, id)
+	if version := expectedVersion(ctx); version != 0 {
+		q.add("version = ?", version)
+	}
 
+	var updatedTodo Todo
+	err := scanTodo(s.conn().QueryRow(ctx,
+		"UPDATE todo SET "+strings.Join(sets, ", ")+q.String()+" RETURNING "+todoColumns, q.args...), &updatedTodo)
 	if err != nil {
-		return nil, fmt.Errorf("lấy todo đã cập nhật thất bại: %v", err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, s.staleOrMissing(ctx, id)
+		}
+		if patch.ProjectID != nil && isForeignKeyViolation(err) {
+			return nil, unknownProject(*patch.ProjectID)
+		}
+		return nil, dbError("cập nhật todo thất bại", err)
+	}
+	if patch.Tags != nil {
+		if err := s.setTags(ctx, id, normalizeTags(*patch.Tags)); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.loadDetails(ctx, &updatedTodo); err != nil {
+		return nil, err
 	}
 	return &updatedTodo, nil
 }
-func (s *DbTodoService) UpdateTodoStatus(ctx context.Context, id string) error {
-	var currentDone bool
-	err := s.db.conn.QueryRow(context.Background(), "SELECT done FROM todo WHERE id = $1", id).Scan(&currentDone)
+
+// UpdateTodoStatus là toggle cũ, được dựng trên CompleteTodo/ReopenTodo (xem toggleTodo).
+func (s *DbTodoService) UpdateTodoStatus(ctx context.Context, id string) (*Todo, error) {
+	return toggleTodo(ctx, s, id)
+}
+
+func (s *DbTodoService) CompleteTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.setDone(ctx, id, true)
+}
+
+func (s *DbTodoService) ReopenTodo(ctx context.Context, id string) (*Todo, error) {
+	return s.setDone(ctx, id, false)
+}
+
+func (s *DbTodoService) setDone(ctx context.Context, id string, done bool) (*Todo, error) {
+	return s.transition(ctx, id, func(current Status) Status {
+		return s.workflow.doneTarget(current, done)
+	})
+}
+
+func (s *DbTodoService) TransitionTodo(ctx context.Context, id string, status Status) (*Todo, error) {
+	if err := s.workflow.checkKnown("status", status); err != nil {
+		return nil, err
+	}
+	return s.transition(ctx, id, func(Status) Status { return status })
+}
+
+// transition chuyển todo sang trạng thái do pick chọn từ trạng thái hiện tại.
+// Todo đã ở trạng thái đích được trả về nguyên vẹn, nên gọi lại nhiều lần cũng không đổi version.
+func (s *DbTodoService) transition(ctx context.Context, id string, pick func(current Status) Status) (*Todo, error) {
+	return updateCurrent(ctx, s.GetTodo, id, func(ctx context.Context, current *Todo) (*Todo, error) {
+		to := pick(current.Status)
+		if to == current.Status {
+			return current, nil
+		}
+		if to == StatusDone {
+			if err := s.checkBlockers(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		moved := *current
+		if err := s.workflow.moveTo(&moved, to, time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+			return nil, err
+		}
+		return s.write(ctx, id, TodoPatch{}, &moved, to == StatusDone)
+	})
+}
+
+// DeleteTodo chuyển todo vào thùng rác (deleted_at). Các todo đang bị nó chặn mất blocker này, kể cả khi todo
+// được khôi phục sau đó, nên được tăng version trong cùng transaction; blocker của chính todo được giữ lại.
+func (s *DbTodoService) DeleteTodo(ctx context.Context, id string) error {
+	return s.inTx(ctx, func(svc *DbTodoService) error {
+		if err := svc.touchDependents(ctx, id); err != nil {
+			return err
+		}
+		if _, err := svc.conn().Exec(ctx, "DELETE FROM todo_dependency WHERE blocker_id = $1 AND tenant_id = $2", id, tenantFrom(ctx)); err != nil {
+			return dbError("gỡ blocker thất bại", err)
+		}
+		w := &sqlWhere{}
+		now := w.arg(time.Now().UTC().Truncate(time.Microsecond))
+		liveTodo(ctx, w, id)
+		if version := expectedVersion(ctx); version != 0 {
+			w.add("version = ?", version)
+		}
+		var trashed Todo
+		err := scanTodo(svc.conn().QueryRow(ctx,
+			"UPDATE todo SET deleted_at = "+now+", updated_at = "+now+", version = version + 1"+w.String()+" RETURNING "+todoColumns,
+			w.args...), &trashed)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return svc.staleOrMissing(ctx, id)
+		}
+		if err != nil {
+			return dbError("xóa todo thất bại", err) // Lỗi khi xóa
+		}
+		// Chỉ deleted_at được ghi vào lịch sử, nên các phần ở bảng khác không cần đọc.
+		live := trashed
+		live.DeletedAt = nil
+		return svc.record(ctx, &live, &trashed)
+	})
+}
+
+// staleOrMissing tìm lý do một câu lệnh ghi có điều kiện version không tác động dòng nào:
+// todo không tồn tại hoặc version không khớp If-Match.
+func (s *DbTodoService) staleOrMissing(ctx context.Context, id string) error {
+	var version int
+	w := &sqlWhere{}
+	liveTodo(ctx, w, id)
+	err := s.conn().QueryRow(ctx, "SELECT version FROM todo"+w.String(), w.args...).Scan(&version)
 	if err != nil {
-		return fmt.Errorf("không tìm thấy todo với id %s: %v", id, err)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return todoNotFound(id)
+		}
+		return dbError("kiểm tra sự tồn tại của todo thất bại", err)
+	}
+	if err := checkVersion(ctx, version); err != nil {
+		return err
 	}
+	return fmt.Errorf("todo %s đã bị thay đổi đồng thời: %w", id, ErrConflict)
+}
 
-	newDone := !currentDone
-	var doneAt interface{}
+func (s *MemoryTodoService) GetAllTodo(ctx context.Context, query TodoQuery) (*TodoPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	if newDone {
-		doneAt = time.Now()
-	} else {
-		doneAt = nil
+	source := s.todos
+	if query.Trashed {
+		source = s.trash
 	}
-	_, err = s.db.conn.Exec(context.Background(), "UPDATE todo SET done = $1, done_at = $2 WHERE id = $3", newDone, doneAt, id)
-	if err != nil {
-		return fmt.Errorf("cập nhật trạng thái todo thất bại: %v", err)
+	todos := make([]Todo, 0, len(source))
+	for _, todo := range source {
+		if !s.visibleLocked(ctx, todo) {
+			continue
+		}
+		// ready cần trạng thái của các blocker nên được lọc ở đây thay vì trong TodoQuery.matches.
+		if query.Ready != nil && (todo.Done || (len(s.openBlockersLocked(todo)) == 0) != *query.Ready) {
+			continue
+		}
+		todos = append(todos, todo)
 	}
+	return paginate(todos, query)
+}
+func (s *MemoryTodoService) GetTodo(ctx context.Context, id string) (*Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	return nil
+	todo, ok := s.liveLocked(ctx, id)
+	if !ok {
+		return nil, todoNotFound(id)
+	}
+	return &todo, nil
 }
-func (s *DbTodoService) DeleteTodo(ctx context.Context, id string) error {
+func (s *MemoryTodoService) CreateTodo(ctx context.Context, todo Todo) (*Todo, error) {
+	if err := validateTodo(todo); err != nil {
+		return nil, err
+	}
+	startSeries(&todo)
+	todo.OwnerID = userFrom(ctx)
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.createLocked(ctx, todo)
+}
 
-	var exists bool
-	err := s.db.conn.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM todo WHERE id = $1)", id).Scan(&exists)
-	if err != nil {
-		return fmt.Errorf("kiểm tra sự tồn tại của todo thất bại: %v", err)
+// createLocked thêm todo đã được validate, giữ nguyên SeriesID, Occurrence và OwnerID; caller phải giữ s.mu.
+func (s *MemoryTodoService) createLocked(ctx context.Context, todo Todo) (*Todo, error) {
+	if todo.ProjectID == "" {
+		todo.ProjectID = DefaultProjectID
+	}
+	if err := s.checkProjectLocked(todo.ProjectID); err != nil {
+		return nil, err
 	}
+	todo.ID = generateNewID()
+	todo.CreatedAt = time.Now()
+	todo.UpdatedAt = todo.CreatedAt
+	todo.Version = 1
+	todo.DueAt = storedTime(todo.DueAt)
+	todo.Tags = normalizeTags(todo.Tags)
+	todo.Checklist = ChecklistProgress{}
+	todo.BlockedBy = []string{}
+	s.ensureTagsLocked(ctx, todo.Tags)
+	s.workflow.start(&todo, todo.CreatedAt)
+	s.todos[todo.ID] = todo
+	s.recordLocked(ctx, nil, &todo)
+	return &todo, nil
+}
 
-	if !exists {
-		return fmt.Errorf("not found") // Lỗi khi không tìm thấy
+func (s *MemoryTodoService) Workflow() *Workflow {
+	return s.workflow
+}
+
+// UpdateTodo ghi đè title, desc và trạng thái (xem replacePatch).
+func (s *MemoryTodoService) UpdateTodo(ctx context.Context, id string, todo Todo) (*Todo, error) {
+	if err := validateTodo(todo); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.patchLocked(ctx, id, replacePatch(todo))
+}
+func (s *MemoryTodoService) PatchTodo(ctx context.Context, id string, patch TodoPatch) (*Todo, error) {
+	if err := validatePatch(patch); err != nil {
+		return nil, err
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.patchLocked(ctx, id, patch)
+}
 
-	_, err = s.db.conn.Exec(context.Background(), "DELETE FROM todo WHERE id = $1", id)
+// patchLocked áp dụng patch đã được validate; caller phải giữ s.mu.
+func (s *MemoryTodoService) patchLocked(ctx context.Context, id string, patch TodoPatch) (*Todo, error) {
+	current, ok := s.liveLocked(ctx, id)
+	if !ok {
+		return nil, todoNotFound(id)
+	}
+	if err := checkVersion(ctx, current.Version); err != nil {
+		return nil, err
+	}
+	if patch.empty() {
+		return &current, nil
+	}
+	before := current
+
+	if patch.ProjectID != nil {
+		if err := s.checkProjectLocked(*patch.ProjectID); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkSchedule(current, patch); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	to, err := s.workflow.target(current.Status, patch.Status, patch.Done)
 	if err != nil {
-		return fmt.Errorf("xóa todo thất bại: %v", err) // Lỗi khi xóa
+		return nil, err
+	}
+	completes := to == StatusDone && current.Status != StatusDone
+	if completes {
+		if err := s.checkBlockersLocked(current); err != nil {
+			return nil, err
+		}
 	}
+	if err := s.workflow.moveTo(&current, to, now); err != nil {
+		return nil, err
+	}
+	if patch.Title != nil {
+		current.Title = *patch.Title
+	}
+	if patch.Desc != nil {
+		current.Desc = *patch.Desc
+	}
+	if patch.Priority != nil {
+		current.Priority = *patch.Priority
+	}
+	if patch.DueAt != nil {
+		current.DueAt = storedTime(*patch.DueAt)
+	}
+	if patch.Estimate != nil {
+		current.Estimate = *patch.Estimate
+	}
+	if patch.Tags != nil {
+		current.Tags = normalizeTags(*patch.Tags)
+		s.ensureTagsLocked(ctx, current.Tags)
+	}
+	if patch.ProjectID != nil {
+		current.ProjectID = *patch.ProjectID
+	}
+	if patch.Recurrence != nil {
+		current.Recurrence = *patch.Recurrence
+		if current.Recurrence != nil && current.SeriesID == "" {
+			current.SeriesID, current.Occurrence = generateNewID(), 1
+		}
+	}
+	current.Version++
+	current.UpdatedAt = now
+	s.todos[id] = current
+	s.recordLocked(ctx, &before, &current)
+	if completes {
+		if err := s.spawnNextLocked(ctx, current); err != nil {
+			return nil, err
+		}
+	}
+	return &current, nil
+}
+func (s *MemoryTodoService) UpdateTodoStatus(ctx context.Context, id string) (*Todo, error) {
+	return toggleTodo(ctx, s, id)
+}
 
+func (s *MemoryTodoService) CompleteTodo(ctx context.Context, id string) (*Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setDoneLocked(ctx, id, true)
+}
+
+func (s *MemoryTodoService) ReopenTodo(ctx context.Context, id string) (*Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.setDoneLocked(ctx, id, false)
+}
+
+func (s *MemoryTodoService) TransitionTodo(ctx context.Context, id string, status Status) (*Todo, error) {
+	if err := s.workflow.checkKnown("status", status); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transitionLocked(ctx, id, func(Status) Status { return status })
+}
+
+// setDoneLocked giống DbTodoService.setDone; caller phải giữ s.mu.
+func (s *MemoryTodoService) setDoneLocked(ctx context.Context, id string, done bool) (*Todo, error) {
+	return s.transitionLocked(ctx, id, func(current Status) Status {
+		return s.workflow.doneTarget(current, done)
+	})
+}
+
+// transitionLocked giống DbTodoService.transition; caller phải giữ s.mu.
+func (s *MemoryTodoService) transitionLocked(ctx context.Context, id string, pick func(current Status) Status) (*Todo, error) {
+	todo, ok := s.liveLocked(ctx, id)
+	if !ok {
+		return nil, todoNotFound(id)
+	}
+	if err := checkVersion(ctx, todo.Version); err != nil {
+		return nil, err
+	}
+	to := pick(todo.Status)
+	if to == todo.Status {
+		return &todo, nil
+	}
+	if to == StatusDone {
+		if err := s.checkBlockersLocked(todo); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	before := todo
+	if err := s.workflow.moveTo(&todo, to, now); err != nil {
+		return nil, err
+	}
+	todo.Version++
+	todo.UpdatedAt = now
+	s.todos[id] = todo
+	s.recordLocked(ctx, &before, &todo)
+	if to == StatusDone {
+		if err := s.spawnNextLocked(ctx, todo); err != nil {
+			return nil, err
+		}
+	}
+	return &todo, nil
+}
+func (s *MemoryTodoService) DeleteTodo(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteLocked(ctx, id)
+}
+
+// deleteLocked chuyển todo vào thùng rác như DbTodoService.DeleteTodo; caller phải giữ s.mu.
+func (s *MemoryTodoService) deleteLocked(ctx context.Context, id string) error {
+	todo, ok := s.liveLocked(ctx, id)
+	if !ok {
+		return todoNotFound(id)
+	}
+	if err := checkVersion(ctx, todo.Version); err != nil {
+		return err
+	}
+	now := time.Now()
+	before := todo
+	todo.DeletedAt = &now
+	todo.Version++
+	todo.UpdatedAt = now
+	delete(s.todos, id)
+	s.trash[id] = todo
+	s.recordLocked(ctx, &before, &todo)
+	for _, other := range s.todos {
+		if containsString(other.BlockedBy, id) {
+			s.unblockLocked(other, id)
+		}
+	}
+	for _, other := range s.trash {
+		if containsString(other.BlockedBy, id) {
+			other.BlockedBy = withoutString(other.BlockedBy, id)
+			other.Version++
+			other.UpdatedAt = now
+			s.trash[other.ID] = other
+		}
+	}
 	return nil
 }
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"log"
+	"time"
+)
+
+// defaultTrashRetention là thời gian todo nằm trong thùng rác trước khi bị janitor xóa hẳn (TRASH_RETENTION).
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// ErrNotTrashed là lỗi khi xóa hẳn một todo chưa được chuyển vào thùng rác.
+var ErrNotTrashed = fmt.Errorf("todo is not in the trash: %w", ErrConflict)
+
+// TrashPurgeResult là kết quả của DELETE /v1/trash.
+type TrashPurgeResult struct {
+	Purged int `json:"purged" example:"3"`
+}
+
+// RestoreTodo đưa todo ra khỏi thùng rác. Todo không nằm trong thùng rác được trả về nguyên vẹn,
+// nên gọi lại nhiều lần cũng không đổi version.
+func (s *DbTodoService) RestoreTodo(ctx context.Context, id string) (*Todo, error) {
+	var restored *Todo
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		var deletedAt time.Time
+		w := &sqlWhere{}
+		w.add("id = ?", id)
+		w.add("deleted_at IS NOT NULL")
+		visibleTodos(ctx, w, "")
+		err := svc.conn().QueryRow(ctx, "SELECT deleted_at FROM todo"+w.String()+" FOR UPDATE", w.args...).Scan(&deletedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			restored, err = svc.GetTodo(ctx, id)
+			return err
+		}
+		if err != nil {
+			return dbError("đọc todo trong thùng rác thất bại", err)
+		}
+
+		now := time.Now().UTC().Truncate(time.Microsecond)
+		var todo Todo
+		err = scanTodo(svc.conn().QueryRow(ctx,
+			"UPDATE todo SET deleted_at = NULL, version = version + 1, updated_at = $2 WHERE id = $1 AND tenant_id = $3 RETURNING "+todoColumns,
+			id, now, tenantFrom(ctx)), &todo)
+		if err != nil {
+			return dbError("khôi phục todo thất bại", err)
+		}
+		if err := svc.loadDetails(ctx, &todo); err != nil {
+			return err
+		}
+		trashed := todo
+		trashed.DeletedAt = &deletedAt
+		restored = &todo
+		return svc.record(ctx, &trashed, &todo)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// PurgeTodo xóa hẳn một todo trong thùng rác cùng tag, checklist và blocker của nó.
+func (s *DbTodoService) PurgeTodo(ctx context.Context, id string) error {
+	w := &sqlWhere{}
+	w.add("id = ?", id)
+	w.add("deleted_at IS NOT NULL")
+	purged, err := s.purge(ctx, w)
+	if err != nil {
+		return err
+	}
+	if purged == 0 {
+		if _, err := s.GetTodo(ctx, id); err != nil {
+			return err
+		}
+		return ErrNotTrashed
+	}
+	return nil
+}
+
+// PurgeTrash xóa hẳn các todo đã vào thùng rác trước before và trả về số todo bị xóa. Với ctx của withAllTenants
+// thùng rác của từng tenant được dọn lần lượt, mỗi tenant trong một transaction.
+func (s *DbTodoService) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	if !forAllTenants(ctx) {
+		w := &sqlWhere{}
+		w.add("deleted_at < ?", before.UTC())
+		return s.purge(ctx, w)
+	}
+	tenants, err := s.trashTenants(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, tenantID := range tenants {
+		purged, err := s.PurgeTrash(withTenant(ctx, tenantID), before)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// trashTenants trả về các tenant có todo vào thùng rác trước before. Đây là câu truy vấn duy nhất đọc qua mọi tenant
+// nên nó không đi qua tenantGuard, và chỉ chạy với ctx của withAllTenants (janitor).
+func (s *DbTodoService) trashTenants(ctx context.Context, before time.Time) ([]string, error) {
+	if !forAllTenants(ctx) {
+		return nil, fmt.Errorf("%w: trashTenants cần ctx của withAllTenants", ErrMissingTenant)
+	}
+	rows, err := s.db.conn.Query(ctx,
+		"SELECT DISTINCT tenant_id FROM todo WHERE deleted_at < $1 ORDER BY tenant_id", before.UTC())
+	if err != nil {
+		return nil, dbError("truy vấn tenant có thùng rác thất bại", err)
+	}
+	defer rows.Close()
+
+	tenants := []string{}
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, dbError("scan tenant thất bại", err)
+		}
+		tenants = append(tenants, tenantID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc tenant", err)
+	}
+	return tenants, nil
+}
+
+// purge xóa hẳn các todo thỏa w mà người dùng trong ctx được thấy và ghi bản ghi purged vào lịch sử của từng todo
+// trong cùng transaction.
+func (s *DbTodoService) purge(ctx context.Context, w *sqlWhere) (int, error) {
+	visibleTodos(ctx, w, "")
+	var purged []Todo
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		rows, err := svc.conn().Query(ctx, "DELETE FROM todo"+w.String()+" RETURNING "+todoColumns, w.args...)
+		if err != nil {
+			return dbError("xóa hẳn todo thất bại", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var todo Todo
+			if err := scanTodo(rows, &todo); err != nil {
+				return dbError("scan thất bại", err)
+			}
+			purged = append(purged, todo)
+		}
+		if err := rows.Err(); err != nil {
+			return dbError("lỗi sau khi đọc rows", err)
+		}
+		rows.Close()
+		for i := range purged {
+			if err := svc.record(ctx, &purged[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(purged), nil
+}
+
+func (s *MemoryTodoService) RestoreTodo(ctx context.Context, id string) (*Todo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.trashedLocked(ctx, id)
+	if !ok {
+		if live, ok := s.liveLocked(ctx, id); ok {
+			return &live, nil
+		}
+		return nil, todoNotFound(id)
+	}
+	before := todo
+	todo.DeletedAt = nil
+	todo.Version++
+	todo.UpdatedAt = time.Now()
+	delete(s.trash, id)
+	s.todos[id] = todo
+	s.recordLocked(ctx, &before, &todo)
+	return &todo, nil
+}
+
+func (s *MemoryTodoService) PurgeTodo(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	todo, ok := s.trashedLocked(ctx, id)
+	if !ok {
+		if _, ok := s.liveLocked(ctx, id); ok {
+			return ErrNotTrashed
+		}
+		return todoNotFound(id)
+	}
+	delete(s.trash, id)
+	delete(s.checklists, id)
+	delete(s.shares, id)
+	s.recordLocked(ctx, &todo, nil)
+	return nil
+}
+
+func (s *MemoryTodoService) PurgeTrash(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, todo := range s.trash {
+		if todo.DeletedAt.Before(before) && s.visibleLocked(ctx, todo) {
+			delete(s.trash, id)
+			delete(s.checklists, id)
+			delete(s.shares, id)
+			s.recordLocked(ctx, &todo, nil)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purgeTrash định kỳ xóa hẳn các todo nằm trong thùng rác lâu hơn retention, ở mọi tenant, cho đến khi ctx bị hủy.
+func purgeTrash(ctx context.Context, svc TodoService, retention, every time.Duration) {
+	ctx = withAllTenants(withActor(ctx, systemActor))
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if purged, err := svc.PurgeTrash(ctx, now.Add(-retention)); err != nil {
+				log.Println("Error purging trash:", err)
+			} else if purged > 0 {
+				log.Printf("Đã xóa hẳn %d todo trong thùng rác\n", purged)
+			}
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxUserNameLength  = 100
+	maxUserEmailLength = 255
+	userHeader         = "X-User-ID"
+)
+
+// User là một người dùng. Todo được tạo trong request có X-User-ID thuộc về người dùng đó (Todo.OwnerID)
+// và chỉ hiện với owner cùng những người được chia sẻ todo hoặc project của nó (xem visibleTo).
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name" example:"Alice"`
+	Email     string    `json:"email" example:"alice@example.com"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TodoShare cho UserID vai trò Role trên todo TodoID.
+type TodoShare struct {
+	TodoID    string    `json:"todo_id"`
+	UserID    string    `json:"user_id"`
+	Role      Role      `json:"role" enums:"viewer,editor,owner" example:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ErrEmailTaken là lỗi khi tạo người dùng với email đã được đăng ký.
+var ErrEmailTaken = fmt.Errorf("email is already registered: %w", ErrConflict)
+
+func userNotFound(id string) error {
+	return &NotFoundError{Resource: "user", ID: id}
+}
+
+func shareNotFound(userID string) error {
+	return &NotFoundError{Resource: "share", ID: userID}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func validateUser(user User) error {
+	verr := &ValidationError{}
+	if strings.TrimSpace(user.Name) == "" {
+		verr.Add("name", "required", "name is required")
+	} else if utf8.RuneCountInString(user.Name) > maxUserNameLength {
+		verr.Add("name", "too_long", fmt.Sprintf("name must be at most %d characters", maxUserNameLength))
+	}
+	email := normalizeEmail(user.Email)
+	switch {
+	case email == "":
+		verr.Add("email", "required", "email is required")
+	case len(email) > maxUserEmailLength:
+		verr.Add("email", "too_long", fmt.Sprintf("email must be at most %d characters", maxUserEmailLength))
+	case strings.Count(email, "@") != 1 || strings.HasPrefix(email, "@") || strings.HasSuffix(email, "@") || strings.ContainsAny(email, " \t"):
+		verr.Add("email", "invalid_format", "email must look like name@example.com")
+	}
+	return verr.Err()
+}
+
+// UserRequest là payload của POST /v1/users.
+type UserRequest struct {
+	Name  string `json:"name" example:"Alice"`
+	Email string `json:"email" example:"alice@example.com"`
+}
+
+var userRules = payloadRules{
+	fields: map[string]fieldRule{
+		"name":  {kind: "string", required: true, trim: true, maxLength: maxUserNameLength},
+		"email": {kind: "string", required: true, trim: true, maxLength: maxUserEmailLength},
+	},
+	rejected: map[string]FieldError{
+		"id":         {Code: "read_only", Message: "id is assigned by the server"},
+		"created_at": {Code: "read_only", Message: "created_at is set by the server"},
+	},
+}
+
+func (r UserRequest) user() User {
+	return User{Name: r.Name, Email: r.Email}
+}
+
+type userKey struct{}
+
+// withUser gắn người dùng đang thao tác vào context; TodoService chỉ cho người dùng này thấy todo của họ.
+func withUser(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userKey{}, userID)
+}
+
+// userFrom trả về ID người dùng trong context, rỗng khi request không nêu người dùng hoặc khi server tự chạy
+// (janitor); khi đó TodoService không giới hạn theo owner.
+func userFrom(ctx context.Context) string {
+	userID, _ := ctx.Value(userKey{}).(string)
+	return userID
+}
+
+// requestUser đọc người dùng của request từ header X-User-ID. Người dùng phải tồn tại và được dùng làm actor
+// của lịch sử thay vì X-Actor. Khi xác thực được bật, người dùng lấy từ token (xem authenticate) và header bị bỏ qua,
+// để không ai mạo danh người khác bằng X-User-ID.
+func (h *APIHandler) requestUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := strings.TrimSpace(r.Header.Get(userHeader))
+		if userID == "" || h.auth != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		_, err := h.todoService.GetUser(ctx, userID)
+		cancel()
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			writeProblem(w, r, http.StatusBadRequest, CodeUnknownUser, fmt.Sprintf("%s does not name an existing user", userHeader))
+			return
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withActor(withUser(r.Context(), userID), userID)))
+	})
+}
+
+// visibleTo trả về điều kiện SQL chỉ giữ các todo của tenant trong ctx mà người dùng trong ctx được thấy: todo không có
+// owner, todo của họ, todo được chia sẻ với họ và todo trong project được chia sẻ với họ. Khi ctx đòi vai trò cao hơn
+// viewer (xem withRequiredRole) chỉ các lần chia sẻ với vai trò đó trở lên được tính. table là tiền tố cột, ví dụ "t.".
+// Khi ctx không có người dùng chỉ tenant được lọc.
+func visibleTo(ctx context.Context, w *sqlWhere, table string) string {
+	tenant := table + "tenant_id = " + w.arg(tenantFrom(ctx))
+	userID := userFrom(ctx)
+	if userID == "" {
+		return tenant
+	}
+	withRole := ""
+	if need := requiredRole(ctx); need != RoleViewer {
+		withRole = " AND role IN (" + rolesFrom(need) + ")"
+	}
+	shares := " WHERE tenant_id = " + w.arg(tenantFrom(ctx)) + " AND user_id = " + w.arg(userID) + withRole
+	return tenant + " AND (" + table + "owner_id IS NULL OR " + table + "owner_id = " + w.arg(userID) +
+		" OR " + table + "id IN (SELECT todo_id FROM todo_share" + shares + ")" +
+		" OR " + table + "project_id IN (SELECT project_id FROM project_share" + shares + "))"
+}
+
+// effectiveRole là vai trò của userID trên todo có owner ownerID: owner của todo và mọi người với todo không có owner
+// là owner, người còn lại có vai trò cao nhất trong lần chia sẻ todo (todoRole) và project của nó (projectRole).
+func effectiveRole(userID, ownerID string, todoRole, projectRole Role) Role {
+	if userID == "" || ownerID == "" || ownerID == userID {
+		return RoleOwner
+	}
+	return higher(todoRole, projectRole)
+}
+
+// visibleTodos thêm điều kiện của visibleTo vào w.
+func visibleTodos(ctx context.Context, w *sqlWhere, table string) {
+	w.add(visibleTo(ctx, w, table))
+}
+
+// liveTodo thêm vào w điều kiện chọn todo id còn sống mà người dùng trong ctx được thấy.
+func liveTodo(ctx context.Context, w *sqlWhere, id string) {
+	w.add("id = ?", id)
+	w.add("deleted_at IS NULL")
+	visibleTodos(ctx, w, "")
+}
+
+const userColumns = "id, name, email, created_at"
+
+func scanUser(row pgx.Row, user *User) error {
+	return row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
+}
+
+func (s *DbTodoService) ListUsers(ctx context.Context) ([]User, error) {
+	rows, err := s.conn().Query(ctx, "SELECT "+userColumns+" FROM users WHERE tenant_id = $1 ORDER BY name, id", tenantFrom(ctx))
+	if err != nil {
+		return nil, dbError("truy vấn người dùng thất bại", err)
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, dbError("scan người dùng thất bại", err)
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc người dùng", err)
+	}
+	return users, nil
+}
+
+func (s *DbTodoService) GetUser(ctx context.Context, id string) (*User, error) {
+	var user User
+	err := scanUser(s.conn().QueryRow(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND tenant_id = $2", id, tenantFrom(ctx)), &user)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, userNotFound(id)
+		}
+		return nil, dbError("truy vấn người dùng thất bại", err)
+	}
+	return &user, nil
+}
+
+func (s *DbTodoService) CreateUser(ctx context.Context, user User) (*User, error) {
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+	if user.ID == "" {
+		user.ID = generateNewID()
+	}
+	user.Name = strings.TrimSpace(user.Name)
+	user.Email = normalizeEmail(user.Email)
+	user.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	_, err := s.conn().Exec(ctx, "INSERT INTO users (id, name, email, created_at, tenant_id) VALUES ($1, $2, $3, $4, $5)",
+		user.ID, user.Name, user.Email, user.CreatedAt, tenantFrom(ctx))
+	if isUniqueViolation(err) {
+		return nil, ErrEmailTaken
+	}
+	if err != nil {
+		return nil, dbError("thêm người dùng thất bại", err)
+	}
+	return &user, nil
+}
+
+func (s *DbTodoService) ListShares(ctx context.Context, todoID string) ([]TodoShare, error) {
+	if _, err := s.GetTodo(ctx, todoID); err != nil {
+		return nil, err
+	}
+	rows, err := s.conn().Query(ctx, "SELECT todo_id, user_id, role, created_at FROM todo_share WHERE todo_id = $1 AND tenant_id = $2 ORDER BY user_id",
+		todoID, tenantFrom(ctx))
+	if err != nil {
+		return nil, dbError("truy vấn chia sẻ thất bại", err)
+	}
+	defer rows.Close()
+
+	shares := []TodoShare{}
+	for rows.Next() {
+		var share TodoShare
+		if err := rows.Scan(&share.TodoID, &share.UserID, &share.Role, &share.CreatedAt); err != nil {
+			return nil, dbError("scan chia sẻ thất bại", err)
+		}
+		shares = append(shares, share)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, dbError("lỗi sau khi đọc chia sẻ", err)
+	}
+	return shares, nil
+}
+
+// ShareTodo chia sẻ todo với userID; chia sẻ lại lần nữa chỉ đổi vai trò và giữ thời điểm chia sẻ đầu tiên.
+func (s *DbTodoService) ShareTodo(ctx context.Context, todoID, userID string, role Role) (*TodoShare, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	share := TodoShare{TodoID: todoID, UserID: userID, Role: role}
+	err := s.inTx(ctx, func(svc *DbTodoService) error {
+		if _, err := svc.GetTodo(ctx, todoID); err != nil {
+			return err
+		}
+		if _, err := svc.GetUser(ctx, userID); err != nil {
+			return err
+		}
+		_, err := svc.conn().Exec(ctx,
+			"INSERT INTO todo_share (todo_id, user_id, role, created_at, tenant_id) VALUES ($1, $2, $3, $4, $5) "+
+				"ON CONFLICT (todo_id, user_id) DO UPDATE SET role = EXCLUDED.role",
+			todoID, userID, role, time.Now().UTC().Truncate(time.Microsecond), tenantFrom(ctx))
+		if err != nil {
+			return dbError("chia sẻ todo thất bại", err)
+		}
+		err = svc.conn().QueryRow(ctx, "SELECT created_at FROM todo_share WHERE todo_id = $1 AND user_id = $2 AND tenant_id = $3",
+			todoID, userID, tenantFrom(ctx)).
+			Scan(&share.CreatedAt)
+		if err != nil {
+			return dbError("truy vấn chia sẻ thất bại", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (s *DbTodoService) UnshareTodo(ctx context.Context, todoID, userID string) error {
+	return s.inTx(ctx, func(svc *DbTodoService) error {
+		if _, err := svc.GetTodo(ctx, todoID); err != nil {
+			return err
+		}
+		tag, err := svc.conn().Exec(ctx, "DELETE FROM todo_share WHERE todo_id = $1 AND user_id = $2 AND tenant_id = $3",
+			todoID, userID, tenantFrom(ctx))
+		if err != nil {
+			return dbError("bỏ chia sẻ todo thất bại", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return shareNotFound(userID)
+		}
+		return nil
+	})
+}
+
+// shareNext chép các lần chia sẻ của occurrence vừa done sang occurrence tiếp theo của nó.
+func (s *DbTodoService) shareNext(ctx context.Context, doneID, nextID string) error {
+	_, err := s.conn().Exec(ctx,
+		"INSERT INTO todo_share (todo_id, user_id, role, created_at, tenant_id) "+
+			"SELECT $2, user_id, role, created_at, tenant_id FROM todo_share WHERE todo_id = $1 AND tenant_id = $3",
+		doneID, nextID, tenantFrom(ctx))
+	if err != nil {
+		return dbError("chia sẻ occurrence tiếp theo thất bại", err)
+	}
+	return nil
+}
+
+// TodoRole đọc vai trò của người dùng trong ctx trên todo, kể cả todo trong thùng rác.
+func (s *DbTodoService) TodoRole(ctx context.Context, id string) (Role, error) {
+	userID := userFrom(ctx)
+	var ownerID *string
+	var todoRole, projectRole Role
+	err := s.conn().QueryRow(ctx,
+		"SELECT t.owner_id, "+
+			"COALESCE((SELECT role FROM todo_share WHERE todo_id = t.id AND user_id = $2 AND tenant_id = $3), ''), "+
+			"COALESCE((SELECT role FROM project_share WHERE project_id = t.project_id AND user_id = $2 AND tenant_id = $3), '') "+
+			"FROM todo t WHERE t.id = $1 AND t.tenant_id = $3", id, userID, tenantFrom(ctx)).Scan(&ownerID, &todoRole, &projectRole)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return roleNone, todoNotFound(id)
+	}
+	if err != nil {
+		return roleNone, dbError("truy vấn vai trò thất bại", err)
+	}
+	owner := ""
+	if ownerID != nil {
+		owner = *ownerID
+	}
+	role := effectiveRole(userID, owner, todoRole, projectRole)
+	if role == roleNone {
+		return roleNone, todoNotFound(id)
+	}
+	return role, nil
+}
+
+// roleLocked giống DbTodoService.TodoRole với todo đã đọc; caller phải giữ s.mu.
+func (s *MemoryTodoService) roleLocked(ctx context.Context, todo Todo) Role {
+	userID := userFrom(ctx)
+	todoRole, projectRole := roleNone, roleNone
+	for _, share := range s.shares[todo.ID] {
+		if share.UserID == userID {
+			todoRole = share.Role
+		}
+	}
+	for _, share := range s.projectShares[todo.ProjectID] {
+		if share.UserID == userID {
+			projectRole = share.Role
+		}
+	}
+	return effectiveRole(userID, todo.OwnerID, todoRole, projectRole)
+}
+
+// visibleLocked cho biết người dùng trong ctx có được thấy todo không (xem visibleTo); caller phải giữ s.mu.
+func (s *MemoryTodoService) visibleLocked(ctx context.Context, todo Todo) bool {
+	role := s.roleLocked(ctx, todo)
+	return role != roleNone && role.atLeast(requiredRole(ctx))
+}
+
+func (s *MemoryTodoService) TodoRole(ctx context.Context, id string) (Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[id]
+	if !ok {
+		todo, ok = s.trash[id]
+	}
+	if !ok {
+		return roleNone, todoNotFound(id)
+	}
+	role := s.roleLocked(ctx, todo)
+	if role == roleNone {
+		return roleNone, todoNotFound(id)
+	}
+	return role, nil
+}
+
+// liveLocked trả về todo còn sống mà người dùng trong ctx được thấy; caller phải giữ s.mu.
+func (s *MemoryTodoService) liveLocked(ctx context.Context, id string) (Todo, bool) {
+	todo, ok := s.todos[id]
+	if !ok || !s.visibleLocked(ctx, todo) {
+		return Todo{}, false
+	}
+	return todo, true
+}
+
+// trashedLocked giống liveLocked với todo trong thùng rác; caller phải giữ s.mu.
+func (s *MemoryTodoService) trashedLocked(ctx context.Context, id string) (Todo, bool) {
+	todo, ok := s.trash[id]
+	if !ok || !s.visibleLocked(ctx, todo) {
+		return Todo{}, false
+	}
+	return todo, true
+}
+
+func (s *MemoryTodoService) ListUsers(ctx context.Context) ([]User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]User, 0, len(s.users))
+	for _, user := range s.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		if users[i].Name != users[j].Name {
+			return users[i].Name < users[j].Name
+		}
+		return users[i].ID < users[j].ID
+	})
+	return users, nil
+}
+
+func (s *MemoryTodoService) GetUser(ctx context.Context, id string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return nil, userNotFound(id)
+	}
+	return &user, nil
+}
+
+func (s *MemoryTodoService) CreateUser(ctx context.Context, user User) (*User, error) {
+	if err := validateUser(user); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user.Email = normalizeEmail(user.Email)
+	for _, other := range s.users {
+		if other.Email == user.Email {
+			return nil, ErrEmailTaken
+		}
+	}
+	if user.ID == "" {
+		user.ID = generateNewID()
+	} else if _, ok := s.users[user.ID]; ok {
+		return nil, ErrConflict
+	}
+	user.Name = strings.TrimSpace(user.Name)
+	user.CreatedAt = time.Now()
+	s.users[user.ID] = user
+	return &user, nil
+}
+
+func (s *MemoryTodoService) ListShares(ctx context.Context, todoID string) ([]TodoShare, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.liveLocked(ctx, todoID); !ok {
+		return nil, todoNotFound(todoID)
+	}
+	return append([]TodoShare{}, s.shares[todoID]...), nil
+}
+
+func (s *MemoryTodoService) ShareTodo(ctx context.Context, todoID, userID string, role Role) (*TodoShare, error) {
+	if err := validateRole(role); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.liveLocked(ctx, todoID); !ok {
+		return nil, todoNotFound(todoID)
+	}
+	if _, ok := s.users[userID]; !ok {
+		return nil, userNotFound(userID)
+	}
+	share := TodoShare{TodoID: todoID, UserID: userID, Role: role, CreatedAt: time.Now()}
+	// Slice mới thay cho slice cũ để bản chụp của bulk vẫn đúng.
+	shares := make([]TodoShare, 0, len(s.shares[todoID])+1)
+	for _, existing := range s.shares[todoID] {
+		if existing.UserID == userID {
+			share.CreatedAt = existing.CreatedAt
+			continue
+		}
+		shares = append(shares, existing)
+	}
+	shares = append(shares, share)
+	sort.Slice(shares, func(i, j int) bool { return shares[i].UserID < shares[j].UserID })
+	s.shares[todoID] = shares
+	return &share, nil
+}
+
+func (s *MemoryTodoService) UnshareTodo(ctx context.Context, todoID, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.liveLocked(ctx, todoID); !ok {
+		return todoNotFound(todoID)
+	}
+	shares := make([]TodoShare, 0, len(s.shares[todoID]))
+	for _, share := range s.shares[todoID] {
+		if share.UserID != userID {
+			shares = append(shares, share)
+		}
+	}
+	if len(shares) == len(s.shares[todoID]) {
+		return shareNotFound(userID)
+	}
+	s.shares[todoID] = shares
+	return nil
+}
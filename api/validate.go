@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxBodyBytes giới hạn kích thước body JSON của các request ghi.
+const maxBodyBytes = 64 << 10
+
+// fieldRule là ràng buộc của một trường trong payload JSON.
+type fieldRule struct {
+	kind      string   // "string", "boolean", "integer" (số nguyên dương), "datetime" (RFC 3339), "recurrence" (RRULE), "array" hoặc "object"
+	required  bool     // phải có mặt (khi không phải partial) và khác rỗng sau khi trim
+	nullable  bool     // null được chấp nhận, ví dụ merge patch dùng null để xóa giá trị
+	trim      bool     // bỏ khoảng trắng đầu/cuối trước khi kiểm tra và lưu
+	maxLength int      // số ký tự tối đa, 0 là không giới hạn
+	enum      []string // các giá trị chuỗi được phép, rỗng là không giới hạn
+	items     string   // kind của từng phần tử khi kind là "array", rỗng là không kiểm tra
+}
+
+// payloadRules mô tả các trường được phép trong một loại request và các trường bị từ chối kèm lý do.
+type payloadRules struct {
+	fields   map[string]fieldRule
+	rejected map[string]FieldError
+}
+
+var (
+	titleRule    = fieldRule{kind: "string", required: true, trim: true, maxLength: maxTitleLength}
+	statusRule   = fieldRule{kind: "string", trim: true}
+	priorityRule = fieldRule{kind: "string", trim: true, enum: priorityNameList()}
+	dueAtRule    = fieldRule{kind: "datetime", nullable: true}
+	estimateRule = fieldRule{kind: "integer", nullable: true}
+	projectRule  = fieldRule{kind: "string", trim: true, maxLength: 255}
+	tagsRule     = fieldRule{kind: "array", items: "string", nullable: true, maxLength: maxTagsPerTodo}
+	// recurrenceRule null bỏ lịch lặp.
+	recurrenceRule = fieldRule{kind: "recurrence", nullable: true}
+)
+
+// serverManagedFields là các trường do server quản lý, client không bao giờ được ghi.
+var serverManagedFields = map[string]FieldError{
+	"id":           {Code: "read_only", Message: "id is assigned by the server"},
+	"created_at":   {Code: "read_only", Message: "created_at is set by the server"},
+	"done_at":      {Code: "read_only", Message: "done_at is maintained by the server when done changes"},
+	"status_times": {Code: "read_only", Message: "status_times is maintained by the server when status changes"},
+	"updated_at":   {Code: "read_only", Message: "updated_at is set by the server"},
+	"deleted_at":   {Code: "read_only", Message: "deleted_at is set when the todo is deleted, restore it with /v1/trash/{id}/restore"},
+	"version":      {Code: "read_only", Message: "version is maintained by the server, send it as If-Match instead"},
+	"checklist":    {Code: "read_only", Message: "checklist progress is computed from the checklist items"},
+	"blocked_by":   {Code: "read_only", Message: "blocked_by is managed with /v1/todos/{id}/blockers"},
+	"series_id":    {Code: "read_only", Message: "series_id is assigned by the server when recurrence is set"},
+	"occurrence":   {Code: "read_only", Message: "occurrence is assigned by the server when an occurrence is spawned"},
+	"owner_id":     {Code: "read_only", Message: "owner_id is the user of the X-User-ID header that created the todo"},
+}
+
+var (
+	createTodoRules = payloadRules{
+		fields: map[string]fieldRule{
+			"title":      titleRule,
+			"desc":       {kind: "string"},
+			"priority":   priorityRule,
+			"due_at":     dueAtRule,
+			"estimate":   estimateRule,
+			"tags":       tagsRule,
+			"project_id": projectRule,
+			"recurrence": recurrenceRule,
+		},
+		rejected: withRejected(serverManagedFields, map[string]FieldError{
+			"done":   {Code: "not_allowed", Message: "new todos always start open, complete them after creating"},
+			"status": {Code: "not_allowed", Message: "new todos start in the initial status of the workflow, transition them after creating"},
+		}),
+	}
+	replaceTodoRules = payloadRules{
+		fields: map[string]fieldRule{
+			"title":      titleRule,
+			"desc":       {kind: "string"},
+			"done":       {kind: "boolean"},
+			"status":     statusRule,
+			"priority":   priorityRule,
+			"due_at":     dueAtRule,
+			"estimate":   estimateRule,
+			"tags":       tagsRule,
+			"project_id": projectRule,
+			"recurrence": recurrenceRule,
+		},
+		rejected: serverManagedFields,
+	}
+	patchTodoRules = payloadRules{
+		fields: map[string]fieldRule{
+			"title":      titleRule,
+			"desc":       {kind: "string", nullable: true},
+			"done":       {kind: "boolean"},
+			"status":     statusRule,
+			"priority":   priorityRule,
+			"due_at":     dueAtRule,
+			"estimate":   estimateRule,
+			"tags":       tagsRule,
+			"project_id": projectRule,
+			"recurrence": recurrenceRule,
+		},
+		rejected: serverManagedFields,
+	}
+	transitionRules = payloadRules{
+		fields: map[string]fieldRule{
+			"status": {kind: "string", required: true, trim: true},
+		},
+		rejected: serverManagedFields,
+	}
+)
+
+func withRejected(base, extra map[string]FieldError) map[string]FieldError {
+	merged := make(map[string]FieldError, len(base)+len(extra))
+	for name, fe := range base {
+		merged[name] = fe
+	}
+	for name, fe := range extra {
+		merged[name] = fe
+	}
+	return merged
+}
+
+// readBody đọc toàn bộ body, tối đa maxBodyBytes.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	return readBodyLimit(w, r, maxBodyBytes)
+}
+
+func readBodyLimit(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w: body must be at most %d bytes", ErrPayloadTooLarge, tooLarge.Limit)
+		}
+		return nil, fmt.Errorf("%w: failed to read request body", ErrInvalidBody)
+	}
+	return body, nil
+}
+
+// decodeObject tách một JSON object thành các trường thô để kiểm tra từng trường.
+func decodeObject(body []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: request body must be a JSON object", ErrInvalidBody)
+	}
+	return fields, nil
+}
+
+// check kiểm tra tất cả trường theo rules và trả về mọi vi phạm cùng lúc.
+// Giá trị trả về là các trường đã chuẩn hóa (chuỗi đã trim). Với partial=true (PATCH),
+// trường bắt buộc được phép vắng mặt nhưng nếu có thì vẫn phải hợp lệ.
+func (rules payloadRules) check(fields map[string]json.RawMessage, partial bool) (map[string]json.RawMessage, error) {
+	verr := &ValidationError{}
+	normalized := make(map[string]json.RawMessage, len(fields))
+	for _, name := range sortedKeys(fields) {
+		if fe, ok := rules.rejected[name]; ok {
+			verr.Add(name, fe.Code, fe.Message)
+			continue
+		}
+		rule, ok := rules.fields[name]
+		if !ok {
+			verr.Add(name, "unknown_field", "unknown field "+name)
+			continue
+		}
+		if value, ok := rule.check(name, fields[name], verr); ok {
+			normalized[name] = value
+		}
+	}
+	if !partial {
+		for _, name := range sortedRuleNames(rules.fields) {
+			if _, present := fields[name]; !present && rules.fields[name].required {
+				verr.Add(name, "required", name+" is required")
+			}
+		}
+	}
+	return normalized, verr.Err()
+}
+
+func (rule fieldRule) check(name string, raw json.RawMessage, verr *ValidationError) (json.RawMessage, bool) {
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		switch {
+		case rule.nullable:
+			return raw, true
+		case rule.required:
+			verr.Add(name, "required", name+" is required")
+		default:
+			verr.Add(name, "invalid_type", name+" must be a "+rule.kind)
+		}
+		return nil, false
+	}
+
+	switch rule.kind {
+	case "boolean":
+		var b bool
+		if err := json.Unmarshal(raw, &b); err != nil {
+			verr.Add(name, "invalid_type", name+" must be a boolean")
+			return nil, false
+		}
+		return raw, true
+	case "integer":
+		var n int
+		if err := json.Unmarshal(raw, &n); err != nil || n <= 0 {
+			verr.Add(name, "invalid_type", name+" must be a positive integer")
+			return nil, false
+		}
+		return raw, true
+	case "array":
+		var items []json.RawMessage
+		if err := json.Unmarshal(raw, &items); err != nil {
+			verr.Add(name, "invalid_type", name+" must be an array")
+			return nil, false
+		}
+		if rule.required && len(items) == 0 {
+			verr.Add(name, "required", name+" must not be empty")
+			return nil, false
+		}
+		if rule.maxLength > 0 && len(items) > rule.maxLength {
+			verr.Add(name, "too_many", fmt.Sprintf("%s must have at most %d items", name, rule.maxLength))
+			return nil, false
+		}
+		if rule.items != "" {
+			valid := true
+			for i, item := range items {
+				if _, ok := (fieldRule{kind: rule.items}).check(fmt.Sprintf("%s[%d]", name, i), item, verr); !ok {
+					valid = false
+				}
+			}
+			if !valid {
+				return nil, false
+			}
+		}
+		return raw, true
+	case "datetime":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			verr.Add(name, "invalid_type", name+" must be an RFC 3339 timestamp string")
+			return nil, false
+		}
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			verr.Add(name, "invalid_format", name+" must be an RFC 3339 timestamp, e.g. 2026-01-31T17:00:00Z")
+			return nil, false
+		}
+		return raw, true
+	case "recurrence":
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			verr.Add(name, "invalid_type", name+" must be an RRULE string")
+			return nil, false
+		}
+		rule, err := ParseRecurrence(s)
+		if err != nil {
+			verr.Add(name, "invalid_format", name+" is not a supported RRULE: "+err.Error())
+			return nil, false
+		}
+		normalized, _ := json.Marshal(rule.String())
+		return normalized, true
+	case "object":
+		if _, err := decodeObject(raw); err != nil {
+			verr.Add(name, "invalid_type", name+" must be an object")
+			return nil, false
+		}
+		return raw, true
+	default:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			verr.Add(name, "invalid_type", name+" must be a string")
+			return nil, false
+		}
+		if rule.trim {
+			s = strings.TrimSpace(s)
+		}
+		if rule.required && s == "" {
+			verr.Add(name, "required", name+" is required")
+			return nil, false
+		}
+		if rule.maxLength > 0 && utf8.RuneCountInString(s) > rule.maxLength {
+			verr.Add(name, "too_long", fmt.Sprintf("%s must be at most %d characters", name, rule.maxLength))
+			return nil, false
+		}
+		if len(rule.enum) > 0 && !containsString(rule.enum, s) {
+			verr.Add(name, "invalid_value", name+" must be one of "+strings.Join(rule.enum, ", "))
+			return nil, false
+		}
+		normalized, _ := json.Marshal(s)
+		return normalized, true
+	}
+}
+
+func sortedRuleNames(fields map[string]fieldRule) []string {
+	names := make(map[string]json.RawMessage, len(fields))
+	for name := range fields {
+		names[name] = nil
+	}
+	return sortedKeys(names)
+}
+
+// decodePayload đọc body (có giới hạn kích thước), kiểm tra theo rules rồi giải mã các giá trị
+// đã chuẩn hóa vào dst với DisallowUnknownFields.
+func decodePayload(w http.ResponseWriter, r *http.Request, rules payloadRules, dst interface{}) error {
+	body, err := readBody(w, r)
+	if err != nil {
+		return err
+	}
+	fields, err := decodeObject(body)
+	if err != nil {
+		return err
+	}
+	return rules.decode(fields, dst)
+}
+
+// decode kiểm tra fields theo rules rồi giải mã các giá trị đã chuẩn hóa vào dst với DisallowUnknownFields.
+func (rules payloadRules) decode(fields map[string]json.RawMessage, dst interface{}) error {
+	normalized, err := rules.check(fields, false)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(normalized)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBody, err)
+	}
+	return nil
+}
+
+// addPrefixed chép các vi phạm của err (nếu là ValidationError) vào verr, thêm prefix vào tên trường,
+// ví dụ "items[2]." cho phần tử thứ ba của một batch.
+func addPrefixed(verr *ValidationError, prefix string, err error) error {
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	for _, fe := range invalid.Fields {
+		verr.Add(prefix+fe.Field, fe.Code, fe.Message)
+	}
+	return nil
+}
+
+// CreateTodoRequest là payload của POST /v1/todos. Estimate tính bằng phút; tag chưa tồn tại được tạo mới;
+// không có project_id thì todo thuộc project mặc định. recurrence (cần due_at) biến todo thành occurrence đầu tiên của một series.
+type CreateTodoRequest struct {
+	Title      string      `json:"title" example:"Write migration"`
+	Desc       string      `json:"desc" example:"todo table"`
+	Priority   Priority    `json:"priority" swaggertype:"string" enums:"low,normal,high,urgent" example:"high"`
+	DueAt      *time.Time  `json:"due_at" example:"2026-01-31T17:00:00Z"`
+	Estimate   *int        `json:"estimate" example:"90"`
+	Tags       []string    `json:"tags" example:"frontend,qr"`
+	ProjectID  string      `json:"project_id" example:"default"`
+	Recurrence *Recurrence `json:"recurrence" swaggertype:"string" example:"FREQ=WEEKLY;BYDAY=MO"`
+}
+
+func (r CreateTodoRequest) todo() Todo {
+	return Todo{Title: r.Title, Desc: r.Desc, Priority: r.Priority, DueAt: r.DueAt, Estimate: r.Estimate, Tags: r.Tags, ProjectID: r.ProjectID,
+		Recurrence: r.Recurrence}
+}
+
+// ReplaceTodoRequest là payload của PUT /v1/todos/{id}. Client cũ chỉ gửi done;
+// gửi status thì done (nếu có) phải khớp với status.
+// Các trường không gửi được đặt về mặc định: priority normal, không có due_at, estimate, tag và lịch lặp.
+// Riêng project_id không gửi thì giữ nguyên project.
+type ReplaceTodoRequest struct {
+	Title      string      `json:"title" example:"Write migration"`
+	Desc       string      `json:"desc" example:"todo table"`
+	Done       *bool       `json:"done,omitempty"`
+	Status     Status      `json:"status,omitempty" example:"in_progress"`
+	Priority   Priority    `json:"priority" swaggertype:"string" enums:"low,normal,high,urgent" example:"high"`
+	DueAt      *time.Time  `json:"due_at" example:"2026-01-31T17:00:00Z"`
+	Estimate   *int        `json:"estimate" example:"90"`
+	Tags       []string    `json:"tags" example:"frontend,qr"`
+	ProjectID  string      `json:"project_id" example:"default"`
+	Recurrence *Recurrence `json:"recurrence" swaggertype:"string" example:"FREQ=WEEKLY;BYDAY=MO"`
+}
+
+// TransitionRequest là payload của POST /v1/todos/{id}/status.
+type TransitionRequest struct {
+	Status Status `json:"status" example:"in_progress"`
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Status là trạng thái của todo trong workflow.
+type Status string
+
+// Các trạng thái của workflow mặc định. StatusDone là trạng thái duy nhất được coi là done
+// trong mọi workflow, để các trường done/done_at của client cũ luôn suy ra được từ status.
+const (
+	StatusBacklog    Status = "backlog"
+	StatusInProgress Status = "in_progress"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+	StatusCancelled  Status = "cancelled"
+)
+
+// ErrInvalidTransition là lỗi khi workflow không cho phép chuyển giữa hai trạng thái.
+var ErrInvalidTransition = fmt.Errorf("transition is not allowed by the workflow: %w", ErrConflict)
+
+// TransitionError cho biết bước chuyển bị từ chối và các trạng thái có thể chuyển tới từ trạng thái hiện tại.
+type TransitionError struct {
+	From    Status
+	To      Status
+	Allowed []Status
+}
+
+func (e *TransitionError) Error() string {
+	allowed := make([]string, 0, len(e.Allowed))
+	for _, s := range e.Allowed {
+		allowed = append(allowed, string(s))
+	}
+	if len(allowed) == 0 {
+		return fmt.Sprintf("cannot move a todo from %s to %s, %s is final", e.From, e.To, e.From)
+	}
+	return fmt.Sprintf("cannot move a todo from %s to %s, allowed: %s", e.From, e.To, strings.Join(allowed, ", "))
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// Workflow là máy trạng thái của todo: trạng thái khởi đầu, trạng thái khi mở lại một todo đã done
+// (dùng cho ReopenTodo và done=false của client cũ) và các bước chuyển hợp lệ.
+type Workflow struct {
+	Initial     Status              `json:"initial" example:"backlog"`
+	Reopen      Status              `json:"reopen" example:"backlog"`
+	Transitions map[Status][]Status `json:"transitions"`
+}
+
+// WorkflowResponse là response của GET /v1/workflow.
+type WorkflowResponse struct {
+	Statuses    []Status            `json:"statuses"`
+	Initial     Status              `json:"initial" example:"backlog"`
+	Reopen      Status              `json:"reopen" example:"backlog"`
+	Transitions map[Status][]Status `json:"transitions"`
+}
+
+func newWorkflowResponse(wf *Workflow) WorkflowResponse {
+	return WorkflowResponse{
+		Statuses:    wf.Statuses(),
+		Initial:     wf.Initial,
+		Reopen:      wf.Reopen,
+		Transitions: wf.Transitions,
+	}
+}
+
+// DefaultWorkflow là backlog → in progress → blocked → done → cancelled.
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Initial: StatusBacklog,
+		Reopen:  StatusBacklog,
+		Transitions: map[Status][]Status{
+			StatusBacklog:    {StatusInProgress, StatusBlocked, StatusDone, StatusCancelled},
+			StatusInProgress: {StatusBacklog, StatusBlocked, StatusDone, StatusCancelled},
+			StatusBlocked:    {StatusBacklog, StatusInProgress, StatusCancelled},
+			StatusDone:       {StatusBacklog, StatusInProgress},
+			StatusCancelled:  {StatusBacklog},
+		},
+	}
+}
+
+// ParseWorkflow đọc workflow từ JSON (biến môi trường TODO_WORKFLOW), ví dụ
+// {"initial": "todo", "reopen": "todo", "transitions": {"todo": ["done"], "done": ["todo"]}}.
+func ParseWorkflow(data []byte) (*Workflow, error) {
+	var wf Workflow
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&wf); err != nil {
+		return nil, fmt.Errorf("workflow không phải JSON hợp lệ: %v", err)
+	}
+	if err := wf.validate(); err != nil {
+		return nil, err
+	}
+	return &wf, nil
+}
+
+func (wf *Workflow) validate() error {
+	var problems []string
+	if !wf.Known(StatusDone) {
+		problems = append(problems, "phải có trạng thái done")
+	}
+	if !wf.Known(wf.Initial) {
+		problems = append(problems, fmt.Sprintf("initial %q không có trong transitions", wf.Initial))
+	} else if wf.Initial == StatusDone {
+		problems = append(problems, "initial không được là done")
+	}
+	if wf.Reopen == StatusDone || !wf.Allowed(StatusDone, wf.Reopen) {
+		problems = append(problems, fmt.Sprintf("phải cho phép chuyển từ done sang reopen %q", wf.Reopen))
+	}
+	for from, targets := range wf.Transitions {
+		for _, to := range targets {
+			if to == from || to == "" {
+				problems = append(problems, fmt.Sprintf("bước chuyển %q → %q không hợp lệ", from, to))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("workflow không hợp lệ: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Statuses trả về mọi trạng thái của workflow theo thứ tự chữ cái.
+func (wf *Workflow) Statuses() []Status {
+	seen := map[Status]bool{}
+	for from, targets := range wf.Transitions {
+		seen[from] = true
+		for _, to := range targets {
+			seen[to] = true
+		}
+	}
+	statuses := make([]Status, 0, len(seen))
+	for s := range seen {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+	return statuses
+}
+
+// Known cho biết status có thuộc workflow không.
+func (wf *Workflow) Known(status Status) bool {
+	for _, s := range wf.Statuses() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed cho biết workflow có cho phép chuyển trực tiếp từ from sang to không.
+func (wf *Workflow) Allowed(from, to Status) bool {
+	for _, s := range wf.Transitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (wf *Workflow) checkKnown(field string, status Status) error {
+	if wf.Known(status) {
+		return nil
+	}
+	names := make([]string, 0)
+	for _, s := range wf.Statuses() {
+		names = append(names, string(s))
+	}
+	verr := &ValidationError{}
+	verr.Add(field, "invalid_value", field+" must be one of "+strings.Join(names, ", "))
+	return verr
+}
+
+// doneTarget là trạng thái đích khi client cũ đặt done: true luôn là StatusDone,
+// false chỉ mở lại (về wf.Reopen) một todo đang done và giữ nguyên các trạng thái mở khác.
+func (wf *Workflow) doneTarget(current Status, done bool) Status {
+	switch {
+	case done:
+		return StatusDone
+	case current == StatusDone:
+		return wf.Reopen
+	}
+	return current
+}
+
+// target tính trạng thái đích của một cập nhật có status và/hoặc done.
+// Gửi cả hai thì chúng phải khớp nhau, ví dụ {"status": "blocked", "done": true} bị từ chối.
+func (wf *Workflow) target(current Status, status *Status, done *bool) (Status, error) {
+	if status == nil {
+		if done == nil {
+			return current, nil
+		}
+		return wf.doneTarget(current, *done), nil
+	}
+	if err := wf.checkKnown("status", *status); err != nil {
+		return "", err
+	}
+	if err := checkDoneMatchesStatus(*status, done); err != nil {
+		return "", err
+	}
+	return *status, nil
+}
+
+// checkDoneMatchesStatus từ chối done mâu thuẫn với status trong cùng một request.
+func checkDoneMatchesStatus(status Status, done *bool) error {
+	if done == nil || *done == (status == StatusDone) {
+		return nil
+	}
+	verr := &ValidationError{}
+	verr.Add("done", "conflicts_with_status", fmt.Sprintf("done must be %t when status is %s", status == StatusDone, status))
+	return verr
+}
+
+// start đưa một todo mới vào trạng thái khởi đầu.
+func (wf *Workflow) start(todo *Todo, now time.Time) {
+	todo.Status = wf.Initial
+	todo.StatusTimes = map[Status]time.Time{wf.Initial: now}
+	todo.Done = false
+	todo.DoneAt = nil
+}
+
+// moveTo chuyển todo sang trạng thái to tại thời điểm now: ghi thời điểm vào status_times
+// và giữ done/done_at khớp với status. Chuyển sang chính trạng thái hiện tại không làm gì.
+func (wf *Workflow) moveTo(todo *Todo, to Status, now time.Time) error {
+	if to == todo.Status {
+		return nil
+	}
+	if err := wf.checkKnown("status", to); err != nil {
+		return err
+	}
+	if !wf.Allowed(todo.Status, to) {
+		return &TransitionError{From: todo.Status, To: to, Allowed: wf.Transitions[todo.Status]}
+	}
+
+	times := make(map[Status]time.Time, len(todo.StatusTimes)+1)
+	for s, t := range todo.StatusTimes {
+		times[s] = t
+	}
+	times[to] = now
+	todo.Status = to
+	todo.StatusTimes = times
+	todo.Done = to == StatusDone
+	if todo.Done {
+		todo.DoneAt = &now
+	} else {
+		todo.DoneAt = nil
+	}
+	return nil
+}
+
+// statusTimeLayouts là các định dạng thời gian có thể gặp trong cột status_times:
+// RFC 3339 do server ghi và dạng không múi giờ do migration sinh từ cột TIMESTAMP.
+var statusTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999"}
+
+// decodeStatusTimes đọc cột status_times (JSONB) thành map trạng thái → thời điểm (UTC).
+func decodeStatusTimes(data []byte) (map[Status]time.Time, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var raw map[Status]string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	times := make(map[Status]time.Time, len(raw))
+	for status, value := range raw {
+		var parsed bool
+		for _, layout := range statusTimeLayouts {
+			if t, err := time.Parse(layout, value); err == nil {
+				times[status] = t.UTC()
+				parsed = true
+				break
+			}
+		}
+		if !parsed {
+			return nil, errors.New("status_times[" + string(status) + "] is not a timestamp: " + value)
+		}
+	}
+	return times, nil
+}
+
+func encodeStatusTimes(times map[Status]time.Time) string {
+	if times == nil {
+		return "{}"
+	}
+	data, _ := json.Marshal(times)
+	return string(data)
+}
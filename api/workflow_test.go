@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseWorkflow(t *testing.T) {
+	wf, err := ParseWorkflow([]byte(`{"initial": "todo", "reopen": "todo", "transitions": {"todo": ["done"], "done": ["todo"]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, []Status{"done", "todo"}, wf.Statuses())
+	assert.True(t, wf.Allowed("todo", StatusDone))
+	assert.False(t, wf.Allowed(StatusDone, StatusDone))
+
+	for name, raw := range map[string]string{
+		"not JSON":             `backlog → done`,
+		"unknown field":        `{"initial": "todo", "reopen": "todo", "transitions": {"todo": ["done"], "done": ["todo"]}, "final": "done"}`,
+		"no done status":       `{"initial": "todo", "reopen": "todo", "transitions": {"todo": ["doing"], "doing": ["todo"]}}`,
+		"unknown initial":      `{"initial": "new", "reopen": "todo", "transitions": {"todo": ["done"], "done": ["todo"]}}`,
+		"done is initial":      `{"initial": "done", "reopen": "todo", "transitions": {"todo": ["done"], "done": ["todo"]}}`,
+		"cannot reopen":        `{"initial": "todo", "reopen": "todo", "transitions": {"todo": ["done"], "done": []}}`,
+		"self transition":      `{"initial": "todo", "reopen": "todo", "transitions": {"todo": ["todo", "done"], "done": ["todo"]}}`,
+		"empty target":         `{"initial": "todo", "reopen": "todo", "transitions": {"todo": ["", "done"], "done": ["todo"]}}`,
+		"reopen is done":       `{"initial": "todo", "reopen": "done", "transitions": {"todo": ["done"], "done": ["todo"]}}`,
+		"missing transitions":  `{"initial": "todo", "reopen": "todo"}`,
+		"unknown reopen state": `{"initial": "todo", "reopen": "later", "transitions": {"todo": ["done"], "done": ["todo"]}}`,
+	} {
+		_, err := ParseWorkflow([]byte(raw))
+		assert.Error(t, err, name)
+	}
+
+	assert.NoError(t, DefaultWorkflow().validate())
+}
+
+func TestWorkflowMoveTo(t *testing.T) {
+	wf := DefaultWorkflow()
+	start := time.Date(2026, time.January, 1, 9, 0, 0, 0, time.UTC)
+	var todo Todo
+	wf.start(&todo, start)
+	assert.Equal(t, StatusBacklog, todo.Status)
+
+	later := start.Add(time.Hour)
+	require.NoError(t, wf.moveTo(&todo, StatusDone, later))
+	assert.True(t, todo.Done)
+	assert.Equal(t, &later, todo.DoneAt)
+	assert.Equal(t, map[Status]time.Time{StatusBacklog: start, StatusDone: later}, todo.StatusTimes)
+
+	before := todo.StatusTimes
+	require.NoError(t, wf.moveTo(&todo, StatusBacklog, later.Add(time.Hour)))
+	assert.False(t, todo.Done)
+	assert.Nil(t, todo.DoneAt)
+	assert.Len(t, before, 2, "moveTo copies status_times instead of mutating the previous map")
+
+	require.NoError(t, wf.moveTo(&todo, StatusCancelled, later))
+	err := wf.moveTo(&todo, StatusDone, later)
+	var transition *TransitionError
+	if assert.ErrorAs(t, err, &transition) {
+		assert.Equal(t, []Status{StatusBacklog}, transition.Allowed)
+		assert.Equal(t, "cannot move a todo from cancelled to done, allowed: backlog", err.Error())
+	}
+}
+
+func TestDecodeStatusTimes(t *testing.T) {
+	// Migration sinh thời điểm không có múi giờ từ cột TIMESTAMP; server ghi RFC 3339.
+	times, err := decodeStatusTimes([]byte(`{"backlog": "2024-11-08T15:45:50.6814", "done": "2024-11-09T08:00:00Z"}`))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.November, 8, 15, 45, 50, 681400000, time.UTC), times[StatusBacklog])
+	assert.Equal(t, time.Date(2024, time.November, 9, 8, 0, 0, 0, time.UTC), times[StatusDone])
+
+	_, err = decodeStatusTimes([]byte(`{"done": "yesterday"}`))
+	assert.Error(t, err)
+}